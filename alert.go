@@ -3,12 +3,12 @@ package shimesaba
 import (
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/Songmu/flextime"
-	"github.com/mashiike/shimesaba/internal/timeutils"
 )
 
 type Alert struct {
@@ -17,9 +17,30 @@ type Alert struct {
 	OpenedAt time.Time
 	ClosedAt *time.Time
 	Reason   string
+	Severity AlertSeverity
 
-	mu    sync.Mutex
-	cache Reliabilities
+	exclusion   bool
+	description string
+	impact      *float64
+	mu          sync.Mutex
+	evaluations map[alertEvaluationKey]*alertEvaluation
+}
+
+// alertEvaluationKey is the parameters that change the evaluation of the alert.
+// The evaluation is shared among the SLO definitions with the same parameters.
+// The reassessment is aligned to the calculate interval, so timeFrame is a part of the key only with reassessment.
+type alertEvaluationKey struct {
+	reassessment bool
+	minSeverity  AlertSeverity
+	timeFrame    time.Duration
+}
+
+// alertEvaluation is the failure rates of the alert in [startAt, endAt).
+type alertEvaluation struct {
+	failureRates timeline
+	startAt      time.Time
+	endAt        time.Time
+	reassessed   bool
 }
 
 func NewAlert(monitor *Monitor, openedAt time.Time, closedAt *time.Time) *Alert {
@@ -43,33 +64,77 @@ func NewVirtualAlert(description string, openedAt time.Time, closedAt time.Time)
 	}
 }
 
+// NewVirtualExclusionAlert creates a virtual alert whose period is excluded from the SLO, instead of violating it.
+func NewVirtualExclusionAlert(description string, openedAt time.Time, closedAt time.Time) *Alert {
+	alert := NewVirtualAlert(description, openedAt, closedAt)
+	alert.exclusion = true
+	return alert
+}
+
 func (alert *Alert) WithHostID(hostID string) *Alert {
-	return &Alert{
-		Monitor:  alert.Monitor,
-		OpenedAt: alert.OpenedAt,
-		ClosedAt: alert.ClosedAt,
-		HostID:   hostID,
-		Reason:   alert.Reason,
-	}
+	cloned := alert.clone()
+	cloned.HostID = hostID
+	return cloned
 }
 
 func (alert *Alert) WithReason(reason string) *Alert {
+	cloned := alert.clone()
+	cloned.Reason = reason
+	return cloned
+}
+
+func (alert *Alert) WithSeverity(severity AlertSeverity) *Alert {
+	cloned := alert.clone()
+	cloned.Severity = severity
+	return cloned
+}
+
+// WithDescription returns Alert with the description, such as the title of an incident of other sources than Mackerel.
+// Unlike the reason, the description is not parsed for the manual corrections and the impact.
+func (alert *Alert) WithDescription(description string) *Alert {
+	cloned := alert.clone()
+	cloned.description = description
+	return cloned
+}
+
+// WithImpact returns Alert with the ratio of the failure caused by the alert, which takes precedence over `impact:` in the reason.
+func (alert *Alert) WithImpact(impact float64) *Alert {
+	cloned := alert.clone()
+	cloned.impact = &impact
+	return cloned
+}
+
+// clone returns a copy of the alert without the evaluation cache.
+func (alert *Alert) clone() *Alert {
 	return &Alert{
 		Monitor:  alert.Monitor,
+		HostID:   alert.HostID,
 		OpenedAt: alert.OpenedAt,
 		ClosedAt: alert.ClosedAt,
-		HostID:   alert.HostID,
-		Reason:   reason,
+		Reason:   alert.Reason,
+		Severity: alert.Severity,
+
+		exclusion:   alert.exclusion,
+		description: alert.description,
+		impact:      alert.impact,
 	}
 }
 
+// Description returns the description of the alert set by WithDescription.
+func (alert *Alert) Description() string {
+	return alert.description
+}
+
 func (alert *Alert) String() string {
 	monitor := "???"
 	if alert.Monitor != nil {
 		monitor = alert.Monitor.ID() + ":" + alert.Monitor.Name()
+	} else if alert.description != "" {
+		monitor = alert.description
 	}
-	return fmt.Sprintf("alert[%s] %s ~ %s",
+	return fmt.Sprintf("alert[%s] %s %s ~ %s",
 		monitor,
+		alert.Severity,
 		alert.OpenedAt,
 		alert.ClosedAt,
 	)
@@ -79,6 +144,16 @@ func (alert *Alert) IsVirtual() bool {
 	return alert.Monitor == nil
 }
 
+// IsExclusion reports whether the period of the alert is excluded from the SLO.
+func (alert *Alert) IsExclusion() bool {
+	return alert.exclusion
+}
+
+// ExclusionWindow returns the period of the alert as a maintenance window.
+func (alert *Alert) ExclusionWindow() *MaintenanceWindow {
+	return NewMaintenanceWindow(alert.OpenedAt, alert.endAt())
+}
+
 func (alert *Alert) endAt() time.Time {
 	if alert.ClosedAt != nil {
 		return *alert.ClosedAt
@@ -86,81 +161,89 @@ func (alert *Alert) endAt() time.Time {
 	return flextime.Now().Add(time.Minute)
 }
 
-func (alert *Alert) EvaluateReliabilities(timeFrame time.Duration, enableReassessment bool) (Reliabilities, error) {
+// EvaluateReliabilities evaluates the alert as SLO violation.
+// If enableReassessment is true and the monitor can be reassessed, the reassessment is used, and the thresholds of the monitor lower than minSeverity are not used for it.
+// Otherwise, the manual correction in the reason is used if it is applied to the SLO definition of sloID.
+func (alert *Alert) EvaluateReliabilities(sloID string, timeFrame time.Duration, enableReassessment bool, minSeverity AlertSeverity) (Reliabilities, error) {
 	log.Printf("[debug] EvaluateReliabilities alert=%s", alert)
+	evaluation := alert.evaluate(timeFrame, enableReassessment, minSeverity)
+	if !evaluation.reassessed {
+		if c, ok := alert.Correction(); ok && c.AppliesTo(sloID) {
+			return alert.evaluateCorrection(c, timeFrame)
+		}
+	}
+	return evaluation.failureRates.newReliabilities(timeFrame, evaluation.startAt, evaluation.endAt)
+}
+
+// evaluate returns the failure rates of the alert, evaluated once for each parameters.
+// The lock is held while evaluating, so the metrics for reassessment are fetched only once even if SLO definitions are evaluated concurrently.
+func (alert *Alert) evaluate(timeFrame time.Duration, enableReassessment bool, minSeverity AlertSeverity) *alertEvaluation {
+	key := alertEvaluationKey{reassessment: enableReassessment}
+	if enableReassessment {
+		key.minSeverity = minSeverity
+		key.timeFrame = timeFrame
+	}
 	alert.mu.Lock()
 	defer alert.mu.Unlock()
-	if alert.cache != nil {
+	if evaluation, ok := alert.evaluations[key]; ok {
 		log.Printf("[debug] return cache alert=%s", alert)
-		return alert.cache, nil
+		return evaluation
+	}
+	if alert.evaluations == nil {
+		alert.evaluations = make(map[alertEvaluationKey]*alertEvaluation)
+	}
+	evaluation := &alertEvaluation{
+		failureRates: newSpanTimeline(alert.OpenedAt, alert.endAt(), 1.0),
+		startAt:      alert.OpenedAt,
+		endAt:        alert.endAt(),
 	}
 	if enableReassessment {
 		if reliabilities, ok := alert.Monitor.EvaluateReliabilities(
 			alert.HostID,
 			timeFrame,
+			minSeverity,
 			alert.OpenedAt.Add(-15*time.Minute),
 			alert.endAt(),
 		); ok {
 			log.Printf("[notice] applying SLO reassessment as an experimental feature for Monitor %s.", alert.Monitor.name)
-			alert.cache = reliabilities
-			return reliabilities, nil
-		}
-	}
-	var startAt, endAt time.Time
-	var isNoViolation IsNoViolationCollection
-	if correctionTime, ok := alert.CorrectionTime(); ok {
-		log.Printf("[notice] applying SLO Violation time %s, to %s", correctionTime, alert.Monitor.name)
-		startAt = alert.OpenedAt
-		endAt = alert.endAt()
-		isNoViolation = make(IsNoViolationCollection, correctionTime/time.Minute)
-		iter := timeutils.NewIterator(startAt, alert.OpenedAt.Add(correctionTime), time.Minute)
-		for iter.HasNext() {
-			t, _ := iter.Next()
-			isNoViolation[t] = false
+			evaluation = &alertEvaluation{
+				failureRates: reliabilities.failureRates(),
+				startAt:      alert.OpenedAt.Add(-15 * time.Minute),
+				endAt:        alert.endAt(),
+				reassessed:   true,
+			}
+			if reliabilities.Len() > 0 {
+				evaluation.startAt = reliabilities[reliabilities.Len()-1].TimeFrameStartAt()
+				evaluation.endAt = reliabilities.CursorAt(0)
+			}
 		}
-	} else {
-		isNoViolation, startAt, endAt = alert.newIsNoViolation()
 	}
-
-	reliabilities, err := isNoViolation.NewReliabilities(timeFrame, startAt, endAt)
-	if err != nil {
-		return nil, err
-	}
-	alert.cache = reliabilities
-	return reliabilities, nil
+	alert.evaluations[key] = evaluation
+	return evaluation
 }
 
-const correctionKeyword = "downtime:"
+const impactKeyword = "impact:"
 
-func (alert *Alert) CorrectionTime() (time.Duration, bool) {
-	i := strings.Index(alert.Reason, correctionKeyword)
+// Impact returns the ratio of the failure caused by the alert, set by WithImpact or specified as `impact:30%` in the reason.
+func (alert *Alert) Impact() (float64, bool) {
+	if alert.impact != nil {
+		return *alert.impact, true
+	}
+	i := strings.Index(alert.Reason, impactKeyword)
 	if i < 0 {
 		return 0, false
 	}
-	str := alert.Reason[i+len(correctionKeyword):]
+	str := alert.Reason[i+len(impactKeyword):]
 	j := strings.IndexRune(str, ' ')
 	if j >= 0 {
 		str = str[:j]
 	}
-	d, err := timeutils.ParseDuration(str)
+	impact, err := parseImpact(str)
 	if err != nil {
-		log.Printf("[debug] try parse correction time failed:%s", err)
+		log.Printf("[warn] %s, try parse impact failed:%s", alert, err)
 		return 0, false
 	}
-	return d, true
-}
-
-func (alert *Alert) newIsNoViolation() (isNoViolation IsNoViolationCollection, startAt, endAt time.Time) {
-	startAt = alert.OpenedAt
-	endAt = alert.endAt()
-
-	isNoViolation = make(IsNoViolationCollection, endAt.Sub(startAt)/time.Minute)
-	iter := timeutils.NewIterator(startAt, endAt, time.Minute)
-	for iter.HasNext() {
-		t, _ := iter.Next()
-		isNoViolation[t] = false
-	}
-	return
+	return impact, true
 }
 
 type Alerts []*Alert
@@ -186,3 +269,66 @@ func (alerts Alerts) EndAt() time.Time {
 	}
 	return endAt
 }
+
+// SuppressFlapping merges alerts of the same monitor and host separated by a gap of mergeGap or less into one incident,
+// and drops incidents shorter than minDuration. Virtual alerts are returned as they are.
+// The alerts of a merged incident are kept one by one, so that each of them is evaluated with its own correction, impact and description,
+// and each of them is extended over the gap to the next one.
+func (alerts Alerts) SuppressFlapping(minDuration, mergeGap time.Duration) Alerts {
+	if minDuration <= 0 && mergeGap <= 0 {
+		return alerts
+	}
+	ret := make(Alerts, 0, len(alerts))
+	groups := make(map[string]Alerts)
+	keys := make([]string, 0)
+	for _, alert := range alerts {
+		if alert.IsVirtual() {
+			ret = append(ret, alert)
+			continue
+		}
+		key := alert.Monitor.ID() + "/" + alert.HostID
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], alert)
+	}
+	for _, key := range keys {
+		group := groups[key]
+		sort.SliceStable(group, func(i, j int) bool {
+			return group[i].OpenedAt.Before(group[j].OpenedAt)
+		})
+		incident := Alerts{group[0]}
+		for _, alert := range group[1:] {
+			if alert.OpenedAt.Sub(incident.EndAt()) <= mergeGap {
+				incident = append(incident, alert)
+				continue
+			}
+			ret = incident.appendIfSustained(ret, minDuration)
+			incident = Alerts{alert}
+		}
+		ret = incident.appendIfSustained(ret, minDuration)
+	}
+	return ret
+}
+
+// appendIfSustained appends the alerts of the incident to dst, if it lasts minDuration or more.
+// The alerts are sorted by the open time, and each of them except the last is extended to the open time of the next one.
+func (alerts Alerts) appendIfSustained(dst Alerts, minDuration time.Duration) Alerts {
+	duration := alerts.EndAt().Sub(alerts[0].OpenedAt)
+	if duration < minDuration {
+		log.Printf("[debug] %s is shorter than min duration %s, ignored", alerts[0], minDuration)
+		return dst
+	}
+	if len(alerts) > 1 {
+		log.Printf("[debug] merge %d alerts of %s", len(alerts), alerts[0])
+	}
+	for i, alert := range alerts {
+		if i+1 < len(alerts) && alert.ClosedAt != nil && alert.ClosedAt.Before(alerts[i+1].OpenedAt) {
+			alert = alert.clone()
+			closedAt := alerts[i+1].OpenedAt
+			alert.ClosedAt = &closedAt
+		}
+		dst = append(dst, alert)
+	}
+	return dst
+}
@@ -15,13 +15,25 @@ import (
 )
 
 type AlertBasedSLI struct {
-	cfg *AlertBasedSLIConfig
+	cfg      *AlertBasedSLIConfig
+	excludes []*AlertBasedSLIConfig
+	sloID    string
 }
 
 func NewAlertBasedSLI(cfg *AlertBasedSLIConfig) *AlertBasedSLI {
 	return &AlertBasedSLI{cfg: cfg}
 }
 
+// WithExcludes returns AlertBasedSLI that does not match monitors matching any of the excludes.
+func (o *AlertBasedSLI) WithExcludes(excludes []*AlertBasedSLIConfig) *AlertBasedSLI {
+	return &AlertBasedSLI{cfg: o.cfg, excludes: excludes, sloID: o.sloID}
+}
+
+// WithSLOID returns AlertBasedSLI that applies the manual corrections limited to the SLO definition of id.
+func (o *AlertBasedSLI) WithSLOID(id string) *AlertBasedSLI {
+	return &AlertBasedSLI{cfg: o.cfg, excludes: o.excludes, sloID: id}
+}
+
 var evaluateReliabilitiesWorkerNum int = 10
 
 func init() {
@@ -74,12 +86,12 @@ func (o AlertBasedSLI) EvaluateReliabilities(timeFrame time.Duration, alerts Ale
 						return nil
 					}
 					log.Printf("[debug] worker_id=%d EvaluateReliabilities %s", workerID, alert.String())
-					tmp, err := alert.EvaluateReliabilities(timeFrame, o.cfg.TryReassessment)
+					tmp, err := alert.EvaluateReliabilities(o.sloID, timeFrame, o.cfg.TryReassessment, o.cfg.SeverityValue())
 					if err != nil {
 						log.Printf("[debug] end EvaluateReliabilities input worker_id=%d: EvaluateReliabilities err: %v", workerID, err)
 						return err
 					}
-					outputQueue <- tmp
+					outputQueue <- tmp.WithImpact(o.impactOf(alert))
 				}
 			}
 		})
@@ -108,17 +120,21 @@ func (o AlertBasedSLI) EvaluateReliabilities(timeFrame time.Duration, alerts Ale
 				}
 				reliabilities, outputErr = reliabilities.MergeInRange(tmp, startAt, endAt)
 				if outputErr != nil {
-					log.Printf("[debug] end EvaluateReliabilities output worker: MergeInRange err: %v", err)
+					log.Printf("[debug] end EvaluateReliabilities output worker: MergeInRange err: %v", outputErr)
 					return
 				}
 			}
 		}
 	}()
 
+	matched := make(Alerts, 0, len(alerts))
 	for _, alert := range alerts {
 		if !o.matchAlert(alert) {
 			continue
 		}
+		matched = append(matched, alert)
+	}
+	for _, alert := range matched.SuppressFlapping(o.cfg.DurationMinDuration(), o.cfg.DurationMergeGap()) {
 		inputQueue <- alert
 	}
 	close(inputQueue)
@@ -137,11 +153,24 @@ func (o AlertBasedSLI) EvaluateReliabilities(timeFrame time.Duration, alerts Ale
 	return reliabilities, nil
 }
 
+// impactOf returns the impact of the alert. the impact in the reason of the alert overrides that of the SLI.
+func (o AlertBasedSLI) impactOf(alert *Alert) float64 {
+	if impact, ok := alert.Impact(); ok {
+		log.Printf("[notice] applying impact %0.1f%%, to %s", impact*100.0, alert)
+		return impact
+	}
+	return o.cfg.ImpactValue()
+}
+
 func (o AlertBasedSLI) matchAlert(alert *Alert) bool {
 	if alert.IsVirtual() {
 		return true
 	}
 	log.Printf("[debug] try match %s vs %v", alert, o.cfg)
+	if !alert.Severity.Satisfies(o.cfg.SeverityValue()) {
+		log.Printf("[debug] %s is lower than severity %s", alert, o.cfg.SeverityValue())
+		return false
+	}
 	if o.MatchMonitor(alert.Monitor) {
 		log.Printf("[debug] match %s", alert)
 		return true
@@ -149,29 +178,53 @@ func (o AlertBasedSLI) matchAlert(alert *Alert) bool {
 	return false
 }
 
+// MatchMonitor reports whether the monitor matches the SLI and does not match any of the excludes.
 func (o AlertBasedSLI) MatchMonitor(monitor *Monitor) bool {
-	if o.cfg.MonitorID != "" {
-		if monitor.ID() != o.cfg.MonitorID {
+	if !matchMonitor(o.cfg, monitor) {
+		return false
+	}
+	for _, exclude := range o.excludes {
+		if matchMonitor(exclude, monitor) {
+			log.Printf("[debug] %s is excluded by %v", monitor, exclude)
+			return false
+		}
+	}
+	return true
+}
+
+func matchMonitor(cfg *AlertBasedSLIConfig, monitor *Monitor) bool {
+	if cfg.MonitorID != "" {
+		if monitor.ID() != cfg.MonitorID {
 			return false
 		}
 	}
-	if o.cfg.MonitorName != "" {
-		if monitor.Name() != o.cfg.MonitorName {
+	if cfg.MonitorName != "" {
+		if monitor.Name() != cfg.MonitorName {
 			return false
 		}
 	}
-	if o.cfg.MonitorNamePrefix != "" {
-		if !strings.HasPrefix(monitor.Name(), o.cfg.MonitorNamePrefix) {
+	if cfg.MonitorNamePrefix != "" {
+		if !strings.HasPrefix(monitor.Name(), cfg.MonitorNamePrefix) {
 			return false
 		}
 	}
-	if o.cfg.MonitorNameSuffix != "" {
-		if !strings.HasSuffix(monitor.Name(), o.cfg.MonitorNameSuffix) {
+	if cfg.MonitorNameSuffix != "" {
+		if !strings.HasSuffix(monitor.Name(), cfg.MonitorNameSuffix) {
+			return false
+		}
+	}
+	if cfg.MonitorNameRegex != "" {
+		re, err := cfg.MonitorNameRegexp()
+		if err != nil {
+			log.Printf("[warn] monitor_name_regex `%s` is invalid: %s", cfg.MonitorNameRegex, err)
+			return false
+		}
+		if !re.MatchString(monitor.Name()) {
 			return false
 		}
 	}
-	if o.cfg.MonitorType != "" {
-		if !strings.EqualFold(monitor.Type(), o.cfg.MonitorType) {
+	if cfg.MonitorType != "" {
+		if !strings.EqualFold(monitor.Type(), cfg.MonitorType) {
 			return false
 		}
 	}
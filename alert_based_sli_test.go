@@ -31,7 +31,7 @@ func TestAlertBasedSLI(t *testing.T) {
 			),
 			time.Date(2021, time.October, 1, 0, 2, 0, 0, time.UTC),
 			ptrTime(time.Date(2021, time.October, 1, 0, 4, 0, 0, time.UTC)),
-		),
+		).WithSeverity(shimesaba.AlertSeverityWarning),
 		shimesaba.NewAlert(
 			shimesaba.NewMonitor(
 				"fugara",
@@ -40,7 +40,7 @@ func TestAlertBasedSLI(t *testing.T) {
 			),
 			time.Date(2021, time.October, 1, 0, 3, 0, 0, time.UTC),
 			ptrTime(time.Date(2021, time.October, 1, 0, 5, 0, 0, time.UTC)),
-		),
+		).WithSeverity(shimesaba.AlertSeverityCritical),
 		shimesaba.NewAlert(
 			shimesaba.NewMonitor(
 				"hogera",
@@ -53,6 +53,7 @@ func TestAlertBasedSLI(t *testing.T) {
 	}
 	cases := []struct {
 		cfg      *shimesaba.AlertBasedSLIConfig
+		excludes []*shimesaba.AlertBasedSLIConfig
 		expected map[time.Time]bool
 	}{
 		{
@@ -122,10 +123,83 @@ func TestAlertBasedSLI(t *testing.T) {
 			},
 			expected: map[time.Time]bool{},
 		},
+		{
+			cfg: &shimesaba.AlertBasedSLIConfig{
+				MonitorNameRegex: "^SLO (hoge|piyo)$",
+			},
+			expected: map[time.Time]bool{
+				time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC): false,
+				time.Date(2021, time.October, 1, 0, 1, 0, 0, time.UTC): false,
+				time.Date(2021, time.October, 1, 0, 2, 0, 0, time.UTC): false,
+				time.Date(2021, time.October, 1, 0, 5, 0, 0, time.UTC): false,
+			},
+		},
+		{
+			cfg: &shimesaba.AlertBasedSLIConfig{
+				MonitorNamePrefix: "SLO",
+			},
+			excludes: []*shimesaba.AlertBasedSLIConfig{
+				{
+					MonitorType: "expression",
+				},
+			},
+			expected: map[time.Time]bool{
+				time.Date(2021, time.October, 1, 0, 2, 0, 0, time.UTC): false,
+				time.Date(2021, time.October, 1, 0, 3, 0, 0, time.UTC): false,
+				time.Date(2021, time.October, 1, 0, 4, 0, 0, time.UTC): false,
+			},
+		},
+		{
+			cfg: &shimesaba.AlertBasedSLIConfig{
+				MonitorID: "fugara",
+				Severity:  "critical",
+			},
+			expected: map[time.Time]bool{
+				time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC): true,
+				time.Date(2021, time.October, 1, 0, 1, 0, 0, time.UTC): true,
+				time.Date(2021, time.October, 1, 0, 2, 0, 0, time.UTC): true,
+				time.Date(2021, time.October, 1, 0, 3, 0, 0, time.UTC): false,
+				time.Date(2021, time.October, 1, 0, 4, 0, 0, time.UTC): false,
+				time.Date(2021, time.October, 1, 0, 5, 0, 0, time.UTC): true,
+			},
+		},
+		{
+			cfg: &shimesaba.AlertBasedSLIConfig{
+				MonitorID:   "fugara",
+				MinDuration: "3m",
+			},
+			expected: map[time.Time]bool{
+				time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC): true,
+				time.Date(2021, time.October, 1, 0, 1, 0, 0, time.UTC): true,
+				time.Date(2021, time.October, 1, 0, 2, 0, 0, time.UTC): false,
+				time.Date(2021, time.October, 1, 0, 3, 0, 0, time.UTC): false,
+				time.Date(2021, time.October, 1, 0, 4, 0, 0, time.UTC): false,
+				time.Date(2021, time.October, 1, 0, 5, 0, 0, time.UTC): true,
+			},
+		},
+		{
+			cfg: &shimesaba.AlertBasedSLIConfig{
+				MonitorID:   "fugara",
+				MinDuration: "4m",
+			},
+			expected: map[time.Time]bool{},
+		},
+		{
+			cfg: &shimesaba.AlertBasedSLIConfig{
+				MonitorID: "hogera",
+				Severity:  "critical",
+			},
+			expected: map[time.Time]bool{
+				time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC): false,
+				time.Date(2021, time.October, 1, 0, 1, 0, 0, time.UTC): false,
+				time.Date(2021, time.October, 1, 0, 2, 0, 0, time.UTC): false,
+				time.Date(2021, time.October, 1, 0, 5, 0, 0, time.UTC): false,
+			},
+		},
 	}
 	for i, c := range cases {
 		t.Run(fmt.Sprintf("case.%d", i), func(t *testing.T) {
-			obj := shimesaba.NewAlertBasedSLI(c.cfg)
+			obj := shimesaba.NewAlertBasedSLI(c.cfg).WithExcludes(c.excludes)
 			actual, err := obj.EvaluateReliabilities(
 				time.Minute,
 				alerts,
@@ -147,6 +221,178 @@ func TestAlertBasedSLI(t *testing.T) {
 
 }
 
+func TestAlertBasedSLIImpact(t *testing.T) {
+	restore := flextime.Fix(time.Date(2021, time.October, 1, 1, 0, 0, 0, time.UTC))
+	defer restore()
+	shard := shimesaba.NewMonitor("shard", "SLO shard-1", "host")
+	alerts := shimesaba.Alerts{
+		shimesaba.NewAlert(shard, time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC), ptrTime(time.Date(2021, time.October, 1, 0, 10, 0, 0, time.UTC))),
+		shimesaba.NewAlert(shard, time.Date(2021, time.October, 1, 0, 20, 0, 0, time.UTC), ptrTime(time.Date(2021, time.October, 1, 0, 30, 0, 0, time.UTC))).
+			WithReason("partial outage impact:10%"),
+		shimesaba.NewAlert(shard, time.Date(2021, time.October, 1, 0, 40, 0, 0, time.UTC), ptrTime(time.Date(2021, time.October, 1, 0, 50, 0, 0, time.UTC))).
+			WithReason("impact:unknown"),
+	}
+	cases := []struct {
+		impact   interface{}
+		expected time.Duration
+	}{
+		{impact: nil, expected: 21 * time.Minute},
+		{impact: "30%", expected: 3*time.Minute + 1*time.Minute + 3*time.Minute},
+		{impact: 0.5, expected: 5*time.Minute + 1*time.Minute + 5*time.Minute},
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case.%d", i), func(t *testing.T) {
+			cfg := &shimesaba.AlertBasedSLIConfig{
+				MonitorNamePrefix: "SLO",
+				Impact:            c.impact,
+			}
+			require.NoError(t, cfg.Restrict())
+			actual, err := shimesaba.NewAlertBasedSLI(cfg).EvaluateReliabilities(
+				time.Hour,
+				alerts,
+				time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2021, time.October, 1, 0, 59, 59, 0, time.UTC),
+			)
+			require.NoError(t, err)
+			require.Len(t, actual, 1)
+			require.InDelta(t, c.expected.Seconds(), actual[0].FailureTime().Seconds(), 0.001)
+			require.InDelta(t, time.Hour.Seconds(), (actual[0].UpTime() + actual[0].FailureTime()).Seconds(), 0.001)
+		})
+	}
+}
+
+func TestDefinitionAlertBasedSLIsWithExclude(t *testing.T) {
+	cfg := &shimesaba.SLOConfig{
+		ID:            "test",
+		RollingPeriod: "28d",
+		Destination: &shimesaba.DestinationConfig{
+			ServiceName: "shimesaba",
+		},
+		CalculateInterval: "1h",
+		ErrorBudgetSize:   0.001,
+		AlertBasedSLI: []*shimesaba.AlertBasedSLIConfig{
+			{
+				MonitorNameRegex: "^api-",
+			},
+			{
+				MonitorType: "external",
+			},
+		},
+		Exclude: []*shimesaba.AlertBasedSLIConfig{
+			{
+				MonitorNamePrefix: "api-batch-",
+			},
+		},
+	}
+	require.NoError(t, cfg.Restrict())
+	d, err := shimesaba.NewDefinition(cfg)
+	require.NoError(t, err)
+	monitors := []*shimesaba.Monitor{
+		shimesaba.NewMonitor("1", "api-latency", "service"),
+		shimesaba.NewMonitor("2", "api-batch-latency", "service"),
+		shimesaba.NewMonitor("3", "api-batch-external", "external"),
+		shimesaba.NewMonitor("4", "web-external", "external"),
+		shimesaba.NewMonitor("5", "web-latency", "service"),
+	}
+	actual := make([]string, 0)
+	for _, m := range d.AlertBasedSLIs(monitors) {
+		actual = append(actual, m.ID())
+	}
+	require.ElementsMatch(t, []string{"1", "4"}, actual, "exclusions apply to all alert_based_sli of the definition")
+}
+
+func TestAlertBasedSLIConfigRestrict(t *testing.T) {
+	cases := []struct {
+		cfg         *shimesaba.AlertBasedSLIConfig
+		exceptedErr bool
+	}{
+		{
+			cfg: &shimesaba.AlertBasedSLIConfig{MonitorNameRegex: "^api-"},
+		},
+		{
+			cfg:         &shimesaba.AlertBasedSLIConfig{MonitorNameRegex: "^api-("},
+			exceptedErr: true,
+		},
+		{
+			cfg: &shimesaba.AlertBasedSLIConfig{MonitorNamePrefix: "api-", Severity: "Critical"},
+		},
+		{
+			cfg:         &shimesaba.AlertBasedSLIConfig{MonitorNamePrefix: "api-", Severity: "fatal"},
+			exceptedErr: true,
+		},
+		{
+			cfg: &shimesaba.AlertBasedSLIConfig{MonitorNamePrefix: "api-", MinDuration: "5m", MergeGap: "2m"},
+		},
+		{
+			cfg:         &shimesaba.AlertBasedSLIConfig{MonitorNamePrefix: "api-", MinDuration: "five minutes"},
+			exceptedErr: true,
+		},
+		{
+			cfg: &shimesaba.AlertBasedSLIConfig{MonitorNamePrefix: "api-", Impact: "10%"},
+		},
+		{
+			cfg:         &shimesaba.AlertBasedSLIConfig{MonitorNamePrefix: "api-", Impact: "120%"},
+			exceptedErr: true,
+		},
+		{
+			cfg:         &shimesaba.AlertBasedSLIConfig{MonitorNamePrefix: "api-", Impact: 0.0},
+			exceptedErr: true,
+		},
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case.%d", i), func(t *testing.T) {
+			err := c.cfg.Restrict()
+			if c.exceptedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestMonitorSeverityOf(t *testing.T) {
+	warning, critical := 80.0, 90.0
+	cases := []struct {
+		monitor  *shimesaba.Monitor
+		value    float64
+		expected shimesaba.AlertSeverity
+	}{
+		{
+			monitor:  shimesaba.NewMonitor("a", "no thresholds", "host"),
+			value:    95,
+			expected: shimesaba.AlertSeverityUnknown,
+		},
+		{
+			monitor:  shimesaba.NewMonitor("b", "critical only", "host").WithThresholds(">", nil, &critical),
+			value:    95,
+			expected: shimesaba.AlertSeverityCritical,
+		},
+		{
+			monitor:  shimesaba.NewMonitor("c", "both", "host").WithThresholds(">", &warning, &critical),
+			value:    95,
+			expected: shimesaba.AlertSeverityCritical,
+		},
+		{
+			monitor:  shimesaba.NewMonitor("c", "both", "host").WithThresholds(">", &warning, &critical),
+			value:    85,
+			expected: shimesaba.AlertSeverityWarning,
+		},
+		{
+			monitor:  shimesaba.NewMonitor("c", "both", "host").WithThresholds(">", &warning, &critical),
+			value:    50,
+			expected: shimesaba.AlertSeverityUnknown,
+		},
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case.%d", i), func(t *testing.T) {
+			actual := c.monitor.SeverityOf(c.value)
+			require.Equal(t, c.expected, actual)
+			require.True(t, actual.Satisfies(shimesaba.AlertSeverityWarning))
+		})
+	}
+}
+
 func ptrTime(t time.Time) *time.Time {
 	return &t
 }
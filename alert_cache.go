@@ -0,0 +1,187 @@
+package shimesaba
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Songmu/flextime"
+	mackerel "github.com/mackerelio/mackerel-client-go"
+)
+
+// monitorCacheTTL is how long a cached monitor definition is used without GetMonitor.
+const monitorCacheTTL = 24 * time.Hour
+
+// AlertCache is an on-disk cache of closed alerts and monitor definitions of Mackerel, shared across runs.
+// Closed alerts never change, so a run fetches only the alerts opened after RefreshFrom.
+type AlertCache struct {
+	SavedAt time.Time `json:"saved_at"`
+	// Alerts are the closed alerts, sorted by OpenedAt in descending order.
+	Alerts []*mackerel.Alert `json:"alerts"`
+	// NextID is the next id of FindWithClosedAlertsByNextID to fetch the alerts older than Alerts.
+	NextID string `json:"next_id,omitempty"`
+	// RefreshFrom is the OpenedAt of the oldest alert that was open, or the newest closed alert at SavedAt.
+	// the alerts opened after RefreshFrom must be fetched again.
+	RefreshFrom time.Time                       `json:"refresh_from"`
+	Monitors    map[string]*cachedMonitorRecord `json:"monitors"`
+
+	path string
+	mu   sync.Mutex
+}
+
+type cachedMonitorRecord struct {
+	FetchedAt time.Time       `json:"fetched_at"`
+	Monitor   *monitorFixture `json:"monitor"`
+}
+
+// LoadAlertCache loads AlertCache from the file. If the file does not exist, it returns an empty cache.
+func LoadAlertCache(path string) (*AlertCache, error) {
+	cache := &AlertCache{
+		path:     path,
+		Monitors: make(map[string]*cachedMonitorRecord),
+	}
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			log.Printf("[debug] alert cache `%s` does not exist, start with empty cache", path)
+			return cache, nil
+		}
+		return nil, fmt.Errorf("failed to read alert cache: %w", err)
+	}
+	if err := json.Unmarshal(bs, cache); err != nil {
+		log.Printf("[warn] alert cache `%s` is broken, start with empty cache: %s", path, err)
+		return &AlertCache{path: path, Monitors: make(map[string]*cachedMonitorRecord)}, nil
+	}
+	if cache.Monitors == nil {
+		cache.Monitors = make(map[string]*cachedMonitorRecord)
+	}
+	log.Printf("[debug] load alert cache `%s`: %d alerts, %d monitors, saved at %s", path, len(cache.Alerts), len(cache.Monitors), cache.SavedAt)
+	return cache, nil
+}
+
+// IsEmpty reports whether the cache has no alerts.
+func (cache *AlertCache) IsEmpty() bool {
+	return len(cache.Alerts) == 0 && cache.RefreshFrom.IsZero()
+}
+
+// Save writes the cache to the file atomically.
+func (cache *AlertCache) Save() error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.SavedAt = flextime.Now()
+	bs, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(cache.path, bs); err != nil {
+		return fmt.Errorf("failed to write alert cache: %w", err)
+	}
+	log.Printf("[debug] save alert cache `%s`: %d alerts, %d monitors", cache.path, len(cache.Alerts), len(cache.Monitors))
+	return nil
+}
+
+// writeFileAtomic writes bs to a temporary file in the same directory, and renames it to path.
+func writeFileAtomic(path string, bs []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(bs); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// update replaces the alerts of the cache with the alerts fetched in the run.
+// alerts must be all alerts opened after the oldest of them, and nextID continues from the oldest.
+// The closed alerts opened before retainFrom are dropped, and nextID is moved to the newest of them.
+func (cache *AlertCache) update(alerts []*mackerel.Alert, nextID string, retainFrom time.Time) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	sorted := make([]*mackerel.Alert, len(alerts))
+	copy(sorted, alerts)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].OpenedAt > sorted[j].OpenedAt
+	})
+	closed := make([]*mackerel.Alert, 0, len(sorted))
+	var refreshFrom time.Time
+	for i, alert := range sorted {
+		openedAt := time.Unix(alert.OpenedAt, 0).UTC()
+		if alert.Status != "OK" {
+			refreshFrom = openedAt
+			continue
+		}
+		if openedAt.Before(retainFrom) {
+			nextID = alert.ID
+			log.Printf("[debug] alert cache drops %d alerts opened before %s", len(sorted)-i, retainFrom)
+			break
+		}
+		closed = append(closed, alert)
+	}
+	if len(closed) > 0 {
+		newest := time.Unix(closed[0].OpenedAt, 0).UTC()
+		if refreshFrom.IsZero() || newest.Before(refreshFrom) {
+			refreshFrom = newest
+		}
+	}
+	cache.Alerts = closed
+	cache.NextID = nextID
+	cache.RefreshFrom = refreshFrom
+}
+
+// alertsBefore returns the cached alerts opened at or before t.
+func (cache *AlertCache) alertsBefore(t time.Time) []*mackerel.Alert {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	alerts := make([]*mackerel.Alert, 0, len(cache.Alerts))
+	for _, alert := range cache.Alerts {
+		if time.Unix(alert.OpenedAt, 0).After(t) {
+			continue
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts
+}
+
+func (cache *AlertCache) getMonitor(id string) (mackerel.Monitor, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	record, ok := cache.Monitors[id]
+	if !ok || flextime.Now().Sub(record.FetchedAt) > monitorCacheTTL {
+		return nil, false
+	}
+	monitor, err := record.Monitor.decode()
+	if err != nil {
+		log.Printf("[debug] cached monitor[%s] can not decode: %s", id, err)
+		return nil, false
+	}
+	return monitor, true
+}
+
+func (cache *AlertCache) setMonitor(id string, monitor mackerel.Monitor) {
+	f, err := newMonitorFixture(monitor)
+	if err != nil {
+		log.Printf("[debug] monitor[%s] can not cache: %s", id, err)
+		return
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.Monitors[id] = &cachedMonitorRecord{
+		FetchedAt: flextime.Now(),
+		Monitor:   f,
+	}
+}
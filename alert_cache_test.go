@@ -0,0 +1,98 @@
+package shimesaba_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Songmu/flextime"
+	mackerel "github.com/mackerelio/mackerel-client-go"
+	"github.com/mashiike/shimesaba"
+	"github.com/stretchr/testify/require"
+)
+
+type pagedMackerelClient struct {
+	*mockMackerelClient
+	pages      map[string]*mackerel.AlertsResp
+	calls      map[string]int
+	getMonitor int
+}
+
+func newPagedMackerelClient(t *testing.T, firstAlertStatus string) *pagedMackerelClient {
+	first := &mackerel.Alert{
+		ID: "A", Status: firstAlertStatus, MonitorID: "dummyMonitorID", Type: "service",
+		OpenedAt: time.Date(2021, 10, 1, 0, 50, 0, 0, time.UTC).Unix(),
+	}
+	if firstAlertStatus == "OK" {
+		first.ClosedAt = time.Date(2021, 10, 1, 0, 55, 0, 0, time.UTC).Unix()
+	}
+	closed := func(id string, minute int) *mackerel.Alert {
+		return &mackerel.Alert{
+			ID: id, Status: "OK", MonitorID: "dummyMonitorID", Type: "service",
+			OpenedAt: time.Date(2021, 10, 1, 0, minute, 0, 0, time.UTC).Unix(),
+			ClosedAt: time.Date(2021, 10, 1, 0, minute+5, 0, 0, time.UTC).Unix(),
+		}
+	}
+	return &pagedMackerelClient{
+		mockMackerelClient: newMockMackerelClient(t),
+		pages: map[string]*mackerel.AlertsResp{
+			"":   {Alerts: []*mackerel.Alert{first, closed("B", 40)}, NextID: "p2"},
+			"p2": {Alerts: []*mackerel.Alert{closed("C", 30)}, NextID: "p3"},
+			"p3": {Alerts: []*mackerel.Alert{closed("D", 10)}},
+		},
+		calls: make(map[string]int),
+	}
+}
+
+func (c *pagedMackerelClient) FindWithClosedAlerts() (*mackerel.AlertsResp, error) {
+	c.calls[""]++
+	return c.pages[""], nil
+}
+
+func (c *pagedMackerelClient) FindWithClosedAlertsByNextID(nextID string) (*mackerel.AlertsResp, error) {
+	c.calls[nextID]++
+	return c.pages[nextID], nil
+}
+
+func (c *pagedMackerelClient) GetMonitor(monitorID string) (mackerel.Monitor, error) {
+	c.getMonitor++
+	return c.mockMackerelClient.GetMonitor(monitorID)
+}
+
+func TestRepositoryFetchAlertsWithAlertCache(t *testing.T) {
+	restore := flextime.Fix(time.Date(2021, 10, 1, 1, 0, 0, 0, time.UTC))
+	defer restore()
+	path := filepath.Join(t.TempDir(), "alert_cache.json")
+	startAt := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	endAt := time.Date(2021, 10, 1, 1, 0, 0, 0, time.UTC)
+
+	first := newPagedMackerelClient(t, "WARNING")
+	cache, err := shimesaba.LoadAlertCache(path)
+	require.NoError(t, err)
+	_, err = shimesaba.NewRepository(first).WithAlertCache(cache).FetchAlerts(context.Background(), startAt, endAt)
+	require.NoError(t, err)
+	require.EqualValues(t, map[string]int{"": 1, "p2": 1, "p3": 1}, first.calls)
+	require.EqualValues(t, 1, first.getMonitor)
+
+	second := newPagedMackerelClient(t, "OK")
+	cache, err = shimesaba.LoadAlertCache(path)
+	require.NoError(t, err)
+	actual, err := shimesaba.NewRepository(second).WithAlertCache(cache).FetchAlerts(context.Background(), startAt, endAt)
+	require.NoError(t, err)
+	require.EqualValues(t, map[string]int{"": 1, "p2": 1}, second.calls, "the still-open alert is refreshed, and closed alerts are from the cache")
+	require.EqualValues(t, 0, second.getMonitor, "the monitor is from the cache")
+
+	expected, err := shimesaba.NewRepository(newPagedMackerelClient(t, "OK")).FetchAlerts(context.Background(), startAt, endAt)
+	require.NoError(t, err)
+	require.EqualValues(t, len(expected), len(actual))
+	for i := range expected {
+		require.EqualValues(t, expected[i].String(), actual[i].String())
+	}
+}
+
+func TestLoadAlertCacheNotExist(t *testing.T) {
+	cache, err := shimesaba.LoadAlertCache(filepath.Join(t.TempDir(), "not_exist.json"))
+	require.NoError(t, err)
+	require.True(t, cache.IsEmpty())
+}
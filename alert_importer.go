@@ -0,0 +1,239 @@
+package shimesaba
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ImportedAlert is an alert of an external incident tool, read from the export.
+type ImportedAlert struct {
+	Service  string
+	Title    string
+	OpenedAt time.Time
+	ClosedAt *time.Time
+}
+
+// Alert returns the imported alert as a virtual alert.
+// The title is the description of the alert, so it is not parsed as the manual corrections.
+func (imported *ImportedAlert) Alert() *Alert {
+	if imported.ClosedAt == nil {
+		return &Alert{
+			OpenedAt:    imported.OpenedAt.Truncate(time.Minute).UTC(),
+			description: imported.Title,
+		}
+	}
+	return NewVirtualAlert("", imported.OpenedAt, *imported.ClosedAt).WithDescription(imported.Title)
+}
+
+// AlertImporter reads alerts from a CSV or JSON export of an external incident tool, and matches them with the rules.
+type AlertImporter struct {
+	cfg    *AlertImportConfig
+	alerts []*ImportedAlert
+}
+
+// LoadAlertImporter creates AlertImporter from the export of the configuration.
+func LoadAlertImporter(cfg *AlertImportConfig) (*AlertImporter, error) {
+	fp, err := os.Open(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open alert import `%s`: %w", cfg.Path, err)
+	}
+	defer fp.Close()
+	importer := &AlertImporter{cfg: cfg}
+	if err := importer.Import(fp); err != nil {
+		return nil, fmt.Errorf("failed to import alerts from `%s`: %w", cfg.Path, err)
+	}
+	return importer, nil
+}
+
+// NewAlertImporter creates AlertImporter without alerts. Import reads the alerts.
+func NewAlertImporter(cfg *AlertImportConfig) *AlertImporter {
+	return &AlertImporter{cfg: cfg}
+}
+
+// Import reads the export in the format of the configuration, and appends the alerts.
+func (importer *AlertImporter) Import(r io.Reader) error {
+	var records []map[string]string
+	var err error
+	switch importer.cfg.Format {
+	case "csv":
+		records, err = readCSVRecords(r)
+	case "json":
+		records, err = readJSONRecords(r)
+	default:
+		err = fmt.Errorf("format `%s` is not supported", importer.cfg.Format)
+	}
+	if err != nil {
+		return err
+	}
+	columns := importer.cfg.Columns
+	for i, record := range records {
+		alert := &ImportedAlert{
+			Service: record[columns.Service],
+			Title:   record[columns.Title],
+		}
+		alert.OpenedAt, err = importer.parseTime(record[columns.OpenedAt])
+		if err != nil {
+			return fmt.Errorf("record[%d] %s: %w", i, columns.OpenedAt, err)
+		}
+		if str := record[columns.ClosedAt]; str != "" {
+			closedAt, err := importer.parseTime(str)
+			if err != nil {
+				return fmt.Errorf("record[%d] %s: %w", i, columns.ClosedAt, err)
+			}
+			alert.ClosedAt = &closedAt
+		}
+		importer.alerts = append(importer.alerts, alert)
+	}
+	return nil
+}
+
+// maxImportedUnixTime is the upper limit of unix time in seconds, 2286-11-20.
+// Unix time in milliseconds, such as 1633046400000, exceeds it.
+const maxImportedUnixTime = 10_000_000_000
+
+// parseTime parses str with time_format, or as RFC3339, local time without offset, or unix time.
+func (importer *AlertImporter) parseTime(str string) (time.Time, error) {
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return time.Time{}, errors.New("empty time")
+	}
+	loc := importer.cfg.Location()
+	if importer.cfg.TimeFormat != "" {
+		return time.ParseInLocation(importer.cfg.TimeFormat, str, loc)
+	}
+	if t, err := time.Parse(time.RFC3339, str); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", str, loc); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04:05", str, loc); err == nil {
+		return t, nil
+	}
+	if sec, err := strconv.ParseInt(str, 10, 64); err == nil {
+		if sec < 0 || sec >= maxImportedUnixTime {
+			return time.Time{}, fmt.Errorf("unix time `%s` is out of range, it must be in seconds", str)
+		}
+		return time.Unix(sec, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("`%s` can not parse as time", str)
+}
+
+// ImportedAlerts returns all imported alerts.
+func (importer *AlertImporter) ImportedAlerts() []*ImportedAlert {
+	return importer.alerts
+}
+
+// Alerts returns the imported alerts that match the rules for the SLO definition of sloID in [startAt, endAt) as virtual alerts.
+func (importer *AlertImporter) Alerts(sloID string, startAt, endAt time.Time) Alerts {
+	alerts := make(Alerts, 0)
+	for _, imported := range importer.alerts {
+		if !imported.OpenedAt.Before(endAt) {
+			continue
+		}
+		if imported.ClosedAt != nil && !imported.ClosedAt.After(startAt) {
+			continue
+		}
+		for _, rule := range importer.cfg.Rules {
+			if matchImportedAlert(rule, sloID, imported) {
+				alerts = append(alerts, imported.Alert())
+				break
+			}
+		}
+	}
+	return alerts
+}
+
+func matchImportedAlert(rule *AlertImportRuleConfig, sloID string, imported *ImportedAlert) bool {
+	affected := false
+	for _, id := range rule.SLO {
+		if id == "*" || id == sloID {
+			affected = true
+			break
+		}
+	}
+	if !affected {
+		return false
+	}
+	if rule.Service != "" {
+		if !strings.EqualFold(imported.Service, rule.Service) {
+			return false
+		}
+	}
+	if rule.Title != "" {
+		if imported.Title != rule.Title {
+			return false
+		}
+	}
+	if rule.TitlePrefix != "" {
+		if !strings.HasPrefix(imported.Title, rule.TitlePrefix) {
+			return false
+		}
+	}
+	if rule.TitleSuffix != "" {
+		if !strings.HasSuffix(imported.Title, rule.TitleSuffix) {
+			return false
+		}
+	}
+	if re := rule.TitleRegexp(); re != nil {
+		if !re.MatchString(imported.Title) {
+			return false
+		}
+	}
+	return true
+}
+
+func readCSVRecords(r io.Reader) ([]map[string]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	header := rows[0]
+	records := make([]map[string]string, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		record := make(map[string]string, len(header))
+		for i, name := range header {
+			if i < len(row) {
+				record[strings.TrimSpace(name)] = row[i]
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func readJSONRecords(r io.Reader) ([]map[string]string, error) {
+	var objects []map[string]interface{}
+	if err := json.NewDecoder(r).Decode(&objects); err != nil {
+		return nil, err
+	}
+	records := make([]map[string]string, 0, len(objects))
+	for _, obj := range objects {
+		record := make(map[string]string, len(obj))
+		for key, value := range obj {
+			switch value := value.(type) {
+			case nil:
+			case string:
+				record[key] = value
+			case float64:
+				record[key] = strconv.FormatFloat(value, 'f', -1, 64)
+			default:
+				record[key] = fmt.Sprint(value)
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
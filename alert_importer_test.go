@@ -0,0 +1,161 @@
+package shimesaba_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mashiike/shimesaba"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertImporter(t *testing.T) {
+	startAt := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	endAt := time.Date(2021, 10, 1, 1, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name     string
+		cfg      *shimesaba.AlertImportConfig
+		sloID    string
+		expected shimesaba.Alerts
+	}{
+		{
+			name: "csv with column mapping",
+			cfg: &shimesaba.AlertImportConfig{
+				Path: "testdata/alert_import.csv",
+				Columns: &shimesaba.AlertImportColumnsConfig{
+					OpenedAt: "Created",
+					ClosedAt: "Resolved",
+					Title:    "Incident",
+					Service:  "Service Name",
+				},
+				TimeZone: "Asia/Tokyo",
+				Rules: []*shimesaba.AlertImportRuleConfig{
+					{SLO: []string{"availability"}, TitleRegex: `^\[SEV[12]\]`},
+				},
+			},
+			sloID: "availability",
+			expected: shimesaba.Alerts{
+				shimesaba.NewVirtualAlert("", time.Date(2021, 10, 1, 0, 10, 0, 0, time.UTC), time.Date(2021, 10, 1, 0, 20, 0, 0, time.UTC)).WithDescription("[SEV1] API is down"),
+				(&shimesaba.Alert{
+					OpenedAt: time.Date(2021, 10, 1, 0, 40, 0, 0, time.UTC),
+				}).WithDescription("[SEV2] Checkout errors"),
+			},
+		},
+		{
+			name: "csv for other slo",
+			cfg: &shimesaba.AlertImportConfig{
+				Path: "testdata/alert_import.csv",
+				Columns: &shimesaba.AlertImportColumnsConfig{
+					OpenedAt: "Created",
+					ClosedAt: "Resolved",
+					Title:    "Incident",
+					Service:  "Service Name",
+				},
+				TimeZone: "Asia/Tokyo",
+				Rules: []*shimesaba.AlertImportRuleConfig{
+					{SLO: []string{"availability"}},
+				},
+			},
+			sloID:    "latency",
+			expected: shimesaba.Alerts{},
+		},
+		{
+			name: "json with default columns",
+			cfg: &shimesaba.AlertImportConfig{
+				Path: "testdata/alert_import.json",
+				Rules: []*shimesaba.AlertImportRuleConfig{
+					{SLO: []string{"*"}, Service: "API", TitlePrefix: "[SEV1]"},
+					{SLO: []string{"batch"}, Service: "batch"},
+				},
+			},
+			sloID: "availability",
+			expected: shimesaba.Alerts{
+				shimesaba.NewVirtualAlert("", time.Date(2021, 10, 1, 0, 10, 0, 0, time.UTC), time.Date(2021, 10, 1, 0, 20, 0, 0, time.UTC)).WithDescription("[SEV1] API is down"),
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.NoError(t, c.cfg.Restrict(""))
+			importer, err := shimesaba.LoadAlertImporter(c.cfg)
+			require.NoError(t, err)
+			actual := importer.Alerts(c.sloID, startAt, endAt)
+			require.EqualValues(t, c.expected, actual)
+		})
+	}
+}
+
+func TestAlertImporterInvalidTime(t *testing.T) {
+	cases := []struct {
+		name     string
+		openedAt string
+	}{
+		{name: "not time", openedAt: "yesterday"},
+		{name: "unix time in milliseconds", openedAt: "1633046400000"},
+		{name: "negative unix time", openedAt: "-1"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := &shimesaba.AlertImportConfig{
+				Path:  "export.csv",
+				Rules: []*shimesaba.AlertImportRuleConfig{{SLO: []string{"*"}}},
+			}
+			require.NoError(t, cfg.Restrict(""))
+			importer := shimesaba.NewAlertImporter(cfg)
+			err := importer.Import(strings.NewReader("title,opened_at\nfoo," + c.openedAt + "\n"))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestImportedAlertTitle(t *testing.T) {
+	imported := &shimesaba.ImportedAlert{
+		Title:    "[SEV1] nodowntime for slo:availability impact:10%",
+		OpenedAt: time.Date(2021, 10, 1, 0, 10, 0, 0, time.UTC),
+		ClosedAt: ptrTime(time.Date(2021, 10, 1, 0, 20, 0, 0, time.UTC)),
+	}
+	alert := imported.Alert()
+	require.Equal(t, imported.Title, alert.Description())
+	_, ok := alert.Correction()
+	require.False(t, ok, "the title is not parsed as a manual correction")
+	_, ok = alert.Impact()
+	require.False(t, ok, "the title is not parsed as the impact")
+}
+
+func TestAlertImportConfigRestrict(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  *shimesaba.AlertImportConfig
+	}{
+		{
+			name: "unknown format",
+			cfg: &shimesaba.AlertImportConfig{
+				Path:  "export.xlsx",
+				Rules: []*shimesaba.AlertImportRuleConfig{{SLO: []string{"*"}}},
+			},
+		},
+		{
+			name: "no rules",
+			cfg:  &shimesaba.AlertImportConfig{Path: "export.csv"},
+		},
+		{
+			name: "no slo",
+			cfg: &shimesaba.AlertImportConfig{
+				Path:  "export.csv",
+				Rules: []*shimesaba.AlertImportRuleConfig{{Service: "api"}},
+			},
+		},
+		{
+			name: "invalid title_regex",
+			cfg: &shimesaba.AlertImportConfig{
+				Path:  "export.csv",
+				Rules: []*shimesaba.AlertImportRuleConfig{{SLO: []string{"*"}, TitleRegex: "["}},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Error(t, c.cfg.Restrict(""))
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package shimesaba
+
+// AlertSeverity is the severity of the alert
+type AlertSeverity int
+
+//go:generate enumer -type=AlertSeverity -yaml -linecomment -output alert_severity_enumer.go
+
+const (
+	AlertSeverityUnknown  AlertSeverity = iota //unknown
+	AlertSeverityWarning                       //warning
+	AlertSeverityCritical                      //critical
+)
+
+// Satisfies reports whether the severity is equal to or higher than the minimum severity.
+// Unknown severity always satisfies, so as not to miss SLO violations that can not be classified.
+func (s AlertSeverity) Satisfies(minSeverity AlertSeverity) bool {
+	if s == AlertSeverityUnknown {
+		return true
+	}
+	return s >= minSeverity
+}
@@ -0,0 +1,99 @@
+// Code generated by "enumer -type=AlertSeverity -yaml -linecomment -output alert_severity_enumer.go"; DO NOT EDIT.
+
+package shimesaba
+
+import (
+	"fmt"
+	"strings"
+)
+
+const _AlertSeverityName = "unknownwarningcritical"
+
+var _AlertSeverityIndex = [...]uint8{0, 7, 14, 22}
+
+const _AlertSeverityLowerName = "unknownwarningcritical"
+
+func (i AlertSeverity) String() string {
+	if i < 0 || i >= AlertSeverity(len(_AlertSeverityIndex)-1) {
+		return fmt.Sprintf("AlertSeverity(%d)", i)
+	}
+	return _AlertSeverityName[_AlertSeverityIndex[i]:_AlertSeverityIndex[i+1]]
+}
+
+// An "invalid array index" compiler error signifies that the constant values have changed.
+// Re-run the stringer command to generate them again.
+func _AlertSeverityNoOp() {
+	var x [1]struct{}
+	_ = x[AlertSeverityUnknown-(0)]
+	_ = x[AlertSeverityWarning-(1)]
+	_ = x[AlertSeverityCritical-(2)]
+}
+
+var _AlertSeverityValues = []AlertSeverity{AlertSeverityUnknown, AlertSeverityWarning, AlertSeverityCritical}
+
+var _AlertSeverityNameToValueMap = map[string]AlertSeverity{
+	_AlertSeverityName[0:7]:        AlertSeverityUnknown,
+	_AlertSeverityLowerName[0:7]:   AlertSeverityUnknown,
+	_AlertSeverityName[7:14]:       AlertSeverityWarning,
+	_AlertSeverityLowerName[7:14]:  AlertSeverityWarning,
+	_AlertSeverityName[14:22]:      AlertSeverityCritical,
+	_AlertSeverityLowerName[14:22]: AlertSeverityCritical,
+}
+
+var _AlertSeverityNames = []string{
+	_AlertSeverityName[0:7],
+	_AlertSeverityName[7:14],
+	_AlertSeverityName[14:22],
+}
+
+// AlertSeverityString retrieves an enum value from the enum constants string name.
+// Throws an error if the param is not part of the enum.
+func AlertSeverityString(s string) (AlertSeverity, error) {
+	if val, ok := _AlertSeverityNameToValueMap[s]; ok {
+		return val, nil
+	}
+
+	if val, ok := _AlertSeverityNameToValueMap[strings.ToLower(s)]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("%s does not belong to AlertSeverity values", s)
+}
+
+// AlertSeverityValues returns all values of the enum
+func AlertSeverityValues() []AlertSeverity {
+	return _AlertSeverityValues
+}
+
+// AlertSeverityStrings returns a slice of all String values of the enum
+func AlertSeverityStrings() []string {
+	strs := make([]string, len(_AlertSeverityNames))
+	copy(strs, _AlertSeverityNames)
+	return strs
+}
+
+// IsAAlertSeverity returns "true" if the value is listed in the enum definition. "false" otherwise
+func (i AlertSeverity) IsAAlertSeverity() bool {
+	for _, v := range _AlertSeverityValues {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalYAML implements a YAML Marshaler for AlertSeverity
+func (i AlertSeverity) MarshalYAML() (interface{}, error) {
+	return i.String(), nil
+}
+
+// UnmarshalYAML implements a YAML Unmarshaler for AlertSeverity
+func (i *AlertSeverity) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+
+	var err error
+	*i, err = AlertSeverityString(s)
+	return err
+}
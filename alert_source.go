@@ -0,0 +1,41 @@
+package shimesaba
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// AlertSource is an additional source of alerts other than Mackerel, such as the incident ledger.
+type AlertSource interface {
+	// Alerts returns the alerts that affect the SLO definition of sloID in [startAt, endAt) as virtual alerts.
+	Alerts(sloID string, startAt, endAt time.Time) Alerts
+}
+
+// AlertSourceDataProvider is a DataProvider that merges the alerts of the sources into the virtual alerts of the provider.
+type AlertSourceDataProvider struct {
+	DataProvider
+	sources []AlertSource
+}
+
+// NewAlertSourceDataProvider creates AlertSourceDataProvider
+func NewAlertSourceDataProvider(provider DataProvider, sources ...AlertSource) *AlertSourceDataProvider {
+	return &AlertSourceDataProvider{
+		DataProvider: provider,
+		sources:      sources,
+	}
+}
+
+// FetchVirtualAlerts returns the virtual alerts of the provider and the alerts of the sources.
+func (p *AlertSourceDataProvider) FetchVirtualAlerts(ctx context.Context, serviceName string, sloID string, startAt time.Time, endAt time.Time) (Alerts, error) {
+	alerts, err := p.DataProvider.FetchVirtualAlerts(ctx, serviceName, sloID, startAt, endAt)
+	if err != nil {
+		return nil, err
+	}
+	for _, source := range p.sources {
+		tmp := source.Alerts(sloID, startAt, endAt)
+		log.Printf("[debug] get %d alerts from %T", len(tmp), source)
+		alerts = append(alerts, tmp...)
+	}
+	return alerts, nil
+}
@@ -62,6 +62,109 @@ func TestAlerts(t *testing.T) {
 	require.EqualValues(t, time.Date(2021, time.October, 1, 0, 6, 0, 0, time.UTC), alerts.EndAt())
 }
 
+func TestAlertsSuppressFlapping(t *testing.T) {
+	restore := flextime.Fix(time.Date(2021, time.October, 1, 0, 30, 0, 0, time.UTC))
+	defer restore()
+	newAlert := func(id string, openedAt time.Time, closedAt *time.Time) *shimesaba.Alert {
+		return shimesaba.NewAlert(shimesaba.NewMonitor(id, id+".example.com", "external"), openedAt, closedAt)
+	}
+	alerts := shimesaba.Alerts{
+		newAlert("flap", time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC), ptrTime(time.Date(2021, time.October, 1, 0, 1, 0, 0, time.UTC))),
+		newAlert("blip", time.Date(2021, time.October, 1, 0, 10, 0, 0, time.UTC), ptrTime(time.Date(2021, time.October, 1, 0, 11, 0, 0, time.UTC))),
+		newAlert("flap", time.Date(2021, time.October, 1, 0, 5, 0, 0, time.UTC), ptrTime(time.Date(2021, time.October, 1, 0, 6, 0, 0, time.UTC))),
+		newAlert("flap", time.Date(2021, time.October, 1, 0, 3, 0, 0, time.UTC), ptrTime(time.Date(2021, time.October, 1, 0, 4, 0, 0, time.UTC))),
+		newAlert("long", time.Date(2021, time.October, 1, 0, 10, 0, 0, time.UTC), ptrTime(time.Date(2021, time.October, 1, 0, 20, 0, 0, time.UTC))),
+		newAlert("open", time.Date(2021, time.October, 1, 0, 28, 0, 0, time.UTC), nil),
+		shimesaba.NewVirtualAlert(
+			"slo:hoge",
+			time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, time.October, 1, 0, 1, 0, 0, time.UTC),
+		),
+	}
+	cases := []struct {
+		minDuration time.Duration
+		mergeGap    time.Duration
+		expected    []string
+	}{
+		{
+			expected: []string{
+				"flap 00:00~00:01", "blip 00:10~00:11", "flap 00:05~00:06", "flap 00:03~00:04",
+				"long 00:10~00:20", "open 00:28~", "virtual 00:00~00:01",
+			},
+		},
+		{
+			minDuration: 5 * time.Minute,
+			expected:    []string{"long 00:10~00:20", "virtual 00:00~00:01"},
+		},
+		{
+			minDuration: 5 * time.Minute,
+			mergeGap:    2 * time.Minute,
+			expected:    []string{"flap 00:00~00:03", "flap 00:03~00:05", "flap 00:05~00:06", "long 00:10~00:20", "virtual 00:00~00:01"},
+		},
+		{
+			mergeGap: 2 * time.Minute,
+			expected: []string{"flap 00:00~00:03", "flap 00:03~00:05", "flap 00:05~00:06", "blip 00:10~00:11", "long 00:10~00:20", "open 00:28~", "virtual 00:00~00:01"},
+		},
+		{
+			minDuration: time.Minute,
+			mergeGap:    time.Minute,
+			expected:    []string{"flap 00:00~00:01", "flap 00:03~00:05", "flap 00:05~00:06", "blip 00:10~00:11", "long 00:10~00:20", "open 00:28~", "virtual 00:00~00:01"},
+		},
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case.%d", i), func(t *testing.T) {
+			actual := make([]string, 0)
+			for _, alert := range alerts.SuppressFlapping(c.minDuration, c.mergeGap) {
+				id := "virtual"
+				if !alert.IsVirtual() {
+					id = alert.Monitor.ID()
+				}
+				closedAt := ""
+				if alert.ClosedAt != nil {
+					closedAt = alert.ClosedAt.Format("15:04")
+				}
+				actual = append(actual, id+" "+alert.OpenedAt.Format("15:04")+"~"+closedAt)
+			}
+			require.ElementsMatch(t, c.expected, actual)
+		})
+	}
+}
+
+func TestAlertsSuppressFlappingKeepsCorrection(t *testing.T) {
+	monitor := shimesaba.NewMonitor("flap", "flap.example.com", "external")
+	alerts := shimesaba.Alerts{
+		shimesaba.NewAlert(
+			monitor,
+			time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC),
+			ptrTime(time.Date(2021, time.October, 1, 0, 1, 0, 0, time.UTC)),
+		).WithReason("nodowntime").WithDescription("false positive"),
+		shimesaba.NewAlert(
+			monitor,
+			time.Date(2021, time.October, 1, 0, 3, 0, 0, time.UTC),
+			ptrTime(time.Date(2021, time.October, 1, 0, 4, 0, 0, time.UTC)),
+		),
+		shimesaba.NewAlert(
+			monitor,
+			time.Date(2021, time.October, 1, 0, 6, 0, 0, time.UTC),
+			ptrTime(time.Date(2021, time.October, 1, 0, 8, 0, 0, time.UTC)),
+		).WithImpact(0.5),
+	}
+	merged := alerts.SuppressFlapping(5*time.Minute, 2*time.Minute)
+	require.Len(t, merged, 3)
+	require.Equal(t, "false positive", merged[0].Description())
+	impact, ok := merged[2].Impact()
+	require.True(t, ok)
+	require.EqualValues(t, 0.5, impact)
+	var failureTime time.Duration
+	for _, alert := range merged {
+		reliabilities, err := alert.EvaluateReliabilities("test", time.Minute, false, shimesaba.AlertSeverityWarning)
+		require.NoError(t, err)
+		_, tmp, _ := reliabilities.CalcTime(0, reliabilities.Len())
+		failureTime += tmp
+	}
+	require.EqualValues(t, 5*time.Minute, failureTime, "00:00~00:03 is corrected as nodowntime, 00:03~00:06 and 00:06~00:08 are violation")
+}
+
 func TestAlertEvaluateReliabilities(t *testing.T) {
 	restore := flextime.Fix(time.Date(2021, time.October, 1, 0, 8, 0, 0, time.UTC))
 	defer restore()
@@ -197,7 +300,7 @@ func TestAlertEvaluateReliabilities(t *testing.T) {
 					"fugara",
 					"fugara.example.com",
 					"service",
-				).WithEvaluator(func(hostID string, timeFrame time.Duration, startAt, endAt time.Time) (shimesaba.Reliabilities, bool) {
+				).WithEvaluator(func(hostID string, timeFrame time.Duration, minSeverity shimesaba.AlertSeverity, startAt, endAt time.Time) (shimesaba.Reliabilities, bool) {
 					isNoViolation := map[time.Time]bool{
 						time.Date(2021, time.September, 30, 23, 58, 0, 0, time.UTC): false,
 						time.Date(2021, time.September, 30, 23, 59, 0, 0, time.UTC): false,
@@ -235,13 +338,53 @@ func TestAlertEvaluateReliabilities(t *testing.T) {
 	}
 	for i, c := range cases {
 		t.Run(fmt.Sprintf("case.%d", i), func(t *testing.T) {
-			actual, err := c.alert.EvaluateReliabilities(c.timeFrame, true)
+			actual, err := c.alert.EvaluateReliabilities("test", c.timeFrame, true, shimesaba.AlertSeverityWarning)
 			require.NoError(t, err)
 			require.EqualValues(t, c.expectedGenerator(), actual)
 		})
 	}
 }
 
+func TestAlertEvaluateReliabilitiesSharedEvaluation(t *testing.T) {
+	calls := make(map[shimesaba.AlertSeverity]int)
+	alert := shimesaba.NewAlert(
+		shimesaba.NewMonitor("fugara", "fugara.example.com", "host").WithEvaluator(
+			func(hostID string, timeFrame time.Duration, minSeverity shimesaba.AlertSeverity, startAt, endAt time.Time) (shimesaba.Reliabilities, bool) {
+				calls[minSeverity]++
+				isNoViolation := map[time.Time]bool{
+					time.Date(2021, time.October, 1, 0, 4, 0, 0, time.UTC): false,
+					time.Date(2021, time.October, 1, 0, 5, 0, 0, time.UTC): false,
+				}
+				if minSeverity == shimesaba.AlertSeverityCritical {
+					isNoViolation = map[time.Time]bool{}
+				}
+				reliabilities, err := shimesaba.IsNoViolationCollection(isNoViolation).NewReliabilities(timeFrame, startAt, endAt)
+				return reliabilities, err == nil
+			},
+		),
+		time.Date(2021, time.October, 1, 0, 3, 0, 0, time.UTC),
+		ptrTime(time.Date(2021, time.October, 1, 0, 8, 0, 0, time.UTC)),
+	)
+	failureTime := func(timeFrame time.Duration, reassessment bool, minSeverity shimesaba.AlertSeverity) time.Duration {
+		t.Helper()
+		reliabilities, err := alert.EvaluateReliabilities("test", timeFrame, reassessment, minSeverity)
+		require.NoError(t, err)
+		for _, r := range reliabilities {
+			require.EqualValues(t, timeFrame, r.TimeFrame())
+		}
+		_, failureTime, _ := reliabilities.CalcTime(0, reliabilities.Len())
+		return failureTime
+	}
+	require.EqualValues(t, 2*time.Minute, failureTime(5*time.Minute, true, shimesaba.AlertSeverityWarning))
+	require.EqualValues(t, 2*time.Minute, failureTime(5*time.Minute, true, shimesaba.AlertSeverityWarning), "the same parameters reuse the reassessment")
+	require.EqualValues(t, 1, calls[shimesaba.AlertSeverityWarning])
+	require.EqualValues(t, 2*time.Minute, failureTime(10*time.Minute, true, shimesaba.AlertSeverityWarning), "another calculate interval is reassessed again")
+	require.EqualValues(t, 2, calls[shimesaba.AlertSeverityWarning])
+	require.EqualValues(t, 5*time.Minute, failureTime(5*time.Minute, false, shimesaba.AlertSeverityWarning), "without reassessment, the whole alert is violation")
+	require.EqualValues(t, 0, failureTime(5*time.Minute, true, shimesaba.AlertSeverityCritical))
+	require.EqualValues(t, 1, calls[shimesaba.AlertSeverityCritical])
+}
+
 func TestAlertCorrectionTime(t *testing.T) {
 	cases := []struct {
 		alert      *shimesaba.Alert
@@ -291,3 +434,119 @@ func TestAlertCorrectionTime(t *testing.T) {
 		})
 	}
 }
+
+func TestAlertCorrection(t *testing.T) {
+	newAlert := func(reason string) *shimesaba.Alert {
+		return shimesaba.NewAlert(
+			shimesaba.NewMonitor("test", "test", "external"),
+			time.Date(2021, time.October, 1, 0, 2, 0, 0, time.UTC),
+			ptrTime(time.Date(2021, time.October, 1, 0, 30, 0, 0, time.UTC)),
+		).WithReason(reason)
+	}
+	cases := []struct {
+		alert      *shimesaba.Alert
+		exceptedOk bool
+		excepted   *shimesaba.Correction
+	}{
+		{
+			alert:      newAlert("downtime:5m-20m"),
+			exceptedOk: true,
+			excepted: &shimesaba.Correction{
+				StartAt: time.Date(2021, time.October, 1, 0, 7, 0, 0, time.UTC),
+				EndAt:   time.Date(2021, time.October, 1, 0, 22, 0, 0, time.UTC),
+			},
+		},
+		{
+			alert:      newAlert("5xx between downtime:00:10-00:15 only"),
+			exceptedOk: true,
+			excepted: &shimesaba.Correction{
+				StartAt: time.Date(2021, time.October, 1, 0, 10, 0, 0, time.UTC),
+				EndAt:   time.Date(2021, time.October, 1, 0, 15, 0, 0, time.UTC),
+			},
+		},
+		{
+			alert:      newAlert("downtime:2021-10-01T09:05:00+09:00-2021-10-01T00:12:00Z"),
+			exceptedOk: true,
+			excepted: &shimesaba.Correction{
+				StartAt: time.Date(2021, time.October, 1, 0, 5, 0, 0, time.UTC),
+				EndAt:   time.Date(2021, time.October, 1, 0, 12, 0, 0, time.UTC),
+			},
+		},
+		{
+			alert:      newAlert("false positive NoDowntime"),
+			exceptedOk: true,
+			excepted: &shimesaba.Correction{
+				NoDowntime: true,
+			},
+		},
+		{
+			alert:      newAlert("downtime:3m slo:alerts,latency"),
+			exceptedOk: true,
+			excepted: &shimesaba.Correction{
+				StartAt: time.Date(2021, time.October, 1, 0, 2, 0, 0, time.UTC),
+				EndAt:   time.Date(2021, time.October, 1, 0, 5, 0, 0, time.UTC),
+				SLOIDs:  []string{"alerts", "latency"},
+			},
+		},
+		{
+			alert:      newAlert("downtime:20m-5m"),
+			exceptedOk: false,
+		},
+		{
+			alert:      newAlert("slo:alerts"),
+			exceptedOk: false,
+		},
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case.%d", i), func(t *testing.T) {
+			actual, ok := c.alert.Correction()
+			require.EqualValues(t, c.exceptedOk, ok)
+			require.EqualValues(t, c.excepted, actual)
+		})
+	}
+}
+
+func TestAlertEvaluateReliabilitiesWithCorrection(t *testing.T) {
+	alert := shimesaba.NewAlert(
+		shimesaba.NewMonitor("test", "test", "external"),
+		time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC),
+		ptrTime(time.Date(2021, time.October, 1, 0, 10, 0, 0, time.UTC)),
+	).WithReason("nodowntime slo:alerts")
+
+	corrected, err := alert.EvaluateReliabilities("alerts", 5*time.Minute, false, shimesaba.AlertSeverityWarning)
+	require.NoError(t, err)
+	_, failureTime, _ := corrected.CalcTime(0, corrected.Len())
+	require.EqualValues(t, 0, failureTime)
+
+	notCorrected, err := alert.EvaluateReliabilities("latency", 5*time.Minute, false, shimesaba.AlertSeverityWarning)
+	require.NoError(t, err)
+	_, failureTime, _ = notCorrected.CalcTime(0, notCorrected.Len())
+	require.EqualValues(t, 10*time.Minute, failureTime)
+}
+
+func TestAlertEvaluateReliabilitiesReassessmentBeforeCorrection(t *testing.T) {
+	monitor := shimesaba.NewMonitor("fugara", "fugara.example.com", "host").WithEvaluator(
+		func(hostID string, timeFrame time.Duration, minSeverity shimesaba.AlertSeverity, startAt, endAt time.Time) (shimesaba.Reliabilities, bool) {
+			reliabilities, err := shimesaba.IsNoViolationCollection{
+				time.Date(2021, time.October, 1, 0, 4, 0, 0, time.UTC): false,
+				time.Date(2021, time.October, 1, 0, 5, 0, 0, time.UTC): false,
+			}.NewReliabilities(timeFrame, startAt, endAt)
+			return reliabilities, err == nil
+		},
+	)
+	alert := shimesaba.NewAlert(
+		monitor,
+		time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC),
+		ptrTime(time.Date(2021, time.October, 1, 0, 10, 0, 0, time.UTC)),
+	).WithReason("nodowntime")
+
+	reassessed, err := alert.EvaluateReliabilities("test", 5*time.Minute, true, shimesaba.AlertSeverityWarning)
+	require.NoError(t, err)
+	_, failureTime, _ := reassessed.CalcTime(0, reassessed.Len())
+	require.EqualValues(t, 2*time.Minute, failureTime, "the reassessment takes precedence over the manual correction")
+
+	corrected, err := alert.EvaluateReliabilities("test", 5*time.Minute, false, shimesaba.AlertSeverityWarning)
+	require.NoError(t, err)
+	_, failureTime, _ = corrected.CalcTime(0, corrected.Len())
+	require.EqualValues(t, 0, failureTime, "without reassessment, the manual correction is used")
+}
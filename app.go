@@ -6,14 +6,20 @@ import (
 	"fmt"
 	"log"
 	"sort"
+	"time"
 
 	"github.com/Songmu/flextime"
 	mackerel "github.com/mackerelio/mackerel-client-go"
+	"golang.org/x/sync/errgroup"
 )
 
 //App manages life cycle
 type App struct {
 	repo           *Repository
+	client         *PolicyMackerelClient
+	alertSources   []AlertSource
+	concurrency    int
+	recorder       *RecordingMackerelClient
 	SLODefinitions []*Definition
 }
 
@@ -25,18 +31,59 @@ func New(apikey string, cfg *Config) (*App, error) {
 
 //NewWithMackerelClient is there to accept mock clients.
 func NewWithMackerelClient(client MackerelClient, cfg *Config) (*App, error) {
+	var store StateStore
+	if cfg.StateStore != nil {
+		var err error
+		store, err = NewStateStore(cfg.StateStore)
+		if err != nil {
+			return nil, err
+		}
+	}
 	slo := make([]*Definition, 0, len(cfg.SLO))
 	for _, c := range cfg.SLO {
 		d, err := NewDefinition(c)
 		if err != nil {
 			return nil, err
 		}
+		if store != nil {
+			d = d.WithStateStore(store).WithStateGracePeriod(cfg.StateStore.DurationGracePeriod())
+		}
 		slo = append(slo, d)
 	}
+	policyClient, err := NewPolicyMackerelClient(client, cfg.MackerelAPI)
+	if err != nil {
+		return nil, err
+	}
 	app := &App{
-		repo:           NewRepository(client),
+		repo:           NewRepository(policyClient),
+		client:         policyClient,
+		concurrency:    cfg.Concurrency,
 		SLODefinitions: slo,
 	}
+	if recorder, ok := client.(*RecordingMackerelClient); ok {
+		app.recorder = recorder
+	}
+	if cfg.AlertCache != "" {
+		cache, err := LoadAlertCache(cfg.AlertCache)
+		if err != nil {
+			return nil, err
+		}
+		app.repo = app.repo.WithAlertCache(cache)
+	}
+	if cfg.IncidentLedger != "" {
+		ledger, err := LoadIncidentLedger(cfg.IncidentLedger)
+		if err != nil {
+			return nil, err
+		}
+		app.alertSources = append(app.alertSources, ledger)
+	}
+	for _, importCfg := range cfg.AlertImports {
+		importer, err := LoadAlertImporter(importCfg)
+		if err != nil {
+			return nil, err
+		}
+		app.alertSources = append(app.alertSources, importer)
+	}
 	return app, nil
 }
 
@@ -44,6 +91,7 @@ type Options struct {
 	dryRun      bool
 	backfill    int
 	dumpReports bool
+	concurrency int
 }
 
 //DryRunOption is an option to output the calculated error budget as standard without posting it to Mackerel.
@@ -66,9 +114,19 @@ func DumpReportsOption(dump bool) func(*Options) {
 	}
 }
 
+//ConcurrencyOption specifies how many SLO definitions are evaluated at the same time. It overrides `concurrency` of the configuration if it is over 0.
+func ConcurrencyOption(n int) func(*Options) {
+	return func(opt *Options) {
+		opt.concurrency = n
+	}
+}
+
 //Run performs the calculation of the error bar calculation
 func (app *App) Run(ctx context.Context, optFns ...func(*Options)) error {
-	orgName, err := app.repo.GetOrgName(ctx)
+	// the calls of Mackerel API in this run are canceled with ctx, and counted for the summary.
+	client := app.client.WithContext(ctx)
+	repo := app.repo.WithClient(client)
+	orgName, err := repo.GetOrgName(ctx)
 	if err != nil {
 		return err
 	}
@@ -81,46 +139,93 @@ func (app *App) Run(ctx context.Context, optFns ...func(*Options)) error {
 		optFn(opts)
 	}
 
-	repo := app.repo
 	if opts.dryRun {
 		log.Println("[notice] **with dry run**")
 		repo = repo.WithDryRun()
 	}
 
+	var provider DataProvider = repo
+	if len(app.alertSources) > 0 {
+		provider = NewAlertSourceDataProvider(repo, app.alertSources...)
+	}
+
 	if opts.backfill <= 0 {
 		return errors.New("backfill must over 0")
 	}
 	now := flextime.Now()
-
-	for _, d := range app.SLODefinitions {
-		log.Printf("[info] service level objective[id=%s]: start create reports \n", d.ID())
-		reports, err := d.CreateReports(ctx, repo, now, opts.backfill)
-		if err != nil {
-			return fmt.Errorf("service level objective[id=%s]: create report faileds: %w", d.ID(), err)
+	if app.recorder != nil {
+		// the replay is evaluated at the time of the last recorded run.
+		if err := app.recorder.saveRecordedAt(now); err != nil {
+			return err
 		}
-		if len(reports) > opts.backfill {
-			sort.Slice(reports, func(i, j int) bool {
-				return reports[i].DataPoint.Before(reports[j].DataPoint)
-			})
-			n := len(reports) - opts.backfill
-			if n < 0 {
-				n = 0
+	}
+
+	concurrency := app.concurrency
+	if opts.concurrency > 0 {
+		concurrency = opts.concurrency
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	log.Printf("[debug] evaluate %d service level objectives, concurrency=%d", len(app.SLODefinitions), concurrency)
+
+	// Each definition is run to the end even if others fail, and the errors are combined after all of them.
+	errs := make([]error, len(app.SLODefinitions))
+	var eg errgroup.Group
+	eg.SetLimit(concurrency)
+	for i, d := range app.SLODefinitions {
+		eg.Go(func() error {
+			errs[i] = app.runDefinition(ctx, repo, provider, d, now, opts)
+			if errs[i] != nil {
+				log.Printf("[warn] %s, continue with the other service level objectives", errs[i])
 			}
-			reports = reports[n:]
-		}
-		log.Printf("[info] service level objective[id=%s]: finish create reports \n", d.ID())
-		if opts.dumpReports {
-			for _, report := range reports {
-				log.Printf("[info] %s", report)
+			return nil
+		})
+	}
+	eg.Wait()
+	runTime := flextime.Now().Sub(now)
+	log.Printf("[info] Mackerel API calls: %s", client.CallSummary())
+	if err := errors.Join(errs...); err != nil {
+		failed := 0
+		for _, err := range errs {
+			if err != nil {
+				failed++
 			}
 		}
-		log.Printf("[info] service level objective[id=%s]: start save reports \n", d.ID())
-		if err := repo.SaveReports(ctx, reports); err != nil {
-			return fmt.Errorf("objective[%s] save report failed: %w", d.ID(), err)
-		}
-		log.Printf("[info] service level objective[id=%s]: finish save reports \n", d.ID())
+		log.Printf("[info] run failed in %d of %d service level objectives. run time:%s\n", failed, len(errs), runTime)
+		return err
 	}
-	runTime := flextime.Now().Sub(now)
 	log.Printf("[info] run successes. run time:%s\n", runTime)
 	return nil
 }
+
+//runDefinition creates and saves the reports of an SLO definition.
+func (app *App) runDefinition(ctx context.Context, repo *Repository, provider DataProvider, d *Definition, now time.Time, opts *Options) error {
+	log.Printf("[info] service level objective[id=%s]: start create reports \n", d.ID())
+	reports, err := d.CreateReports(ctx, provider, now, opts.backfill)
+	if err != nil {
+		return fmt.Errorf("service level objective[id=%s]: create report faileds: %w", d.ID(), err)
+	}
+	if len(reports) > opts.backfill {
+		sort.Slice(reports, func(i, j int) bool {
+			return reports[i].DataPoint.Before(reports[j].DataPoint)
+		})
+		n := len(reports) - opts.backfill
+		if n < 0 {
+			n = 0
+		}
+		reports = reports[n:]
+	}
+	log.Printf("[info] service level objective[id=%s]: finish create reports \n", d.ID())
+	if opts.dumpReports {
+		for _, report := range reports {
+			log.Printf("[info] %s", report)
+		}
+	}
+	log.Printf("[info] service level objective[id=%s]: start save reports \n", d.ID())
+	if err := repo.SaveReports(ctx, reports); err != nil {
+		return fmt.Errorf("objective[%s] save report failed: %w", d.ID(), err)
+	}
+	log.Printf("[info] service level objective[id=%s]: finish save reports \n", d.ID())
+	return nil
+}
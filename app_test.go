@@ -51,6 +51,28 @@ func TestAppWithMock(t *testing.T) {
 						"shimesaba.uptime.alerts":                              backfill,
 					},
 				},
+				{
+					configFile: "testdata/app_burn_rate_test.yaml",
+					expected: map[string]int{
+						"shimesaba.error_budget.alerts":                        backfill,
+						"shimesaba.error_budget_consumption.alerts":            backfill,
+						"shimesaba.error_budget_consumption_percentage.alerts": backfill,
+						"shimesaba.error_budget_percentage.alerts":             backfill,
+						"shimesaba.error_budget_remaining_percentage.alerts":   backfill,
+						"shimesaba.burn_rate_1m.alerts":                        backfill,
+						"shimesaba.burn_rate_5m.alerts":                        backfill,
+					},
+				},
+				{
+					configFile: "testdata/app_metric_based_test.yaml",
+					expected: map[string]int{
+						"shimesaba.error_budget.requests":                        backfill,
+						"shimesaba.error_budget_consumption.requests":            backfill,
+						"shimesaba.error_budget_consumption_percentage.requests": backfill,
+						"shimesaba.error_budget_percentage.requests":             backfill,
+						"shimesaba.error_budget_remaining_percentage.requests":   backfill,
+					},
+				},
 			}
 			for _, c := range cases {
 				t.Run(c.configFile, func(t *testing.T) {
@@ -84,3 +106,45 @@ func TestAppWithMock(t *testing.T) {
 		})
 	}
 }
+
+func TestAppConcurrency(t *testing.T) {
+	for _, concurrency := range []int{0, 1, 3} {
+		t.Run(fmt.Sprintf("concurrency=%d", concurrency), func(t *testing.T) {
+			var buf bytes.Buffer
+			logger.Setup(&buf, "debug")
+			defer func() {
+				t.Log(buf.String())
+				logger.Setup(os.Stderr, "info")
+			}()
+			cfg := shimesaba.NewDefaultConfig()
+			err := cfg.Load("testdata/app_concurrency_test.yaml")
+			require.NoError(t, err, "load cfg")
+			require.Equal(t, 3, cfg.Concurrency)
+			client := newMockMackerelClient(t)
+			app, err := shimesaba.NewWithMackerelClient(client, cfg)
+			require.NoError(t, err, "create app")
+			restore := flextime.Set(time.Date(2021, 10, 1, 0, 21, 0, 0, time.UTC))
+			defer restore()
+			err = app.Run(context.Background(), shimesaba.BackfillOption(3), shimesaba.ConcurrencyOption(concurrency))
+			require.Error(t, err, "the broken definition fails")
+			require.Contains(t, err.Error(), "service level objective[id=broken]")
+
+			actual := make(map[string]int)
+			for _, v := range client.posted {
+				actual[v.Name]++
+			}
+			require.EqualValues(t, map[string]int{
+				"shimesaba.error_budget.alerts":                          3,
+				"shimesaba.error_budget_consumption.alerts":              3,
+				"shimesaba.error_budget_consumption_percentage.alerts":   3,
+				"shimesaba.error_budget_percentage.alerts":               3,
+				"shimesaba.error_budget_remaining_percentage.alerts":     3,
+				"shimesaba.error_budget.requests":                        3,
+				"shimesaba.error_budget_consumption.requests":            3,
+				"shimesaba.error_budget_consumption_percentage.requests": 3,
+				"shimesaba.error_budget_percentage.requests":             3,
+				"shimesaba.error_budget_remaining_percentage.requests":   3,
+			}, actual, "the other definitions are reported")
+		})
+	}
+}
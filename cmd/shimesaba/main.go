@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -9,8 +10,10 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/Songmu/flextime"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/handlename/ssmwrap/v2"
+	mackerel "github.com/mackerelio/mackerel-client-go"
 	"github.com/mashiike/shimesaba"
 	"github.com/mashiike/shimesaba/internal/logger"
 	cli "github.com/urfave/cli/v2"
@@ -23,6 +26,7 @@ var (
 	globalDryRun      bool
 	globalDumpReports bool
 	globalBackfill    int
+	globalConcurrency int
 )
 
 func main() {
@@ -89,6 +93,16 @@ func main() {
 				EnvVars:     []string{"SHIMESABA_DUMP_REPORTS"},
 				Destination: &globalDumpReports,
 			},
+			&cli.StringFlag{
+				Name:    "record",
+				Usage:   "record responses of Mackerel API to the fixture directory",
+				EnvVars: []string{"SHIMESABA_RECORD"},
+			},
+			&cli.StringFlag{
+				Name:    "replay",
+				Usage:   "replay responses of Mackerel API from the fixture directory, without access to Mackerel",
+				EnvVars: []string{"SHIMESABA_REPLAY"},
+			},
 			&cli.IntFlag{
 				Name:        "backfill",
 				DefaultText: "3",
@@ -97,6 +111,12 @@ func main() {
 				EnvVars:     []string{"BACKFILL", "SHIMESABA_BACKFILL"},
 				Destination: &globalBackfill,
 			},
+			&cli.IntFlag{
+				Name:        "concurrency",
+				Usage:       "number of SLO definitions evaluated at the same time, overrides concurrency of the config file",
+				EnvVars:     []string{"SHIMESABA_CONCURRENCY"},
+				Destination: &globalConcurrency,
+			},
 		},
 		Action: run,
 		Commands: []*cli.Command{
@@ -166,6 +186,26 @@ func buildApp(c *cli.Context) (*shimesaba.App, error) {
 	if err := cfg.ValidateVersion(Version); err != nil {
 		return nil, err
 	}
+	if c.String("record") != "" && c.String("replay") != "" {
+		return nil, errors.New("record and replay can not be used at the same time")
+	}
+	if dir := c.String("replay"); dir != "" {
+		client, err := shimesaba.NewReplayingMackerelClient(dir)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("[notice] replay Mackerel API from `%s`, recorded at %s", dir, client.RecordedAt())
+		flextime.Fix(client.RecordedAt())
+		return shimesaba.NewWithMackerelClient(client, cfg)
+	}
+	if dir := c.String("record"); dir != "" {
+		client, err := shimesaba.NewRecordingMackerelClient(mackerel.NewClient(c.String("mackerel-apikey")), dir)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("[notice] record Mackerel API to `%s`", dir)
+		return shimesaba.NewWithMackerelClient(client, cfg)
+	}
 	return shimesaba.New(c.String("mackerel-apikey"), cfg)
 }
 
@@ -182,6 +222,7 @@ func run(c *cli.Context) error {
 		shimesaba.DryRunOption(c.Bool("dry-run") || globalDryRun),
 		shimesaba.DumpReportsOption(c.Bool("dump-reports") || globalDumpReports),
 		shimesaba.BackfillOption(backfill),
+		shimesaba.ConcurrencyOption(globalConcurrency),
 	}
 	handler := func(ctx context.Context) error {
 		return app.Run(ctx, optFns...)
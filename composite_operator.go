@@ -0,0 +1,12 @@
+package shimesaba
+
+// CompositeOperator is how a composite SLO combines the reliabilities of the component SLOs
+type CompositeOperator int
+
+//go:generate enumer -type=CompositeOperator -yaml -linecomment -output composite_operator_enumer.go
+
+const (
+	CompositeOperatorAnyBad   CompositeOperator = iota //any_bad
+	CompositeOperatorAllBad                            //all_bad
+	CompositeOperatorWeighted                          //weighted
+)
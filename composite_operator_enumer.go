@@ -0,0 +1,99 @@
+// Code generated by "enumer -type=CompositeOperator -yaml -linecomment -output composite_operator_enumer.go"; DO NOT EDIT.
+
+package shimesaba
+
+import (
+	"fmt"
+	"strings"
+)
+
+const _CompositeOperatorName = "any_badall_badweighted"
+
+var _CompositeOperatorIndex = [...]uint8{0, 7, 14, 22}
+
+const _CompositeOperatorLowerName = "any_badall_badweighted"
+
+func (i CompositeOperator) String() string {
+	if i < 0 || i >= CompositeOperator(len(_CompositeOperatorIndex)-1) {
+		return fmt.Sprintf("CompositeOperator(%d)", i)
+	}
+	return _CompositeOperatorName[_CompositeOperatorIndex[i]:_CompositeOperatorIndex[i+1]]
+}
+
+// An "invalid array index" compiler error signifies that the constant values have changed.
+// Re-run the stringer command to generate them again.
+func _CompositeOperatorNoOp() {
+	var x [1]struct{}
+	_ = x[CompositeOperatorAnyBad-(0)]
+	_ = x[CompositeOperatorAllBad-(1)]
+	_ = x[CompositeOperatorWeighted-(2)]
+}
+
+var _CompositeOperatorValues = []CompositeOperator{CompositeOperatorAnyBad, CompositeOperatorAllBad, CompositeOperatorWeighted}
+
+var _CompositeOperatorNameToValueMap = map[string]CompositeOperator{
+	_CompositeOperatorName[0:7]:        CompositeOperatorAnyBad,
+	_CompositeOperatorLowerName[0:7]:   CompositeOperatorAnyBad,
+	_CompositeOperatorName[7:14]:       CompositeOperatorAllBad,
+	_CompositeOperatorLowerName[7:14]:  CompositeOperatorAllBad,
+	_CompositeOperatorName[14:22]:      CompositeOperatorWeighted,
+	_CompositeOperatorLowerName[14:22]: CompositeOperatorWeighted,
+}
+
+var _CompositeOperatorNames = []string{
+	_CompositeOperatorName[0:7],
+	_CompositeOperatorName[7:14],
+	_CompositeOperatorName[14:22],
+}
+
+// CompositeOperatorString retrieves an enum value from the enum constants string name.
+// Throws an error if the param is not part of the enum.
+func CompositeOperatorString(s string) (CompositeOperator, error) {
+	if val, ok := _CompositeOperatorNameToValueMap[s]; ok {
+		return val, nil
+	}
+
+	if val, ok := _CompositeOperatorNameToValueMap[strings.ToLower(s)]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("%s does not belong to CompositeOperator values", s)
+}
+
+// CompositeOperatorValues returns all values of the enum
+func CompositeOperatorValues() []CompositeOperator {
+	return _CompositeOperatorValues
+}
+
+// CompositeOperatorStrings returns a slice of all String values of the enum
+func CompositeOperatorStrings() []string {
+	strs := make([]string, len(_CompositeOperatorNames))
+	copy(strs, _CompositeOperatorNames)
+	return strs
+}
+
+// IsACompositeOperator returns "true" if the value is listed in the enum definition. "false" otherwise
+func (i CompositeOperator) IsACompositeOperator() bool {
+	for _, v := range _CompositeOperatorValues {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalYAML implements a YAML Marshaler for CompositeOperator
+func (i CompositeOperator) MarshalYAML() (interface{}, error) {
+	return i.String(), nil
+}
+
+// UnmarshalYAML implements a YAML Unmarshaler for CompositeOperator
+func (i *CompositeOperator) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+
+	var err error
+	*i, err = CompositeOperatorString(s)
+	return err
+}
@@ -0,0 +1,159 @@
+package shimesaba
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+// CompositeSLO is an SLO that combines the per-minute reliabilities of other SLO definitions.
+type CompositeSLO struct {
+	operator   CompositeOperator
+	components []*Definition
+	weights    []float64
+}
+
+// NewCompositeSLO creates CompositeSLO from CompositeConfig.
+// The component SLOs are evaluated with the calculate interval of the composite SLO.
+func NewCompositeSLO(cfg *CompositeConfig, calculate time.Duration) (*CompositeSLO, error) {
+	components := make([]*Definition, 0, len(cfg.SLO))
+	weights := make([]float64, 0, len(cfg.SLO))
+	for _, c := range cfg.SLO {
+		if c.sloConfig == nil {
+			return nil, fmt.Errorf("composite slo id=%s is not resolved", c.ID)
+		}
+		d, err := NewDefinition(c.sloConfig)
+		if err != nil {
+			return nil, err
+		}
+		d.calculate = calculate
+		components = append(components, d)
+		weights = append(weights, c.WeightValue())
+	}
+	return &CompositeSLO{
+		operator:   cfg.OperatorValue(),
+		components: components,
+		weights:    weights,
+	}, nil
+}
+
+func (o CompositeSLO) String() string {
+	ids := make([]string, 0, len(o.components))
+	for _, d := range o.components {
+		ids = append(ids, d.ID())
+	}
+	return fmt.Sprintf("composite[%s, slo=%v]", o.operator, ids)
+}
+
+// EvaluateReliabilities evaluates the component SLOs and combines them.
+//
+//   - any_bad: the minute is a failure if any of the component SLOs failed. (same as Reliabilities.Merge)
+//   - all_bad: the minute is a failure only if all of the component SLOs failed.
+//   - weighted: the failure rate of the minute is the weighted average of the component SLOs.
+//
+// all_bad and weighted ignore the components that exclude the minute, and exclude the minute only if all of the components exclude it.
+func (o CompositeSLO) EvaluateReliabilities(ctx context.Context, provider DataProvider, startAt, endAt time.Time) (Reliabilities, error) {
+	components := make([]Reliabilities, 0, len(o.components))
+	for _, d := range o.components {
+		rc, err := d.evaluateReliabilities(ctx, provider, startAt, endAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate composite slo id=%s: %w", d.ID(), err)
+		}
+		components = append(components, rc)
+	}
+	log.Printf("[debug] %s evaluate %d components", o, len(components))
+	switch o.operator {
+	case CompositeOperatorAnyBad:
+		var reliabilities Reliabilities
+		for i, rc := range components {
+			var err error
+			reliabilities, err = reliabilities.Merge(rc)
+			if err != nil {
+				return nil, fmt.Errorf("failed to merge composite slo id=%s: %w", o.components[i].ID(), err)
+			}
+		}
+		return reliabilities, nil
+	case CompositeOperatorAllBad:
+		return combineReliabilities(components, func(rates []float64, included []bool) float64 {
+			rate := 1.0
+			for i, r := range rates {
+				if included[i] {
+					rate = math.Min(rate, r)
+				}
+			}
+			return rate
+		})
+	case CompositeOperatorWeighted:
+		return combineReliabilities(components, func(rates []float64, included []bool) float64 {
+			var sum, totalWeight float64
+			for i, r := range rates {
+				if included[i] {
+					sum += r * o.weights[i]
+					totalWeight += o.weights[i]
+				}
+			}
+			if totalWeight == 0.0 {
+				return 0.0
+			}
+			return sum / totalWeight
+		})
+	default:
+		return nil, fmt.Errorf("unknown composite operator `%s`", o.operator)
+	}
+}
+
+// combineReliabilities combines the failure rates of each minute with fn.
+// fn gets the failure rates of the components with whether the minute is included in each of them, and the excluded components are ignored.
+// A minute excluded in all of the components is excluded from the combined reliability.
+// A tumbling window that is missing in a component is treated as no failure.
+func combineReliabilities(components []Reliabilities, fn func(rates []float64, included []bool) float64) (Reliabilities, error) {
+	type window struct {
+		cursorAt  time.Time
+		timeFrame time.Duration
+		// the failure rates of the components, followed by their excluded minutes.
+		timelines []timeline
+	}
+	n := len(components)
+	windows := make(map[time.Time]*window)
+	for i, rc := range components {
+		for _, r := range rc {
+			if r.TotalEvents() > 0.0 {
+				return nil, fmt.Errorf("tumbling window at %s has events, the operator supports only alert based and threshold based SLI", r.CursorAt())
+			}
+			w, ok := windows[r.CursorAt()]
+			if !ok {
+				w = &window{
+					cursorAt:  r.CursorAt(),
+					timeFrame: r.TimeFrame(),
+					timelines: make([]timeline, 2*n),
+				}
+				windows[r.CursorAt()] = w
+			}
+			w.timelines[i] = r.includedFailureRates()
+			w.timelines[n+i] = r.excluded
+		}
+	}
+	combined := make([]*Reliability, 0, len(windows))
+	for _, w := range windows {
+		startAt := w.cursorAt.Add(-w.timeFrame)
+		included := make([]bool, n)
+		failureRates := combineTimelines(startAt, w.cursorAt, func(values []float64) float64 {
+			for i := range included {
+				included[i] = values[n+i] == 0.0
+			}
+			return fn(values[:n], included)
+		}, w.timelines...)
+		excluded := combineTimelines(startAt, w.cursorAt, func(values []float64) float64 {
+			for _, v := range values[n:] {
+				if v == 0.0 {
+					return 0.0
+				}
+			}
+			return 1.0
+		}, w.timelines...)
+		combined = append(combined, newReliabilityWithTimeline(startAt, w.timeFrame, failureRates).exclude(excluded))
+	}
+	return NewReliabilities(combined)
+}
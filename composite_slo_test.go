@@ -0,0 +1,190 @@
+package shimesaba_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mashiike/shimesaba"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompositeSLO(t *testing.T) {
+	cfg := shimesaba.NewDefaultConfig()
+	err := cfg.Load("testdata/composite_test.yaml")
+	require.NoError(t, err)
+	provider := &stubDataProvider{
+		metrics: map[string]shimesaba.MetricValues{
+			"shimesaba/availability": {
+				time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC): 0,
+				time.Date(2021, time.October, 1, 0, 1, 0, 0, time.UTC): 0,
+				time.Date(2021, time.October, 1, 0, 2, 0, 0, time.UTC): 1,
+				time.Date(2021, time.October, 1, 0, 3, 0, 0, time.UTC): 1,
+			},
+			"shimesaba/latency": {
+				time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC): 0.1,
+				time.Date(2021, time.October, 1, 0, 1, 0, 0, time.UTC): 0.8,
+				time.Date(2021, time.October, 1, 0, 2, 0, 0, time.UTC): 0.8,
+				time.Date(2021, time.October, 1, 0, 3, 0, 0, time.UTC): 0.1,
+			},
+		},
+	}
+	expected := map[string]time.Duration{
+		"availability":     2 * time.Minute,
+		"latency":          2 * time.Minute,
+		"journey_any_bad":  3 * time.Minute,
+		"journey_all_bad":  1 * time.Minute,
+		"journey_weighted": 2 * time.Minute,
+	}
+	for _, sloCfg := range cfg.SLO {
+		t.Run(sloCfg.ID, func(t *testing.T) {
+			d, err := shimesaba.NewDefinition(sloCfg)
+			require.NoError(t, err)
+			reports, err := d.CreateReports(context.Background(), provider, time.Date(2021, time.October, 1, 0, 4, 0, 0, time.UTC), 1)
+			require.NoError(t, err)
+			require.NotEmpty(t, reports)
+			actual := reports[len(reports)-1]
+			require.EqualValues(t, time.Date(2021, time.October, 1, 0, 4, 0, 0, time.UTC), actual.DataPoint)
+			require.EqualValues(t, expected[sloCfg.ID], actual.FailureTime)
+			require.EqualValues(t, 4*time.Minute-expected[sloCfg.ID], actual.UpTime)
+		})
+	}
+}
+
+func TestCompositeSLOMaintenanceWindows(t *testing.T) {
+	cfg := shimesaba.NewDefaultConfig()
+	err := cfg.Load("testdata/composite_maintenance_test.yaml")
+	require.NoError(t, err)
+	provider := &stubDataProvider{
+		metrics: map[string]shimesaba.MetricValues{
+			"shimesaba/availability": {
+				time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC): 0,
+				time.Date(2021, time.October, 1, 0, 1, 0, 0, time.UTC): 0,
+				time.Date(2021, time.October, 1, 0, 2, 0, 0, time.UTC): 1,
+				time.Date(2021, time.October, 1, 0, 3, 0, 0, time.UTC): 1,
+			},
+			"shimesaba/latency": {
+				time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC): 0.1,
+				time.Date(2021, time.October, 1, 0, 1, 0, 0, time.UTC): 0.8,
+				time.Date(2021, time.October, 1, 0, 2, 0, 0, time.UTC): 0.8,
+				time.Date(2021, time.October, 1, 0, 3, 0, 0, time.UTC): 0.1,
+			},
+		},
+	}
+	// availability fails at 00:00 and 00:01 and excludes 00:02, latency excludes 00:01 and 00:02.
+	cases := map[string]struct {
+		failureTime  time.Duration
+		excludedTime time.Duration
+	}{
+		"availability": {failureTime: 2 * time.Minute, excludedTime: time.Minute},
+		"latency":      {failureTime: 0, excludedTime: 2 * time.Minute},
+		// 00:00 fails, 00:01 and 00:02 are excluded in either of them.
+		"journey_any_bad": {failureTime: time.Minute, excludedTime: 2 * time.Minute},
+		// 00:01 fails because only availability includes it, 00:02 is excluded in both of them.
+		"journey_all_bad": {failureTime: time.Minute, excludedTime: time.Minute},
+		// 00:00 fails 3/4, and 00:01 fails fully because only availability includes it.
+		"journey_weighted": {failureTime: 105 * time.Second, excludedTime: time.Minute},
+	}
+	for _, sloCfg := range cfg.SLO {
+		t.Run(sloCfg.ID, func(t *testing.T) {
+			d, err := shimesaba.NewDefinition(sloCfg)
+			require.NoError(t, err)
+			reports, err := d.CreateReports(context.Background(), provider, time.Date(2021, time.October, 1, 0, 4, 0, 0, time.UTC), 1)
+			require.NoError(t, err)
+			require.NotEmpty(t, reports)
+			actual := reports[len(reports)-1]
+			expected := cases[sloCfg.ID]
+			require.EqualValues(t, expected.failureTime, actual.FailureTime)
+			require.EqualValues(t, expected.excludedTime, actual.ExcludedTime)
+			require.EqualValues(t, 4*time.Minute-expected.excludedTime-expected.failureTime, actual.UpTime)
+		})
+	}
+}
+
+func TestCompositeSLOConfig(t *testing.T) {
+	cases := []struct {
+		name        string
+		composite   *shimesaba.CompositeConfig
+		extra       []*shimesaba.SLOConfig
+		exceptedErr bool
+	}{
+		{
+			name: "unknown_slo",
+			composite: &shimesaba.CompositeConfig{
+				SLO: []*shimesaba.CompositeComponentConfig{{ID: "unknown"}},
+			},
+			exceptedErr: true,
+		},
+		{
+			name: "nested",
+			composite: &shimesaba.CompositeConfig{
+				SLO: []*shimesaba.CompositeComponentConfig{{ID: "journey_any_bad"}},
+			},
+			exceptedErr: true,
+		},
+		{
+			name: "unknown_operator",
+			composite: &shimesaba.CompositeConfig{
+				Operator: "xor",
+				SLO:      []*shimesaba.CompositeComponentConfig{{ID: "availability"}},
+			},
+			exceptedErr: true,
+		},
+		{
+			name: "all_bad_with_metric_based_sli",
+			composite: &shimesaba.CompositeConfig{
+				Operator: "all_bad",
+				SLO:      []*shimesaba.CompositeComponentConfig{{ID: "availability"}, {ID: "requests"}},
+			},
+			extra: []*shimesaba.SLOConfig{
+				{
+					ID: "requests",
+					MetricBasedSLI: []*shimesaba.MetricBasedSLIConfig{
+						{GoodEventMetric: "requests.2xx", TotalEventMetric: "requests.total"},
+					},
+				},
+			},
+			exceptedErr: true,
+		},
+		{
+			name: "any_bad_with_metric_based_sli",
+			composite: &shimesaba.CompositeConfig{
+				Operator: "any_bad",
+				SLO:      []*shimesaba.CompositeComponentConfig{{ID: "availability"}, {ID: "requests"}},
+			},
+			extra: []*shimesaba.SLOConfig{
+				{
+					ID: "requests",
+					MetricBasedSLI: []*shimesaba.MetricBasedSLIConfig{
+						{GoodEventMetric: "requests.2xx", TotalEventMetric: "requests.total"},
+					},
+				},
+			},
+		},
+		{
+			name: "valid",
+			composite: &shimesaba.CompositeConfig{
+				Operator: "all_bad",
+				SLO:      []*shimesaba.CompositeComponentConfig{{ID: "availability"}},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := shimesaba.NewDefaultConfig()
+			err := cfg.Load("testdata/composite_test.yaml")
+			require.NoError(t, err)
+			cfg.SLO = append(cfg.SLO, c.extra...)
+			cfg.SLO = append(cfg.SLO, &shimesaba.SLOConfig{
+				ID:        "test",
+				Composite: c.composite,
+			})
+			err = cfg.Restrict()
+			if c.exceptedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
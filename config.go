@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -21,22 +22,46 @@ type Config struct {
 	SLOConfig `yaml:"-,inline" json:"-,inline"`
 	SLO       []*SLOConfig `yaml:"slo" json:"slo"`
 
+	IncidentLedger string               `yaml:"incident_ledger,omitempty" json:"incident_ledger,omitempty"`
+	AlertImports   []*AlertImportConfig `yaml:"alert_imports,omitempty" json:"alert_imports,omitempty"`
+	AlertCache     string               `yaml:"alert_cache,omitempty" json:"alert_cache,omitempty"`
+	StateStore     *StateStoreConfig    `yaml:"state_store,omitempty" json:"state_store,omitempty"`
+	// Concurrency is the maximum number of SLO definitions evaluated at the same time. the default is 1.
+	Concurrency int                `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
+	MackerelAPI *MackerelAPIConfig `yaml:"mackerel_api,omitempty" json:"mackerel_api,omitempty"`
+
 	configFilePath     string
 	versionConstraints gv.Constraints
 }
 
 // SLOConfig is a setting related to SLI/SLO
 type SLOConfig struct {
-	ID                string                 `json:"id" yaml:"id"`
-	RollingPeriod     string                 `yaml:"rolling_period" json:"rolling_period"`
-	Destination       *DestinationConfig     `yaml:"destination" json:"destination"`
-	ErrorBudgetSize   interface{}            `yaml:"error_budget_size" json:"error_budget_size"`
-	AlertBasedSLI     []*AlertBasedSLIConfig `json:"alert_based_sli" yaml:"alert_based_sli"`
-	CalculateInterval string                 `yaml:"calculate_interval" json:"calculate_interval"`
+	ID                string                     `json:"id" yaml:"id"`
+	RollingPeriod     string                     `yaml:"rolling_period" json:"rolling_period"`
+	Window            string                     `yaml:"window,omitempty" json:"window,omitempty"`
+	TimeZone          string                     `yaml:"time_zone,omitempty" json:"time_zone,omitempty"`
+	Destination       *DestinationConfig         `yaml:"destination" json:"destination"`
+	ErrorBudgetSize   interface{}                `yaml:"error_budget_size" json:"error_budget_size"`
+	ErrorBudgetUnit   string                     `yaml:"error_budget_unit" json:"error_budget_unit"`
+	AlertBasedSLI     []*AlertBasedSLIConfig     `json:"alert_based_sli" yaml:"alert_based_sli"`
+	MetricBasedSLI    []*MetricBasedSLIConfig    `json:"metric_based_sli" yaml:"metric_based_sli"`
+	ThresholdBasedSLI []*ThresholdBasedSLIConfig `json:"threshold_based_sli" yaml:"threshold_based_sli"`
+	Composite         *CompositeConfig           `json:"composite,omitempty" yaml:"composite,omitempty"`
+	CalculateInterval string                     `yaml:"calculate_interval" json:"calculate_interval"`
+
+	// Exclude is a list of matchers for monitors that are not SLI. it applies to all alert_based_sli of the SLO.
+	Exclude []*AlertBasedSLIConfig `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+
+	MaintenanceWindows []*MaintenanceWindowConfig `json:"maintenance_windows,omitempty" yaml:"maintenance_windows,omitempty"`
+	DowntimePolicy     string                     `json:"downtime_policy,omitempty" yaml:"downtime_policy,omitempty"`
 
 	rollingPeriod             time.Duration
+	window                    WindowType
+	location                  *time.Location
 	errorBudgetSizePercentage float64
+	errorBudgetUnit           ErrorBudgetUnit
 	calculateInterval         time.Duration
+	downtimePolicy            DowntimePolicy
 }
 
 // DestinationConfig is a configuration for submitting service metrics to Mackerel
@@ -48,8 +73,13 @@ type DestinationConfig struct {
 }
 
 type DestinationMetricConfig struct {
-	MetricTypeName string `json:"metric_type_name,omitempty" yaml:"metric_type_name,omitempty"`
-	Enabled        *bool  `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	MetricTypeName string   `json:"metric_type_name,omitempty" yaml:"metric_type_name,omitempty"`
+	Enabled        *bool    `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Lookbacks      []string `json:"lookbacks,omitempty" yaml:"lookbacks,omitempty"`
+	Lookback       string   `json:"lookback,omitempty" yaml:"lookback,omitempty"`
+
+	lookbacks []time.Duration
+	lookback  time.Duration
 }
 
 type AlertBasedSLIConfig struct {
@@ -57,14 +87,131 @@ type AlertBasedSLIConfig struct {
 	MonitorName       string `json:"monitor_name,omitempty" yaml:"monitor_name,omitempty"`
 	MonitorNamePrefix string `json:"monitor_name_prefix,omitempty" yaml:"monitor_name_prefix,omitempty"`
 	MonitorNameSuffix string `json:"monitor_name_suffix,omitempty" yaml:"monitor_name_suffix,omitempty"`
+	MonitorNameRegex  string `json:"monitor_name_regex,omitempty" yaml:"monitor_name_regex,omitempty"`
 	MonitorType       string `json:"monitor_type,omitempty" yaml:"monitor_type,omitempty"`
 	TryReassessment   bool   `json:"try_reassessment,omitempty" yaml:"try_reassessment,omitempty"`
+	Severity          string `json:"severity,omitempty" yaml:"severity,omitempty"`
+	MinDuration       string `json:"min_duration,omitempty" yaml:"min_duration,omitempty"`
+	MergeGap          string `json:"merge_gap,omitempty" yaml:"merge_gap,omitempty"`
+	// Impact is the ratio of the failure caused by an alert, such as 30% for a monitor of one shard out of ten.
+	Impact interface{} `json:"impact,omitempty" yaml:"impact,omitempty"`
+
+	monitorNameRegex *regexp.Regexp
+	severity         AlertSeverity
+	minDuration      time.Duration
+	mergeGap         time.Duration
+	impact           float64
+}
+
+// MetricBasedSLIConfig is a configuration for SLI based on the ratio of good events to total events.
+type MetricBasedSLIConfig struct {
+	ServiceName      string `json:"service_name,omitempty" yaml:"service_name,omitempty"`
+	GoodEventMetric  string `json:"good_event_metric,omitempty" yaml:"good_event_metric,omitempty"`
+	TotalEventMetric string `json:"total_event_metric,omitempty" yaml:"total_event_metric,omitempty"`
+}
+
+// ThresholdBasedSLIConfig is a configuration for SLI based on comparing a host or service metric with a threshold.
+type ThresholdBasedSLIConfig struct {
+	HostID      string   `json:"host_id,omitempty" yaml:"host_id,omitempty"`
+	ServiceName string   `json:"service_name,omitempty" yaml:"service_name,omitempty"`
+	MetricName  string   `json:"metric_name,omitempty" yaml:"metric_name,omitempty"`
+	Operator    string   `json:"operator,omitempty" yaml:"operator,omitempty"`
+	Threshold   *float64 `json:"threshold,omitempty" yaml:"threshold,omitempty"`
+}
+
+// MaintenanceWindowConfig is a configuration for a scheduled period excluded from the SLO.
+// Either start_at and end_at for a one-off range, or schedule and duration for a recurring one is required.
+type MaintenanceWindowConfig struct {
+	StartAt  string `json:"start_at,omitempty" yaml:"start_at,omitempty"`
+	EndAt    string `json:"end_at,omitempty" yaml:"end_at,omitempty"`
+	Schedule string `json:"schedule,omitempty" yaml:"schedule,omitempty"`
+	Duration string `json:"duration,omitempty" yaml:"duration,omitempty"`
+	TimeZone string `json:"time_zone,omitempty" yaml:"time_zone,omitempty"`
+
+	window *MaintenanceWindow
+}
+
+// AlertImportConfig is a configuration for importing alerts from a CSV or JSON export of an external incident tool.
+type AlertImportConfig struct {
+	Path       string                    `json:"path" yaml:"path"`
+	Format     string                    `json:"format,omitempty" yaml:"format,omitempty"`
+	Columns    *AlertImportColumnsConfig `json:"columns,omitempty" yaml:"columns,omitempty"`
+	TimeFormat string                    `json:"time_format,omitempty" yaml:"time_format,omitempty"`
+	TimeZone   string                    `json:"time_zone,omitempty" yaml:"time_zone,omitempty"`
+	Rules      []*AlertImportRuleConfig  `json:"rules" yaml:"rules"`
+
+	location *time.Location
+}
+
+// AlertImportColumnsConfig is a mapping from the fields of the alert to the columns (or keys for JSON) of the export.
+type AlertImportColumnsConfig struct {
+	OpenedAt string `json:"opened_at,omitempty" yaml:"opened_at,omitempty"`
+	ClosedAt string `json:"closed_at,omitempty" yaml:"closed_at,omitempty"`
+	Title    string `json:"title,omitempty" yaml:"title,omitempty"`
+	Service  string `json:"service,omitempty" yaml:"service,omitempty"`
+}
+
+// AlertImportRuleConfig is a matcher for imported alerts, like AlertBasedSLIConfig for Mackerel monitors.
+// The matched alerts are treated as SLO violations of the SLO definitions.
+type AlertImportRuleConfig struct {
+	SLO         []string `json:"slo" yaml:"slo"`
+	Service     string   `json:"service,omitempty" yaml:"service,omitempty"`
+	Title       string   `json:"title,omitempty" yaml:"title,omitempty"`
+	TitlePrefix string   `json:"title_prefix,omitempty" yaml:"title_prefix,omitempty"`
+	TitleSuffix string   `json:"title_suffix,omitempty" yaml:"title_suffix,omitempty"`
+	TitleRegex  string   `json:"title_regex,omitempty" yaml:"title_regex,omitempty"`
+
+	titleRegex *regexp.Regexp
+}
+
+// StateStoreConfig is a configuration for the store of the evaluated reliabilities, to evaluate only new intervals in the next run.
+type StateStoreConfig struct {
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+	Path string `json:"path" yaml:"path"`
+	// GracePeriod is the period before the run in which the metric based and threshold based SLIs are always evaluated again,
+	// to count the metric values posted late. the default is 1h.
+	GracePeriod string `json:"grace_period,omitempty" yaml:"grace_period,omitempty"`
+
+	gracePeriod time.Duration
+}
+
+// MackerelAPIConfig is a configuration for the retry, the rate limit and the timeout of every call of Mackerel API.
+type MackerelAPIConfig struct {
+	MaxAttempts int    `json:"max_attempts,omitempty" yaml:"max_attempts,omitempty"`
+	MinDelay    string `json:"min_delay,omitempty" yaml:"min_delay,omitempty"`
+	MaxDelay    string `json:"max_delay,omitempty" yaml:"max_delay,omitempty"`
+	Timeout     string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// RateLimit is the maximum number of calls per second. 0 means unlimited.
+	RateLimit float64 `json:"rate_limit,omitempty" yaml:"rate_limit,omitempty"`
+	Burst     int     `json:"burst,omitempty" yaml:"burst,omitempty"`
+
+	minDelay time.Duration
+	maxDelay time.Duration
+	timeout  time.Duration
+}
+
+// CompositeConfig is a configuration for SLO that combines the reliabilities of other SLO definitions.
+type CompositeConfig struct {
+	Operator string                      `json:"operator,omitempty" yaml:"operator,omitempty"`
+	SLO      []*CompositeComponentConfig `json:"slo,omitempty" yaml:"slo,omitempty"`
+
+	operator CompositeOperator
+}
+
+// CompositeComponentConfig refers to the SLO definition that makes up the composite SLO.
+type CompositeComponentConfig struct {
+	ID     string   `json:"id" yaml:"id"`
+	Weight *float64 `json:"weight,omitempty" yaml:"weight,omitempty"`
+
+	sloConfig *SLOConfig
 }
 
 const (
 	defaultMetricPrefix = "shimesaba"
 )
 
+var defaultBurnRateLookbacks = []string{"1h", "6h", "1d", "3d"}
+
 // NewDefaultConfig creates a default configuration.
 func NewDefaultConfig() *Config {
 	return &Config{
@@ -102,20 +249,65 @@ func (c *Config) Restrict() error {
 	if len(c.SLO) == 0 {
 		return errors.New("slo definition not found")
 	}
+	if c.IncidentLedger != "" && !filepath.IsAbs(c.IncidentLedger) {
+		c.IncidentLedger = filepath.Join(c.configFilePath, c.IncidentLedger)
+	}
+	if c.AlertCache != "" && !filepath.IsAbs(c.AlertCache) {
+		c.AlertCache = filepath.Join(c.configFilePath, c.AlertCache)
+	}
+	for i, importCfg := range c.AlertImports {
+		if err := importCfg.Restrict(c.configFilePath); err != nil {
+			return fmt.Errorf("alert_imports[%d] is invalid: %w", i, err)
+		}
+	}
+	if c.StateStore != nil {
+		if err := c.StateStore.Restrict(c.configFilePath); err != nil {
+			return fmt.Errorf("state_store is invalid: %w", err)
+		}
+	}
+	if c.Concurrency < 0 {
+		return errors.New("concurrency must not be negative")
+	}
+	if c.MackerelAPI == nil {
+		c.MackerelAPI = &MackerelAPIConfig{}
+	}
+	if err := c.MackerelAPI.Restrict(); err != nil {
+		return fmt.Errorf("mackerel_api is invalid: %w", err)
+	}
 
-	sloIDs := make(map[string]struct{}, len(c.SLO))
+	sloIDs := make(map[string]*SLOConfig, len(c.SLO))
 
 	for i, cfg := range c.SLO {
 		mergedCfg := c.SLOConfig.Merge(cfg)
 		if _, ok := sloIDs[mergedCfg.ID]; ok {
 			return fmt.Errorf("slo id=%s is duplicated", mergedCfg.ID)
 		}
+		sloIDs[mergedCfg.ID] = mergedCfg
 		c.SLO[i] = mergedCfg
 		if err := mergedCfg.Restrict(); err != nil {
 			return fmt.Errorf("slo[%s] is invalid: %w", mergedCfg.ID, err)
 		}
 	}
 
+	for _, cfg := range c.SLO {
+		if cfg.Composite == nil {
+			continue
+		}
+		for i, component := range cfg.Composite.SLO {
+			componentCfg, ok := sloIDs[component.ID]
+			if !ok {
+				return fmt.Errorf("slo[%s] is invalid: composite.slo[%d] id=%s is not found", cfg.ID, i, component.ID)
+			}
+			if componentCfg.Composite != nil {
+				return fmt.Errorf("slo[%s] is invalid: composite.slo[%d] id=%s is composite, nested composite is not supported", cfg.ID, i, component.ID)
+			}
+			if cfg.Composite.OperatorValue() != CompositeOperatorAnyBad && len(componentCfg.MetricBasedSLI) > 0 {
+				return fmt.Errorf("slo[%s] is invalid: composite.slo[%d] id=%s has metric_based_sli, which is supported only by any_bad operator", cfg.ID, i, component.ID)
+			}
+			component.sloConfig = componentCfg
+		}
+	}
+
 	return nil
 }
 
@@ -125,16 +317,39 @@ func (c *SLOConfig) Restrict() error {
 		return errors.New("id is required")
 	}
 
-	if c.RollingPeriod == "" {
+	var err error
+	if c.Window == "" {
+		c.window = WindowTypeRolling
+	} else {
+		c.window, err = WindowTypeString(c.Window)
+		if err != nil {
+			return fmt.Errorf("window is invalid: %w", err)
+		}
+	}
+
+	// the calendar window does not use rolling_period.
+	if c.RollingPeriod == "" && !c.window.IsCalendar() {
 		return errors.New("rolling_period is required")
 	}
-	var err error
-	c.rollingPeriod, err = timeutils.ParseDuration(c.RollingPeriod)
-	if err != nil {
-		return fmt.Errorf("rolling_period is invalid format: %w", err)
+	if c.RollingPeriod != "" {
+		c.rollingPeriod, err = timeutils.ParseDuration(c.RollingPeriod)
+		if err != nil {
+			return fmt.Errorf("rolling_period is invalid format: %w", err)
+		}
+		if c.rollingPeriod < time.Minute {
+			return fmt.Errorf("rolling_period must over or equal 1m")
+		}
 	}
-	if c.rollingPeriod < time.Minute {
-		return fmt.Errorf("rolling_period must over or equal 1m")
+	if c.TimeZone == "" {
+		c.location = time.UTC
+	} else {
+		c.location, err = time.LoadLocation(c.TimeZone)
+		if err != nil {
+			return fmt.Errorf("time_zone is invalid: %w", err)
+		}
+	}
+	if c.TimeZone != "" && !c.window.IsCalendar() {
+		log.Printf("[warn] slo[%s]: time_zone is used only when window is calendar_month or calendar_quarter", c.ID)
 	}
 
 	if c.Destination == nil {
@@ -156,6 +371,9 @@ func (c *SLOConfig) Restrict() error {
 			}
 			c.errorBudgetSizePercentage = value / 100.0
 		} else {
+			if c.window.IsCalendar() {
+				return fmt.Errorf("error_budget must be percentage when window is %s, because the length of the calendar period is not constant", c.window)
+			}
 			errorBudgetSizeDuration, err := timeutils.ParseDuration(errorBudgetSizeString)
 			if err != nil {
 				return fmt.Errorf("error_budget can not parse as duration: %w", err)
@@ -175,6 +393,74 @@ func (c *SLOConfig) Restrict() error {
 			return fmt.Errorf("alert_based_sli[%d] %w", i, err)
 		}
 	}
+	for i, exclude := range c.Exclude {
+		if err := exclude.Restrict(); err != nil {
+			return fmt.Errorf("exclude[%d] %w", i, err)
+		}
+	}
+	if len(c.Exclude) > 0 && len(c.AlertBasedSLI) == 0 {
+		log.Printf("[warn] slo[%s]: exclude is used only with alert_based_sli", c.ID)
+	}
+	for i, metricBasedSLI := range c.MetricBasedSLI {
+		if err := metricBasedSLI.Restrict(c.Destination.ServiceName); err != nil {
+			return fmt.Errorf("metric_based_sli[%d] %w", i, err)
+		}
+	}
+	for i, thresholdBasedSLI := range c.ThresholdBasedSLI {
+		if err := thresholdBasedSLI.Restrict(c.Destination.ServiceName); err != nil {
+			return fmt.Errorf("threshold_based_sli[%d] %w", i, err)
+		}
+	}
+
+	if c.DowntimePolicy == "" {
+		c.downtimePolicy = DowntimePolicyNone
+	} else {
+		c.downtimePolicy, err = DowntimePolicyString(c.DowntimePolicy)
+		if err != nil {
+			return fmt.Errorf("downtime_policy is invalid: %w", err)
+		}
+	}
+	if c.downtimePolicy != DowntimePolicyNone && len(c.AlertBasedSLI) == 0 {
+		log.Printf("[warn] slo[%s]: downtime_policy is used only with alert_based_sli", c.ID)
+	}
+
+	for i, maintenanceWindow := range c.MaintenanceWindows {
+		if err := maintenanceWindow.Restrict(); err != nil {
+			return fmt.Errorf("maintenance_windows[%d] %w", i, err)
+		}
+	}
+
+	if c.Composite != nil {
+		if len(c.AlertBasedSLI) != 0 || len(c.MetricBasedSLI) != 0 || len(c.ThresholdBasedSLI) != 0 {
+			return errors.New("composite can not be used with alert_based_sli, metric_based_sli or threshold_based_sli")
+		}
+		if err := c.Composite.Restrict(); err != nil {
+			return fmt.Errorf("composite %w", err)
+		}
+	}
+
+	if c.ErrorBudgetUnit == "" {
+		c.errorBudgetUnit = ErrorBudgetUnitMinutes
+	} else {
+		c.errorBudgetUnit, err = ErrorBudgetUnitString(c.ErrorBudgetUnit)
+		if err != nil {
+			return fmt.Errorf("error_budget_unit is invalid: %w", err)
+		}
+	}
+	if c.errorBudgetUnit == ErrorBudgetUnitEvents {
+		if c.Composite != nil {
+			return errors.New("error_budget_unit `events` can not be used with composite")
+		}
+		if len(c.MetricBasedSLI) == 0 {
+			return errors.New("error_budget_unit `events` requires metric_based_sli")
+		}
+		if len(c.AlertBasedSLI) != 0 {
+			log.Printf("[warn] slo[%s]: alert_based_sli does not consume the error budget when error_budget_unit is `events`", c.ID)
+		}
+		if len(c.ThresholdBasedSLI) != 0 {
+			log.Printf("[warn] slo[%s]: threshold_based_sli does not consume the error budget when error_budget_unit is `events`", c.ID)
+		}
+	}
 
 	if c.CalculateInterval == "" {
 		return errors.New("calculate_interval is required")
@@ -189,6 +475,16 @@ func (c *SLOConfig) Restrict() error {
 	if c.calculateInterval >= 24*time.Hour {
 		log.Printf("[warn] We do not recommend calculate_interval=`%s` setting. because can not post service metrics older than 24 hours to Mackerel.\n", c.CalculateInterval)
 	}
+	if burnRateCfg, ok := c.Destination.Metrics[BurnRate.ID()]; ok && *burnRateCfg.Enabled {
+		for i, lookback := range burnRateCfg.DurationLookbacks() {
+			if lookback%c.calculateInterval != 0 {
+				return fmt.Errorf("destination metrics `%s`: lookbacks[%d] must be a multiple of calculate_interval", BurnRate.ID(), i)
+			}
+			if !c.window.IsCalendar() && lookback > c.rollingPeriod {
+				return fmt.Errorf("destination metrics `%s`: lookbacks[%d] must be less than or equal to rolling_period", BurnRate.ID(), i)
+			}
+		}
+	}
 
 	return nil
 }
@@ -233,11 +529,84 @@ func (c *DestinationMetricConfig) Restrict(t DestinationMetricType) error {
 		enabled := t.DefaultEnabled()
 		c.Enabled = &enabled
 	}
+	if t == TimeUntilExhaustion && c.Lookback != "" {
+		lookback, err := timeutils.ParseDuration(c.Lookback)
+		if err != nil {
+			return fmt.Errorf("lookback is invalid format: %w", err)
+		}
+		if lookback <= 0 {
+			return errors.New("lookback must over 0")
+		}
+		c.lookback = lookback
+	}
+	if t != TimeUntilExhaustion && c.Lookback != "" {
+		log.Printf("[warn] lookback is used only for %s", TimeUntilExhaustion)
+	}
+	if t != BurnRate {
+		if len(c.Lookbacks) != 0 {
+			log.Printf("[warn] lookbacks is used only for %s", BurnRate)
+		}
+		return nil
+	}
+	if len(c.Lookbacks) == 0 {
+		c.Lookbacks = defaultBurnRateLookbacks
+	}
+	c.lookbacks = make([]time.Duration, 0, len(c.Lookbacks))
+	for i, str := range c.Lookbacks {
+		lookback, err := timeutils.ParseDuration(str)
+		if err != nil {
+			return fmt.Errorf("lookbacks[%d] is invalid format: %w", i, err)
+		}
+		if lookback <= 0 {
+			return fmt.Errorf("lookbacks[%d] must over 0", i)
+		}
+		c.lookbacks = append(c.lookbacks, lookback)
+	}
 	return nil
 }
 
+// DurationLookback converts Lookback as time.Duration
+func (c *DestinationMetricConfig) DurationLookback() time.Duration {
+	return c.lookback
+}
+
+// DurationLookbacks converts Lookbacks as []time.Duration
+func (c *DestinationMetricConfig) DurationLookbacks() []time.Duration {
+	return c.lookbacks
+}
+
 // Restrict restricts a configuration.
 func (c *AlertBasedSLIConfig) Restrict() error {
+	severity, err := c.parseSeverity()
+	if err != nil {
+		return err
+	}
+	c.severity = severity
+	if c.MinDuration != "" {
+		c.minDuration, err = timeutils.ParseDuration(c.MinDuration)
+		if err != nil {
+			return fmt.Errorf("min_duration is invalid format: %w", err)
+		}
+	}
+	if c.MergeGap != "" {
+		c.mergeGap, err = timeutils.ParseDuration(c.MergeGap)
+		if err != nil {
+			return fmt.Errorf("merge_gap is invalid format: %w", err)
+		}
+	}
+	if c.Impact != nil {
+		c.impact, err = parseImpact(c.Impact)
+		if err != nil {
+			return fmt.Errorf("impact is invalid: %w", err)
+		}
+	}
+	if c.MonitorNameRegex != "" {
+		re, err := regexp.Compile(c.MonitorNameRegex)
+		if err != nil {
+			return fmt.Errorf("monitor_name_regex is invalid: %w", err)
+		}
+		c.monitorNameRegex = re
+	}
 	if c.MonitorID != "" {
 		return nil
 	}
@@ -250,11 +619,197 @@ func (c *AlertBasedSLIConfig) Restrict() error {
 	if c.MonitorNameSuffix != "" {
 		return nil
 	}
+	if c.MonitorNameRegex != "" {
+		return nil
+	}
 	if c.MonitorType != "" {
 		return nil
 	}
 
-	return errors.New("either monitor_id, monitor_name, monitor_name_prefix, monitor_name_suffix or monitor_type is required")
+	return errors.New("either monitor_id, monitor_name, monitor_name_prefix, monitor_name_suffix, monitor_name_regex or monitor_type is required")
+}
+
+func (c *AlertBasedSLIConfig) parseSeverity() (AlertSeverity, error) {
+	if c.Severity == "" {
+		return AlertSeverityWarning, nil
+	}
+	severity, err := AlertSeverityString(c.Severity)
+	if err != nil || severity == AlertSeverityUnknown {
+		return AlertSeverityUnknown, fmt.Errorf("severity must be `warning` or `critical`, got `%s`", c.Severity)
+	}
+	return severity, nil
+}
+
+// SeverityValue returns the minimum severity of alerts treated as SLO violation, default is warning.
+func (c *AlertBasedSLIConfig) SeverityValue() AlertSeverity {
+	if c.severity == AlertSeverityUnknown {
+		severity, err := c.parseSeverity()
+		if err != nil {
+			return AlertSeverityWarning
+		}
+		c.severity = severity
+	}
+	return c.severity
+}
+
+// DurationMinDuration returns the minimum sustained duration of an incident treated as SLO violation.
+func (c *AlertBasedSLIConfig) DurationMinDuration() time.Duration {
+	if c.minDuration == 0 && c.MinDuration != "" {
+		c.minDuration, _ = timeutils.ParseDuration(c.MinDuration)
+	}
+	return c.minDuration
+}
+
+// DurationMergeGap returns the maximum gap between alerts of the same monitor merged into one incident.
+func (c *AlertBasedSLIConfig) DurationMergeGap() time.Duration {
+	if c.mergeGap == 0 && c.MergeGap != "" {
+		c.mergeGap, _ = timeutils.ParseDuration(c.MergeGap)
+	}
+	return c.mergeGap
+}
+
+// ImpactValue returns the ratio of the failure caused by an alert, default is 1.0 (full outage)
+func (c *AlertBasedSLIConfig) ImpactValue() float64 {
+	if c.impact == 0.0 {
+		impact, err := parseImpact(c.Impact)
+		if err != nil {
+			return 1.0
+		}
+		c.impact = impact
+	}
+	return c.impact
+}
+
+// parseImpact parses the impact as a percentage string such as `30%` or a ratio such as 0.3.
+func parseImpact(v interface{}) (float64, error) {
+	var impact float64
+	switch v := v.(type) {
+	case nil:
+		return 1.0, nil
+	case float64:
+		impact = v
+	case int:
+		impact = float64(v)
+	case string:
+		str := strings.TrimSpace(v)
+		var err error
+		if strings.HasSuffix(str, "%") {
+			impact, err = strconv.ParseFloat(strings.TrimSuffix(str, "%"), 64)
+			impact /= 100.0
+		} else {
+			impact, err = strconv.ParseFloat(str, 64)
+		}
+		if err != nil {
+			return 0.0, fmt.Errorf("`%s` can not parse as percentage: %w", v, err)
+		}
+	default:
+		return 0.0, fmt.Errorf("unexpected type %T", v)
+	}
+	if impact <= 0.0 || impact > 1.0 {
+		return 0.0, fmt.Errorf("must be greater than 0%% and less than or equal to 100%%, got %v", v)
+	}
+	return impact, nil
+}
+
+// MonitorNameRegexp returns compiled monitor_name_regex. it returns nil if monitor_name_regex is empty.
+func (c *AlertBasedSLIConfig) MonitorNameRegexp() (*regexp.Regexp, error) {
+	if c.MonitorNameRegex == "" {
+		return nil, nil
+	}
+	if c.monitorNameRegex == nil {
+		re, err := regexp.Compile(c.MonitorNameRegex)
+		if err != nil {
+			return nil, err
+		}
+		c.monitorNameRegex = re
+	}
+	return c.monitorNameRegex, nil
+}
+
+// Restrict restricts a configuration.
+func (c *MetricBasedSLIConfig) Restrict(defaultServiceName string) error {
+	if c.ServiceName == "" {
+		log.Printf("[debug] service_name is empty, fallback %s", defaultServiceName)
+		c.ServiceName = defaultServiceName
+	}
+	if c.GoodEventMetric == "" {
+		return errors.New("good_event_metric is required")
+	}
+	if c.TotalEventMetric == "" {
+		return errors.New("total_event_metric is required")
+	}
+	return nil
+}
+
+// Restrict restricts a configuration.
+func (c *ThresholdBasedSLIConfig) Restrict(defaultServiceName string) error {
+	if c.HostID != "" && c.ServiceName != "" {
+		return errors.New("host_id and service_name can not be set at the same time")
+	}
+	if c.HostID == "" && c.ServiceName == "" {
+		log.Printf("[debug] host_id and service_name are empty, fallback service_name %s", defaultServiceName)
+		c.ServiceName = defaultServiceName
+	}
+	if c.MetricName == "" {
+		return errors.New("metric_name is required")
+	}
+	if _, err := isThresholdViolated(c.Operator, 0, 0); err != nil {
+		return fmt.Errorf("operator is invalid: %w", err)
+	}
+	if c.Threshold == nil {
+		return errors.New("threshold is required")
+	}
+	return nil
+}
+
+// Restrict restricts a configuration.
+func (c *CompositeConfig) Restrict() error {
+	if c.Operator == "" {
+		c.operator = CompositeOperatorAnyBad
+	} else {
+		var err error
+		c.operator, err = CompositeOperatorString(c.Operator)
+		if err != nil {
+			return fmt.Errorf("operator is invalid: %w", err)
+		}
+	}
+	if len(c.SLO) == 0 {
+		return errors.New("slo is required")
+	}
+	componentIDs := make(map[string]struct{}, len(c.SLO))
+	for i, component := range c.SLO {
+		if component.ID == "" {
+			return fmt.Errorf("slo[%d] id is required", i)
+		}
+		if _, ok := componentIDs[component.ID]; ok {
+			return fmt.Errorf("slo[%d] id=%s is duplicated", i, component.ID)
+		}
+		componentIDs[component.ID] = struct{}{}
+		if component.Weight == nil {
+			continue
+		}
+		if c.operator != CompositeOperatorWeighted {
+			log.Printf("[warn] composite.slo[%d] weight is ignored, because operator is `%s`", i, c.operator)
+			continue
+		}
+		if *component.Weight <= 0.0 {
+			return fmt.Errorf("slo[%d] weight must over 0.0", i)
+		}
+	}
+	return nil
+}
+
+// OperatorValue returns how to combine the reliabilities of the component SLOs
+func (c *CompositeConfig) OperatorValue() CompositeOperator {
+	return c.operator
+}
+
+// WeightValue returns the weight of the component SLO, default is 1.0
+func (c *CompositeComponentConfig) WeightValue() float64 {
+	if c.Weight == nil {
+		return 1.0
+	}
+	return *c.Weight
 }
 
 // Merge merges SLOConfig together
@@ -262,19 +817,249 @@ func (c *SLOConfig) Merge(o *SLOConfig) *SLOConfig {
 	ret := &SLOConfig{
 		ID:                coalesceString(o.ID, c.ID),
 		RollingPeriod:     coalesceString(o.RollingPeriod, c.RollingPeriod),
+		Window:            coalesceString(o.Window, c.Window),
+		TimeZone:          coalesceString(o.TimeZone, c.TimeZone),
 		Destination:       c.Destination.Merge(o.Destination),
 		ErrorBudgetSize:   c.ErrorBudgetSize,
+		ErrorBudgetUnit:   coalesceString(o.ErrorBudgetUnit, c.ErrorBudgetUnit),
 		CalculateInterval: coalesceString(o.CalculateInterval, c.CalculateInterval),
+		DowntimePolicy:    coalesceString(o.DowntimePolicy, c.DowntimePolicy),
 	}
 	if o.ErrorBudgetSize != nil {
 		ret.ErrorBudgetSize = o.ErrorBudgetSize
 	}
 	ret.AlertBasedSLI = append(ret.AlertBasedSLI, c.AlertBasedSLI...)
 	ret.AlertBasedSLI = append(ret.AlertBasedSLI, o.AlertBasedSLI...)
+	ret.Exclude = append(ret.Exclude, c.Exclude...)
+	ret.Exclude = append(ret.Exclude, o.Exclude...)
+	ret.MetricBasedSLI = append(ret.MetricBasedSLI, c.MetricBasedSLI...)
+	ret.MetricBasedSLI = append(ret.MetricBasedSLI, o.MetricBasedSLI...)
+	ret.ThresholdBasedSLI = append(ret.ThresholdBasedSLI, c.ThresholdBasedSLI...)
+	ret.ThresholdBasedSLI = append(ret.ThresholdBasedSLI, o.ThresholdBasedSLI...)
+	ret.MaintenanceWindows = append(ret.MaintenanceWindows, c.MaintenanceWindows...)
+	ret.MaintenanceWindows = append(ret.MaintenanceWindows, o.MaintenanceWindows...)
+	ret.Composite = c.Composite
+	if o.Composite != nil {
+		ret.Composite = o.Composite
+	}
 
 	return ret
 }
 
+// Restrict restricts a maintenance window configuration.
+func (c *MaintenanceWindowConfig) Restrict() error {
+	loc := time.UTC
+	if c.TimeZone != "" {
+		var err error
+		loc, err = time.LoadLocation(c.TimeZone)
+		if err != nil {
+			return fmt.Errorf("time_zone is invalid: %w", err)
+		}
+	}
+	if c.Schedule != "" {
+		if c.StartAt != "" || c.EndAt != "" {
+			return errors.New("schedule can not be used with start_at and end_at")
+		}
+		if c.Duration == "" {
+			return errors.New("duration is required when schedule is set")
+		}
+		duration, err := timeutils.ParseDuration(c.Duration)
+		if err != nil {
+			return fmt.Errorf("duration is invalid format: %w", err)
+		}
+		if duration <= 0 {
+			return errors.New("duration must be positive")
+		}
+		c.window, err = NewRecurringMaintenanceWindow(c.Schedule, loc, duration)
+		if err != nil {
+			return fmt.Errorf("schedule is invalid: %w", err)
+		}
+		return nil
+	}
+	if c.StartAt == "" || c.EndAt == "" {
+		return errors.New("either start_at and end_at, or schedule and duration is required")
+	}
+	startAt, err := parseMaintenanceWindowTime(c.StartAt, loc)
+	if err != nil {
+		return fmt.Errorf("start_at is invalid format: %w", err)
+	}
+	endAt, err := parseMaintenanceWindowTime(c.EndAt, loc)
+	if err != nil {
+		return fmt.Errorf("end_at is invalid format: %w", err)
+	}
+	if !endAt.After(startAt) {
+		return errors.New("end_at must be after start_at")
+	}
+	c.window = NewMaintenanceWindow(startAt, endAt)
+	return nil
+}
+
+// Restrict restricts an alert import configuration. a relative path is resolved from configFilePath.
+func (c *AlertImportConfig) Restrict(configFilePath string) error {
+	if c.Path == "" {
+		return errors.New("path is required")
+	}
+	if !filepath.IsAbs(c.Path) {
+		c.Path = filepath.Join(configFilePath, c.Path)
+	}
+	if c.Format == "" {
+		c.Format = strings.TrimPrefix(strings.ToLower(filepath.Ext(c.Path)), ".")
+	}
+	if c.Format != "csv" && c.Format != "json" {
+		return fmt.Errorf("format `%s` is not supported, csv or json", c.Format)
+	}
+	if c.Columns == nil {
+		c.Columns = &AlertImportColumnsConfig{}
+	}
+	c.Columns.OpenedAt = coalesceString(c.Columns.OpenedAt, "opened_at")
+	c.Columns.ClosedAt = coalesceString(c.Columns.ClosedAt, "closed_at")
+	c.Columns.Title = coalesceString(c.Columns.Title, "title")
+	c.Columns.Service = coalesceString(c.Columns.Service, "service")
+	c.location = time.UTC
+	if c.TimeZone != "" {
+		var err error
+		c.location, err = time.LoadLocation(c.TimeZone)
+		if err != nil {
+			return fmt.Errorf("time_zone is invalid: %w", err)
+		}
+	}
+	if len(c.Rules) == 0 {
+		return errors.New("rules is required")
+	}
+	for i, rule := range c.Rules {
+		if err := rule.Restrict(); err != nil {
+			return fmt.Errorf("rules[%d] %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Location returns the time zone of the times without offset in the export.
+func (c *AlertImportConfig) Location() *time.Location {
+	if c.location == nil {
+		return time.UTC
+	}
+	return c.location
+}
+
+// Restrict restricts an alert import rule configuration.
+func (c *AlertImportRuleConfig) Restrict() error {
+	if len(c.SLO) == 0 {
+		return errors.New("slo is required")
+	}
+	if c.TitleRegex != "" {
+		re, err := regexp.Compile(c.TitleRegex)
+		if err != nil {
+			return fmt.Errorf("title_regex is invalid: %w", err)
+		}
+		c.titleRegex = re
+	}
+	return nil
+}
+
+// TitleRegexp returns compiled title_regex. it returns nil if title_regex is empty.
+func (c *AlertImportRuleConfig) TitleRegexp() *regexp.Regexp {
+	return c.titleRegex
+}
+
+// Restrict restricts a state store configuration. a relative path is resolved from configFilePath.
+func (c *StateStoreConfig) Restrict(configFilePath string) error {
+	if c.Type == "" {
+		c.Type = "file"
+	}
+	if c.Type != "file" {
+		return fmt.Errorf("type `%s` is not supported, file", c.Type)
+	}
+	if c.Path == "" {
+		return errors.New("path is required")
+	}
+	if !filepath.IsAbs(c.Path) {
+		c.Path = filepath.Join(configFilePath, c.Path)
+	}
+	c.gracePeriod = defaultStateGracePeriod
+	if c.GracePeriod != "" {
+		var err error
+		c.gracePeriod, err = timeutils.ParseDuration(c.GracePeriod)
+		if err != nil {
+			return fmt.Errorf("grace_period is invalid format: %w", err)
+		}
+		if c.gracePeriod < 0 {
+			return errors.New("grace_period must not be negative")
+		}
+	}
+	return nil
+}
+
+// DurationGracePeriod returns the period in which the metric based and threshold based SLIs are always evaluated again.
+func (c *StateStoreConfig) DurationGracePeriod() time.Duration {
+	return c.gracePeriod
+}
+
+// Default values of MackerelAPIConfig.
+const (
+	defaultMackerelAPIMaxAttempts = 10
+	defaultMackerelAPIMinDelay    = time.Second
+	defaultMackerelAPIMaxDelay    = 10 * time.Second
+	defaultMackerelAPITimeout     = 30 * time.Second
+)
+
+// Restrict restricts a configuration of Mackerel API calls, and fills the default values.
+func (c *MackerelAPIConfig) Restrict() error {
+	if c.MaxAttempts < 0 {
+		return errors.New("max_attempts must not be negative")
+	}
+	if c.MaxAttempts == 0 {
+		c.MaxAttempts = defaultMackerelAPIMaxAttempts
+	}
+	durations := []struct {
+		name         string
+		str          string
+		defaultValue time.Duration
+		value        *time.Duration
+	}{
+		{name: "min_delay", str: c.MinDelay, defaultValue: defaultMackerelAPIMinDelay, value: &c.minDelay},
+		{name: "max_delay", str: c.MaxDelay, defaultValue: defaultMackerelAPIMaxDelay, value: &c.maxDelay},
+		{name: "timeout", str: c.Timeout, defaultValue: defaultMackerelAPITimeout, value: &c.timeout},
+	}
+	for _, d := range durations {
+		if d.str == "" {
+			*d.value = d.defaultValue
+			continue
+		}
+		value, err := timeutils.ParseDuration(d.str)
+		if err != nil {
+			return fmt.Errorf("%s is invalid format: %w", d.name, err)
+		}
+		*d.value = value
+	}
+	if c.maxDelay < c.minDelay {
+		return errors.New("max_delay must be longer than min_delay")
+	}
+	if c.RateLimit < 0 {
+		return errors.New("rate_limit must not be negative")
+	}
+	if c.Burst < 0 {
+		return errors.New("burst must not be negative")
+	}
+	if c.Burst == 0 {
+		c.Burst = 1
+	}
+	return nil
+}
+
+// parseMaintenanceWindowTime parses str as RFC3339, or as local time in loc if the offset is omitted.
+func parseMaintenanceWindowTime(str string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, str); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02T15:04:05", str, loc)
+}
+
+// MaintenanceWindow returns the restricted maintenance window.
+func (c *MaintenanceWindowConfig) MaintenanceWindow() *MaintenanceWindow {
+	return c.window
+}
+
 // Merge merges DestinationConfig together
 func (c *DestinationConfig) Merge(o *DestinationConfig) *DestinationConfig {
 	if o == nil {
@@ -314,6 +1099,11 @@ func (c *DestinationMetricConfig) Merge(o *DestinationMetricConfig) *Destination
 	ret := &DestinationMetricConfig{
 		MetricTypeName: coalesceString(o.MetricTypeName, c.MetricTypeName),
 		Enabled:        coalesce(o.Enabled, c.Enabled),
+		Lookbacks:      c.Lookbacks,
+		Lookback:       coalesceString(o.Lookback, c.Lookback),
+	}
+	if len(o.Lookbacks) != 0 {
+		ret.Lookbacks = o.Lookbacks
 	}
 	return ret
 }
@@ -342,6 +1132,24 @@ func (c *SLOConfig) DurationRollingPeriod() time.Duration {
 	return c.rollingPeriod
 }
 
+// DowntimePolicyValue returns how to treat Mackerel downtimes and muted monitors, default is none
+func (c *SLOConfig) DowntimePolicyValue() DowntimePolicy {
+	return c.downtimePolicy
+}
+
+// WindowValue returns the type of the time window, default is rolling
+func (c *SLOConfig) WindowValue() WindowType {
+	return c.window
+}
+
+// Location returns the time zone of the calendar period, default is UTC
+func (c *SLOConfig) Location() *time.Location {
+	if c.location == nil {
+		return time.UTC
+	}
+	return c.location
+}
+
 // DurationCalculate converts CalculateInterval as time.Duration
 func (c *SLOConfig) DurationCalculate() time.Duration {
 	return c.calculateInterval
@@ -351,6 +1159,11 @@ func (c *SLOConfig) ErrorBudgetSizePercentage() float64 {
 	return c.errorBudgetSizePercentage
 }
 
+// ErrorBudgetUnitValue converts ErrorBudgetUnit as ErrorBudgetUnit
+func (c *SLOConfig) ErrorBudgetUnitValue() ErrorBudgetUnit {
+	return c.errorBudgetUnit
+}
+
 func coalesceString(strs ...string) string {
 	for _, str := range strs {
 		if str != "" {
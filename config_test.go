@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/mashiike/shimesaba"
 	"github.com/mashiike/shimesaba/internal/logger"
@@ -170,3 +171,331 @@ func TestSLOConfigMetricPrefixSuffix(t *testing.T) {
 		})
 	}
 }
+
+func TestSLOConfigErrorBudgetUnit(t *testing.T) {
+	cases := []struct {
+		cfg         *shimesaba.SLOConfig
+		exceptedErr bool
+		expected    shimesaba.ErrorBudgetUnit
+	}{
+		{
+			cfg: &shimesaba.SLOConfig{
+				ID:            "test",
+				RollingPeriod: "28d",
+				Destination: &shimesaba.DestinationConfig{
+					ServiceName: "shimesaba",
+				},
+				CalculateInterval: "1h",
+				ErrorBudgetSize:   0.001,
+			},
+			expected: shimesaba.ErrorBudgetUnitMinutes,
+		},
+		{
+			cfg: &shimesaba.SLOConfig{
+				ID:            "test",
+				RollingPeriod: "28d",
+				Destination: &shimesaba.DestinationConfig{
+					ServiceName: "shimesaba",
+				},
+				CalculateInterval: "1h",
+				ErrorBudgetSize:   0.001,
+				ErrorBudgetUnit:   "events",
+				MetricBasedSLI: []*shimesaba.MetricBasedSLIConfig{
+					{
+						GoodEventMetric:  "requests.2xx",
+						TotalEventMetric: "requests.total",
+					},
+				},
+			},
+			expected: shimesaba.ErrorBudgetUnitEvents,
+		},
+		{
+			cfg: &shimesaba.SLOConfig{
+				ID:            "test",
+				RollingPeriod: "28d",
+				Destination: &shimesaba.DestinationConfig{
+					ServiceName: "shimesaba",
+				},
+				CalculateInterval: "1h",
+				ErrorBudgetSize:   0.001,
+				ErrorBudgetUnit:   "events",
+			},
+			exceptedErr: true,
+		},
+		{
+			cfg: &shimesaba.SLOConfig{
+				ID:            "test",
+				RollingPeriod: "28d",
+				Destination: &shimesaba.DestinationConfig{
+					ServiceName: "shimesaba",
+				},
+				CalculateInterval: "1h",
+				ErrorBudgetSize:   0.001,
+				ErrorBudgetUnit:   "requests",
+			},
+			exceptedErr: true,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case.%d", i), func(t *testing.T) {
+			err := c.cfg.Restrict()
+			if !c.exceptedErr {
+				require.NoError(t, err)
+				require.Equal(t, c.expected, c.cfg.ErrorBudgetUnitValue())
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestSLOConfigBurnRateLookbacks(t *testing.T) {
+	enabled := true
+	cases := []struct {
+		lookbacks   []string
+		exceptedErr bool
+		expected    []time.Duration
+	}{
+		{
+			expected: []time.Duration{time.Hour, 6 * time.Hour, 24 * time.Hour, 72 * time.Hour},
+		},
+		{
+			lookbacks: []string{"2h", "1d"},
+			expected:  []time.Duration{2 * time.Hour, 24 * time.Hour},
+		},
+		{
+			lookbacks:   []string{"90m"},
+			exceptedErr: true,
+		},
+		{
+			lookbacks:   []string{"29d"},
+			exceptedErr: true,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case.%d", i), func(t *testing.T) {
+			cfg := &shimesaba.SLOConfig{
+				ID:            "test",
+				RollingPeriod: "28d",
+				Destination: &shimesaba.DestinationConfig{
+					ServiceName: "shimesaba",
+					Metrics: map[string]*shimesaba.DestinationMetricConfig{
+						"burn_rate": {
+							Enabled:   &enabled,
+							Lookbacks: c.lookbacks,
+						},
+					},
+				},
+				CalculateInterval: "1h",
+				ErrorBudgetSize:   0.001,
+			}
+			err := cfg.Restrict()
+			if c.exceptedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.EqualValues(t, c.expected, cfg.Destination.Metrics["burn_rate"].DurationLookbacks())
+		})
+	}
+}
+
+func TestMaintenanceWindowConfigRestrict(t *testing.T) {
+	cases := []struct {
+		cfg         *shimesaba.MaintenanceWindowConfig
+		exceptedErr bool
+		contains    time.Time
+	}{
+		{
+			cfg: &shimesaba.MaintenanceWindowConfig{
+				StartAt: "2022-01-05T10:00:00Z",
+				EndAt:   "2022-01-05T11:00:00Z",
+			},
+			contains: time.Date(2022, 1, 5, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			cfg: &shimesaba.MaintenanceWindowConfig{
+				StartAt:  "2022-01-05T10:00:00",
+				EndAt:    "2022-01-05T11:00:00",
+				TimeZone: "Asia/Tokyo",
+			},
+			contains: time.Date(2022, 1, 5, 1, 30, 0, 0, time.UTC),
+		},
+		{
+			cfg: &shimesaba.MaintenanceWindowConfig{
+				Schedule: "0 3 * * 0",
+				Duration: "2h",
+				TimeZone: "Asia/Tokyo",
+			},
+			contains: time.Date(2022, 1, 8, 19, 0, 0, 0, time.UTC),
+		},
+		{
+			cfg: &shimesaba.MaintenanceWindowConfig{
+				StartAt: "2022-01-05T11:00:00Z",
+				EndAt:   "2022-01-05T10:00:00Z",
+			},
+			exceptedErr: true,
+		},
+		{
+			cfg: &shimesaba.MaintenanceWindowConfig{
+				Schedule: "0 3 * * 0",
+			},
+			exceptedErr: true,
+		},
+		{
+			cfg: &shimesaba.MaintenanceWindowConfig{
+				Schedule: "every sunday",
+				Duration: "2h",
+			},
+			exceptedErr: true,
+		},
+		{
+			cfg:         &shimesaba.MaintenanceWindowConfig{},
+			exceptedErr: true,
+		},
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case.%d", i), func(t *testing.T) {
+			err := c.cfg.Restrict()
+			if c.exceptedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, c.cfg.MaintenanceWindow().Contains(c.contains))
+		})
+	}
+}
+
+func TestSLOConfigDowntimePolicy(t *testing.T) {
+	cases := []struct {
+		policy      string
+		exceptedErr bool
+		expected    shimesaba.DowntimePolicy
+	}{
+		{policy: "", expected: shimesaba.DowntimePolicyNone},
+		{policy: "exclude_alerts", expected: shimesaba.DowntimePolicyExcludeAlerts},
+		{policy: "exclude_minutes", expected: shimesaba.DowntimePolicyExcludeMinutes},
+		{policy: "ignore", exceptedErr: true},
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case.%d", i), func(t *testing.T) {
+			cfg := &shimesaba.SLOConfig{
+				ID:            "test",
+				RollingPeriod: "28d",
+				Destination: &shimesaba.DestinationConfig{
+					ServiceName: "shimesaba",
+				},
+				CalculateInterval: "1h",
+				ErrorBudgetSize:   0.001,
+				DowntimePolicy:    c.policy,
+				AlertBasedSLI: []*shimesaba.AlertBasedSLIConfig{
+					{MonitorNamePrefix: "SLO"},
+				},
+			}
+			err := cfg.Restrict()
+			if c.exceptedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.expected, cfg.DowntimePolicyValue())
+		})
+	}
+}
+
+func TestSLOConfigWindow(t *testing.T) {
+	cases := []struct {
+		window        string
+		rollingPeriod string
+		exceptedErr   bool
+		expected      shimesaba.WindowType
+	}{
+		{window: "", rollingPeriod: "28d", expected: shimesaba.WindowTypeRolling},
+		{window: "", exceptedErr: true},
+		{window: "calendar_month", expected: shimesaba.WindowTypeCalendarMonth},
+		{window: "calendar_quarter", rollingPeriod: "28d", expected: shimesaba.WindowTypeCalendarQuarter},
+		{window: "calendar_year", exceptedErr: true},
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case.%d", i), func(t *testing.T) {
+			cfg := &shimesaba.SLOConfig{
+				ID:            "test",
+				RollingPeriod: c.rollingPeriod,
+				Window:        c.window,
+				Destination: &shimesaba.DestinationConfig{
+					ServiceName: "shimesaba",
+				},
+				CalculateInterval: "1h",
+				ErrorBudgetSize:   "0.1%",
+				AlertBasedSLI: []*shimesaba.AlertBasedSLIConfig{
+					{MonitorNamePrefix: "SLO"},
+				},
+			}
+			err := cfg.Restrict()
+			if c.exceptedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.expected, cfg.WindowValue())
+		})
+	}
+}
+
+func TestMackerelAPIConfigRestrict(t *testing.T) {
+	cases := []struct {
+		cfg                 *shimesaba.MackerelAPIConfig
+		exceptedErr         bool
+		expectedMaxAttempts int
+		expectedBurst       int
+	}{
+		{
+			cfg:                 &shimesaba.MackerelAPIConfig{},
+			expectedMaxAttempts: 10,
+			expectedBurst:       1,
+		},
+		{
+			cfg: &shimesaba.MackerelAPIConfig{
+				MaxAttempts: 3,
+				MinDelay:    "500ms",
+				MaxDelay:    "5s",
+				Timeout:     "10s",
+				RateLimit:   5,
+				Burst:       10,
+			},
+			expectedMaxAttempts: 3,
+			expectedBurst:       10,
+		},
+		{
+			cfg:         &shimesaba.MackerelAPIConfig{MaxAttempts: -1},
+			exceptedErr: true,
+		},
+		{
+			cfg:         &shimesaba.MackerelAPIConfig{Timeout: "soon"},
+			exceptedErr: true,
+		},
+		{
+			cfg:         &shimesaba.MackerelAPIConfig{MinDelay: "10s", MaxDelay: "1s"},
+			exceptedErr: true,
+		},
+		{
+			cfg:         &shimesaba.MackerelAPIConfig{RateLimit: -1},
+			exceptedErr: true,
+		},
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case.%d", i), func(t *testing.T) {
+			err := c.cfg.Restrict()
+			if c.exceptedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.expectedMaxAttempts, c.cfg.MaxAttempts)
+			require.Equal(t, c.expectedBurst, c.cfg.Burst)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package shimesaba
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/mashiike/shimesaba/internal/timeutils"
+)
+
+const (
+	correctionKeyword   = "downtime:"
+	noDowntimeKeyword   = "nodowntime"
+	correctionSLOPrefix = "slo:"
+)
+
+// Correction is a manual correction of the SLO violation, written by a person in the reason for closing the alert.
+type Correction struct {
+	// NoDowntime means that the alert is a false positive and is not an SLO violation at all.
+	NoDowntime bool
+	// StartAt and EndAt are the period of the SLO violation, [StartAt, EndAt).
+	StartAt time.Time
+	EndAt   time.Time
+	// SLOIDs limits the correction to the SLO definitions. empty means all SLO definitions.
+	SLOIDs []string
+}
+
+// AppliesTo reports whether the correction is applied to the SLO definition.
+func (c *Correction) AppliesTo(sloID string) bool {
+	if len(c.SLOIDs) == 0 {
+		return true
+	}
+	for _, id := range c.SLOIDs {
+		if id == "*" || id == sloID {
+			return true
+		}
+	}
+	return false
+}
+
+// Duration returns the length of the SLO violation period.
+func (c *Correction) Duration() time.Duration {
+	if c.NoDowntime {
+		return 0
+	}
+	return c.EndAt.Sub(c.StartAt)
+}
+
+func (c *Correction) String() string {
+	var str string
+	if c.NoDowntime {
+		str = noDowntimeKeyword
+	} else {
+		str = fmt.Sprintf("downtime %s ~ %s", c.StartAt.Format(time.RFC3339), c.EndAt.Format(time.RFC3339))
+	}
+	if len(c.SLOIDs) > 0 {
+		str += " for " + strings.Join(c.SLOIDs, ",")
+	}
+	return str
+}
+
+// Correction returns the manual correction written in the reason of the alert.
+//
+// The following keywords are available, separated by half-width spaces:
+//   - `downtime:3m` violates for 3 minutes from the open time.
+//   - `downtime:<start>-<end>` violates from start to end. each of them is a duration from the open time like `5m`, a time of day in UTC like `10:05`, or RFC3339.
+//   - `nodowntime` is a false positive, no violation at all.
+//   - `slo:<id>,<id>` limits the correction to the SLO definitions.
+func (alert *Alert) Correction() (*Correction, bool) {
+	c := &Correction{}
+	found := false
+	for _, token := range strings.Fields(alert.Reason) {
+		lower := strings.ToLower(token)
+		switch {
+		case lower == noDowntimeKeyword:
+			c.NoDowntime = true
+			found = true
+		case strings.HasPrefix(lower, correctionKeyword):
+			startAt, endAt, err := alert.parseCorrectionPeriod(token[len(correctionKeyword):])
+			if err != nil {
+				log.Printf("[warn] %s, try parse correction `%s` failed:%s", alert, token, err)
+				continue
+			}
+			c.StartAt, c.EndAt = startAt, endAt
+			found = true
+		case strings.HasPrefix(lower, correctionSLOPrefix):
+			for _, id := range strings.Split(token[len(correctionSLOPrefix):], ",") {
+				if id != "" {
+					c.SLOIDs = append(c.SLOIDs, id)
+				}
+			}
+		}
+	}
+	if !found {
+		return nil, false
+	}
+	return c, true
+}
+
+// CorrectionTime returns the length of the SLO violation corrected manually.
+func (alert *Alert) CorrectionTime() (time.Duration, bool) {
+	c, ok := alert.Correction()
+	if !ok {
+		return 0, false
+	}
+	return c.Duration(), true
+}
+
+func (alert *Alert) parseCorrectionPeriod(str string) (time.Time, time.Time, error) {
+	if d, err := timeutils.ParseDuration(str); err == nil {
+		return alert.OpenedAt, alert.OpenedAt.Add(d), nil
+	}
+	// RFC3339 contains '-' too, so try every position to split start and end.
+	for i := 0; i < len(str); i++ {
+		if str[i] != '-' {
+			continue
+		}
+		startAt, err := alert.parseCorrectionPoint(str[:i])
+		if err != nil {
+			continue
+		}
+		endAt, err := alert.parseCorrectionPoint(str[i+1:])
+		if err != nil {
+			continue
+		}
+		if endAt.Before(startAt) {
+			return time.Time{}, time.Time{}, fmt.Errorf("end %s is before start %s", endAt, startAt)
+		}
+		return startAt, endAt, nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("`%s` is neither duration nor <start>-<end>", str)
+}
+
+func (alert *Alert) parseCorrectionPoint(str string) (time.Time, error) {
+	if d, err := timeutils.ParseDuration(str); err == nil {
+		return alert.OpenedAt.Add(d), nil
+	}
+	if t, err := time.Parse("15:04", str); err == nil {
+		y, m, d := alert.OpenedAt.Date()
+		return time.Date(y, m, d, t.Hour(), t.Minute(), 0, 0, time.UTC), nil
+	}
+	if t, err := time.Parse(time.RFC3339, str); err == nil {
+		return t.Truncate(time.Minute).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("`%s` is not a duration, time of day or RFC3339", str)
+}
+
+// evaluateCorrection evaluates the alert as SLO violation only in the period of the correction.
+func (alert *Alert) evaluateCorrection(c *Correction, timeFrame time.Duration) (Reliabilities, error) {
+	log.Printf("[notice] applying SLO correction %s, to %s", c, alert)
+	startAt, endAt := alert.OpenedAt, alert.endAt()
+	isNoViolation := make(IsNoViolationCollection)
+	if !c.NoDowntime {
+		if c.StartAt.Before(startAt) {
+			startAt = c.StartAt
+		}
+		if c.EndAt.After(endAt) {
+			endAt = c.EndAt
+		}
+		iter := timeutils.NewIterator(c.StartAt, c.EndAt, time.Minute)
+		for iter.HasNext() {
+			t, _ := iter.Next()
+			isNoViolation[t] = false
+		}
+	}
+	return isNoViolation.NewReliabilities(timeFrame, startAt, endAt)
+}
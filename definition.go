@@ -13,26 +13,77 @@ type Definition struct {
 	id              string
 	destination     *Destination
 	rollingPeriod   time.Duration
+	window          WindowType
+	location        *time.Location
 	calculate       time.Duration
 	errorBudgetSize float64
+	errorBudgetUnit ErrorBudgetUnit
 
-	alertBasedSLIs []*AlertBasedSLI
+	alertBasedSLIs     []*AlertBasedSLI
+	virtualSLI         *AlertBasedSLI // evaluates the virtual alerts without alert based SLIs
+	metricBasedSLIs    []*MetricBasedSLI
+	thresholdBasedSLIs []*ThresholdBasedSLI
+	composite          *CompositeSLO
+	maintenanceWindows MaintenanceWindows
+	downtimePolicy     DowntimePolicy
+
+	configHash       string
+	stateStore       StateStore
+	stateGracePeriod time.Duration
 }
 
 // NewDefinition creates Definition from SLOConfig
 func NewDefinition(cfg *SLOConfig) (*Definition, error) {
+	maintenanceWindows := make(MaintenanceWindows, 0, len(cfg.MaintenanceWindows))
+	for i, windowCfg := range cfg.MaintenanceWindows {
+		if windowCfg.MaintenanceWindow() == nil {
+			if err := windowCfg.Restrict(); err != nil {
+				return nil, fmt.Errorf("slo[%s]: maintenance_windows[%d] %w", cfg.ID, i, err)
+			}
+		}
+		maintenanceWindows = append(maintenanceWindows, windowCfg.MaintenanceWindow())
+	}
 	AlertBasedSLIs := make([]*AlertBasedSLI, 0, len(cfg.AlertBasedSLI))
-	for _, cfg := range cfg.AlertBasedSLI {
-		AlertBasedSLIs = append(AlertBasedSLIs, NewAlertBasedSLI(cfg))
+	for _, sliCfg := range cfg.AlertBasedSLI {
+		AlertBasedSLIs = append(AlertBasedSLIs, NewAlertBasedSLI(sliCfg).WithExcludes(cfg.Exclude).WithSLOID(cfg.ID))
+	}
+	MetricBasedSLIs := make([]*MetricBasedSLI, 0, len(cfg.MetricBasedSLI))
+	for _, cfg := range cfg.MetricBasedSLI {
+		MetricBasedSLIs = append(MetricBasedSLIs, NewMetricBasedSLI(cfg).WithMaintenanceWindows(maintenanceWindows))
+	}
+	ThresholdBasedSLIs := make([]*ThresholdBasedSLI, 0, len(cfg.ThresholdBasedSLI))
+	for _, cfg := range cfg.ThresholdBasedSLI {
+		ThresholdBasedSLIs = append(ThresholdBasedSLIs, NewThresholdBasedSLI(cfg))
+	}
+	configHash, err := newConfigHash(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("slo[%s]: %w", cfg.ID, err)
 	}
-	return &Definition{
-		id:              cfg.ID,
-		destination:     NewDestination(cfg.Destination),
-		rollingPeriod:   cfg.DurationRollingPeriod(),
-		calculate:       cfg.DurationCalculate(),
-		errorBudgetSize: cfg.ErrorBudgetSizePercentage(),
-		alertBasedSLIs:  AlertBasedSLIs,
-	}, nil
+	d := &Definition{
+		id:                 cfg.ID,
+		destination:        NewDestination(cfg.Destination),
+		rollingPeriod:      cfg.DurationRollingPeriod(),
+		window:             cfg.WindowValue(),
+		location:           cfg.Location(),
+		calculate:          cfg.DurationCalculate(),
+		errorBudgetSize:    cfg.ErrorBudgetSizePercentage(),
+		errorBudgetUnit:    cfg.ErrorBudgetUnitValue(),
+		alertBasedSLIs:     AlertBasedSLIs,
+		virtualSLI:         NewAlertBasedSLI(&AlertBasedSLIConfig{}).WithSLOID(cfg.ID),
+		metricBasedSLIs:    MetricBasedSLIs,
+		thresholdBasedSLIs: ThresholdBasedSLIs,
+		maintenanceWindows: maintenanceWindows,
+		downtimePolicy:     cfg.DowntimePolicyValue(),
+		configHash:         configHash,
+		stateGracePeriod:   defaultStateGracePeriod,
+	}
+	if cfg.Composite != nil {
+		d.composite, err = NewCompositeSLO(cfg.Composite, d.calculate)
+		if err != nil {
+			return nil, fmt.Errorf("slo[%s]: %w", cfg.ID, err)
+		}
+	}
+	return d, nil
 }
 
 // ID returns SLOConfig.id
@@ -40,38 +91,271 @@ func (d *Definition) ID() string {
 	return d.id
 }
 
+// ConfigHash returns the hash of the SLO configuration, which keys the state saved in StateStore.
+func (d *Definition) ConfigHash() string {
+	return d.configHash
+}
+
+// WithStateStore returns Definition that saves the evaluated reliabilities to the store, and reuses them in the next run.
+func (d *Definition) WithStateStore(store StateStore) *Definition {
+	cloned := *d
+	cloned.stateStore = store
+	return &cloned
+}
+
+// WithStateGracePeriod returns Definition that always evaluates the metric based and threshold based SLIs of the period before the run again,
+// because their metric values can be posted after the interval is saved.
+func (d *Definition) WithStateGracePeriod(gracePeriod time.Duration) *Definition {
+	cloned := *d
+	cloned.stateGracePeriod = gracePeriod
+	return &cloned
+}
+
 type DataProvider interface {
 	FetchAlerts(ctx context.Context, startAt time.Time, endAt time.Time) (Alerts, error)
 	FetchVirtualAlerts(ctx context.Context, serviceName string, sloID string, startAt time.Time, endAt time.Time) (Alerts, error)
+	FetchServiceMetricValues(ctx context.Context, serviceName string, metricName string, startAt time.Time, endAt time.Time) (MetricValues, error)
+	FetchHostMetricValues(ctx context.Context, hostID string, metricName string, startAt time.Time, endAt time.Time) (MetricValues, error)
+	FetchDowntimes(ctx context.Context, startAt time.Time, endAt time.Time) (Downtimes, error)
+	FetchMonitors(ctx context.Context) ([]*Monitor, error)
 }
 
 // CreateReports returns Report with Metrics
 func (d *Definition) CreateReports(ctx context.Context, provider DataProvider, now time.Time, backfill int) ([]*Report, error) {
 	startAt := d.StartAt(now, backfill)
-	alerts, err := provider.FetchAlerts(ctx, startAt, now)
+	var reliabilities Reliabilities
+	var err error
+	if d.stateStore != nil && d.composite == nil {
+		reliabilities, err = d.evaluateReliabilitiesWithState(ctx, provider, startAt, now)
+	} else {
+		reliabilities, err = d.evaluateReliabilities(ctx, provider, startAt, now)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d.newReports(reliabilities), nil
+}
+
+// evaluateReliabilities evaluates all SLIs of the definition and merges them.
+func (d *Definition) evaluateReliabilities(ctx context.Context, provider DataProvider, startAt, endAt time.Time) (Reliabilities, error) {
+	if d.composite != nil {
+		startAt, endAt = d.truncatePeriod(startAt, endAt)
+		reliabilities, err := d.composite.EvaluateReliabilities(ctx, provider, startAt, endAt)
+		if err != nil {
+			return nil, err
+		}
+		return d.excludeMaintenanceWindows(reliabilities, d.maintenanceWindows), nil
+	}
+	alerts, maintenanceWindows, err := d.fetchAlertsAndWindows(ctx, provider, startAt, endAt)
+	if err != nil {
+		return nil, err
+	}
+	startAt, endAt = d.truncatePeriod(startAt, endAt)
+	return d.evaluateSLIs(ctx, provider, alerts, maintenanceWindows, startAt, endAt)
+}
+
+// evaluateReliabilitiesWithState evaluates only the intervals after the reliabilities saved in the state store,
+// and the intervals affected by the alerts and the excluded periods changed since the state was saved.
+func (d *Definition) evaluateReliabilitiesWithState(ctx context.Context, provider DataProvider, startAt, endAt time.Time) (Reliabilities, error) {
+	alerts, maintenanceWindows, err := d.fetchAlertsAndWindows(ctx, provider, startAt, endAt)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch alerts: %w", err)
+		return nil, err
 	}
-	log.Printf("[debug] get %d alerts", len(alerts))
-	valerts, err := provider.FetchVirtualAlerts(ctx, d.destination.ServiceName, d.id, startAt, now)
+	sources := newStateSources(alerts, maintenanceWindows)
+	startAt, endAt = d.truncatePeriod(startAt, endAt)
+	evaluateFrom := startAt
+	var stored Reliabilities
+	state, err := d.stateStore.LoadReliabilityState(ctx, d.id, d.configHash)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch virtual alerts: %w", err)
+		log.Printf("[warn] slo[%s]: %s, evaluate all intervals", d.id, err)
+	} else if state != nil {
+		graceFrom := endAt
+		if len(d.metricBasedSLIs) > 0 || len(d.thresholdBasedSLIs) > 0 {
+			graceFrom = endAt.Add(-d.stateGracePeriod)
+		}
+		evaluateFrom, stored = state.resume(startAt, d.calculate, sources, graceFrom)
+	}
+	log.Printf("[info] slo[%s]: reuse %d saved intervals, evaluate from %s", d.id, stored.Len(), evaluateFrom)
+	var reliabilities Reliabilities
+	if evaluateFrom.Before(endAt) {
+		reliabilities, err = d.evaluateSLIs(ctx, provider, d.alertsAffecting(alerts, evaluateFrom), maintenanceWindows, evaluateFrom, endAt)
+		if err != nil {
+			return nil, err
+		}
+	}
+	reliabilities, err = stored.Merge(reliabilities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge saved reliabilities: %w", err)
+	}
+	state = NewReliabilityState(d.id, d.configHash, endAt, reliabilities, sources)
+	if err := d.stateStore.SaveReliabilityState(ctx, state); err != nil {
+		log.Printf("[warn] slo[%s]: %s", d.id, err)
+	}
+	return reliabilities, nil
+}
+
+// alertsAffecting returns the alerts that can affect the intervals after startAt.
+// The alerts closed within the merge gap before startAt are kept, because flapping alerts are merged into one.
+func (d *Definition) alertsAffecting(alerts Alerts, startAt time.Time) Alerts {
+	var mergeGap time.Duration
+	for _, o := range d.alertBasedSLIs {
+		if gap := o.cfg.DurationMergeGap(); gap > mergeGap {
+			mergeGap = gap
+		}
+	}
+	affecting := make(Alerts, 0, len(alerts))
+	for _, alert := range alerts {
+		if alert.ClosedAt != nil && alert.ClosedAt.Before(startAt.Add(-mergeGap)) {
+			continue
+		}
+		affecting = append(affecting, alert)
+	}
+	return affecting
+}
+
+// fetchAlertsAndWindows fetches the alerts for the alert based SLIs and the virtual alerts, and the maintenance windows including the excluded periods and the downtimes.
+// The virtual alerts and the downtimes are fetched regardless of the SLI types, only the alerts of the monitors are skipped without alert based SLIs.
+func (d *Definition) fetchAlertsAndWindows(ctx context.Context, provider DataProvider, startAt, endAt time.Time) (Alerts, MaintenanceWindows, error) {
+	var alerts Alerts
+	maintenanceWindows := append(MaintenanceWindows{}, d.maintenanceWindows...)
+	if len(d.alertBasedSLIs) > 0 {
+		var err error
+		alerts, err = provider.FetchAlerts(ctx, startAt, endAt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch alerts: %w", err)
+		}
+		log.Printf("[debug] get %d alerts", len(alerts))
+	}
+	valerts, err := provider.FetchVirtualAlerts(ctx, d.destination.ServiceName, d.id, startAt, endAt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch virtual alerts: %w", err)
 	}
 	log.Printf("[debug] get %d virtual alerts", len(valerts))
-	alerts = append(alerts, valerts...)
-	reports, err := d.CreateReportsWithAlertsAndPeriod(ctx, alerts, startAt, now)
+	for _, valert := range valerts {
+		if valert.IsExclusion() {
+			log.Printf("[debug] %s is excluded from the SLO", valert)
+			maintenanceWindows = append(maintenanceWindows, valert.ExclusionWindow())
+			continue
+		}
+		alerts = append(alerts, valert)
+	}
+	if d.downtimePolicy != DowntimePolicyNone {
+		downtimes, err := provider.FetchDowntimes(ctx, startAt, endAt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch downtimes: %w", err)
+		}
+		log.Printf("[debug] get %d downtimes", len(downtimes))
+		var monitors []*Monitor
+		if d.downtimePolicy == DowntimePolicyExcludeMinutes && len(downtimes) > 0 {
+			monitors, err = provider.FetchMonitors(ctx)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to fetch monitors: %w", err)
+			}
+		}
+		var downtimeWindows MaintenanceWindows
+		alerts, downtimeWindows = d.applyDowntimePolicy(alerts, downtimes, monitors)
+		maintenanceWindows = append(maintenanceWindows, downtimeWindows...)
+	}
+	return alerts, maintenanceWindows, nil
+}
+
+// evaluateSLIs evaluates all SLIs of the definition between startAt and endAt, and merges them.
+func (d *Definition) evaluateSLIs(ctx context.Context, provider DataProvider, alerts Alerts, maintenanceWindows MaintenanceWindows, startAt, endAt time.Time) (Reliabilities, error) {
+	alertReliabilities, err := d.evaluateAlertBasedSLIs(alerts, startAt, endAt)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create reports: %w", err)
+		return nil, err
 	}
-	return reports, nil
+	metricReliabilities, err := d.evaluateMetricBasedSLIs(ctx, provider, startAt, endAt)
+	if err != nil {
+		return nil, err
+	}
+	thresholdReliabilities, err := d.evaluateThresholdBasedSLIs(ctx, provider, startAt, endAt)
+	if err != nil {
+		return nil, err
+	}
+	reliabilities, err := alertReliabilities.Merge(metricReliabilities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge alert based and metric based reliabilities: %w", err)
+	}
+	reliabilities, err = reliabilities.Merge(thresholdReliabilities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge threshold based reliabilities: %w", err)
+	}
+	return d.excludeMaintenanceWindows(reliabilities, maintenanceWindows), nil
+}
+
+// applyDowntimePolicy drops the open alerts of muted monitors and the alerts covered by the downtimes,
+// or returns the occurrences of the downtimes to be excluded, according to the downtime policy.
+// The occurrences are excluded only if the downtime overlaps the monitors of the alert based SLIs, found in monitors and the alerts.
+func (d *Definition) applyDowntimePolicy(alerts Alerts, downtimes Downtimes, monitors []*Monitor) (Alerts, MaintenanceWindows) {
+	filtered := make(Alerts, 0, len(alerts))
+	for _, alert := range alerts {
+		if !alert.IsVirtual() && alert.ClosedAt == nil && alert.Monitor.IsMuted() {
+			log.Printf("[debug] %s is ignored, because it is open and the monitor is muted", alert)
+			continue
+		}
+		if d.downtimePolicy == DowntimePolicyExcludeAlerts && downtimes.Covers(alert) {
+			log.Printf("[debug] %s is ignored, because it is opened during downtime", alert)
+			continue
+		}
+		filtered = append(filtered, alert)
+	}
+	if d.downtimePolicy != DowntimePolicyExcludeMinutes {
+		return filtered, nil
+	}
+	candidates := append([]*Monitor{}, monitors...)
+	for _, alert := range alerts {
+		if !alert.IsVirtual() {
+			candidates = append(candidates, alert.Monitor)
+		}
+	}
+	matched := d.AlertBasedSLIs(candidates)
+	return filtered, downtimes.Windows(func(downtime *Downtime) bool {
+		for _, monitor := range matched {
+			if downtime.OverlapsMonitor(monitor) {
+				return true
+			}
+		}
+		log.Printf("[debug] %s is ignored, because it does not overlap the monitors of the SLO", downtime)
+		return false
+	})
+}
+
+// excludeMaintenanceWindows excludes the minutes in the maintenance windows from the reliabilities.
+func (d *Definition) excludeMaintenanceWindows(reliabilities Reliabilities, maintenanceWindows MaintenanceWindows) Reliabilities {
+	if len(maintenanceWindows) == 0 || reliabilities.Len() == 0 {
+		return reliabilities
+	}
+	excluded := maintenanceWindows.timeline(
+		reliabilities[reliabilities.Len()-1].TimeFrameStartAt(),
+		reliabilities[0].CursorAt(),
+	)
+	log.Printf("[debug] %s are excluded by maintenance windows", excluded.duration())
+	return reliabilities.exclude(excluded)
 }
 
 func (d *Definition) CreateReportsWithAlertsAndPeriod(ctx context.Context, alerts Alerts, startAt, endAt time.Time) ([]*Report, error) {
+	startAt, endAt = d.truncatePeriod(startAt, endAt)
+	reliabilities, err := d.evaluateAlertBasedSLIs(alerts, startAt, endAt)
+	if err != nil {
+		return nil, err
+	}
+	return d.newReports(d.excludeMaintenanceWindows(reliabilities, d.maintenanceWindows)), nil
+}
+
+func (d *Definition) truncatePeriod(startAt, endAt time.Time) (time.Time, time.Time) {
 	log.Printf("[debug] original report range = %s ~ %s", startAt, endAt)
 	startAt = startAt.Truncate(d.calculate)
 	endAt = endAt.Add(+time.Nanosecond).Truncate(d.calculate).Add(-time.Nanosecond)
 	log.Printf("[debug] truncate report range = %s ~ %s", startAt, endAt)
 	log.Printf("[debug] timeFrame = %s, calculateInterval = %s", d.rollingPeriod, d.calculate)
+	return startAt, endAt
+}
+
+func (d *Definition) evaluateAlertBasedSLIs(alerts Alerts, startAt, endAt time.Time) (Reliabilities, error) {
+	if len(d.alertBasedSLIs) == 0 {
+		return d.evaluateVirtualAlerts(alerts, startAt, endAt)
+	}
 	var Reliabilities Reliabilities
 	log.Printf("[debug] alert based SLI count = %d", len(d.alertBasedSLIs))
 	for i, o := range d.alertBasedSLIs {
@@ -84,15 +368,76 @@ func (d *Definition) CreateReportsWithAlertsAndPeriod(ctx context.Context, alert
 			return nil, fmt.Errorf("failed to merge reliabilities for alert_based_sli[%d]: %w", i, err)
 		}
 	}
-	for _, r := range Reliabilities {
+	return Reliabilities, nil
+}
+
+// evaluateVirtualAlerts evaluates the virtual alerts as SLO violation, for the definition without alert based SLIs.
+// With alert based SLIs, the virtual alerts are evaluated by each of them instead.
+func (d *Definition) evaluateVirtualAlerts(alerts Alerts, startAt, endAt time.Time) (Reliabilities, error) {
+	virtualAlerts := make(Alerts, 0, len(alerts))
+	for _, alert := range alerts {
+		if alert.IsVirtual() {
+			virtualAlerts = append(virtualAlerts, alert)
+		}
+	}
+	if len(virtualAlerts) == 0 {
+		return nil, nil
+	}
+	log.Printf("[debug] virtual alert count = %d", len(virtualAlerts))
+	rc, err := d.virtualSLI.EvaluateReliabilities(d.calculate, virtualAlerts, startAt, endAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate reliabilities for virtual alerts: %w", err)
+	}
+	return rc, nil
+}
+
+func (d *Definition) evaluateMetricBasedSLIs(ctx context.Context, provider DataProvider, startAt, endAt time.Time) (Reliabilities, error) {
+	var Reliabilities Reliabilities
+	log.Printf("[debug] metric based SLI count = %d", len(d.metricBasedSLIs))
+	for i, o := range d.metricBasedSLIs {
+		rc, err := o.EvaluateReliabilities(ctx, provider, d.calculate, startAt, endAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate reliabilities for metric_based_sli[%d]: %w", i, err)
+		}
+		Reliabilities, err = Reliabilities.Merge(rc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge reliabilities for metric_based_sli[%d]: %w", i, err)
+		}
+	}
+	return Reliabilities, nil
+}
+
+func (d *Definition) evaluateThresholdBasedSLIs(ctx context.Context, provider DataProvider, startAt, endAt time.Time) (Reliabilities, error) {
+	var Reliabilities Reliabilities
+	log.Printf("[debug] threshold based SLI count = %d", len(d.thresholdBasedSLIs))
+	for i, o := range d.thresholdBasedSLIs {
+		rc, err := o.EvaluateReliabilities(ctx, provider, d.calculate, startAt, endAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate reliabilities for threshold_based_sli[%d]: %w", i, err)
+		}
+		Reliabilities, err = Reliabilities.Merge(rc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge reliabilities for threshold_based_sli[%d]: %w", i, err)
+		}
+	}
+	return Reliabilities, nil
+}
+
+func (d *Definition) newReports(reliabilities Reliabilities) []*Report {
+	for _, r := range reliabilities {
 		log.Printf("[debug] reliability[%s~%s] =  (%s, %s)", r.TimeFrameStartAt(), r.TimeFrameEndAt(), r.UpTime(), r.FailureTime())
 	}
-	reports := NewReports(d.id, d.destination, d.errorBudgetSize, d.rollingPeriod, Reliabilities)
+	var reports []*Report
+	if d.window.IsCalendar() {
+		reports = NewCalendarReports(d.id, d.destination, d.errorBudgetSize, d.window, d.location, d.errorBudgetUnit, reliabilities)
+	} else {
+		reports = NewReports(d.id, d.destination, d.errorBudgetSize, d.rollingPeriod, d.errorBudgetUnit, reliabilities)
+	}
 	sort.Slice(reports, func(i, j int) bool {
 		return reports[i].DataPoint.Before(reports[j].DataPoint)
 	})
 	log.Printf("[debug] created %d reports", len(reports))
-	return reports, nil
+	return reports
 }
 
 func (d *Definition) AlertBasedSLIs(monitors []*Monitor) []*Monitor {
@@ -112,5 +457,15 @@ func (d *Definition) AlertBasedSLIs(monitors []*Monitor) []*Monitor {
 }
 
 func (d *Definition) StartAt(now time.Time, backfill int) time.Time {
+	if d.window.IsCalendar() {
+		oldest := now.Truncate(d.calculate).Add(-(time.Duration(backfill) * d.calculate))
+		startAt := d.window.PeriodStartAt(oldest, d.location).UTC()
+		for _, lookback := range d.destination.BurnRateLookbacks {
+			if lookbackStartAt := oldest.Add(-lookback); lookbackStartAt.Before(startAt) {
+				startAt = lookbackStartAt
+			}
+		}
+		return startAt
+	}
 	return now.Truncate(d.calculate).Add(-(time.Duration(backfill) * d.calculate) - d.rollingPeriod)
 }
@@ -73,6 +73,8 @@ func TestDefinition(t *testing.T) {
 					ErrorBudgetSize:        3 * time.Minute,
 					ErrorBudget:            -3 * time.Minute,
 					ErrorBudgetConsumption: 4 * time.Minute,
+					ErrorBudgetRecovery24h: 6 * time.Minute,
+					ErrorBudgetRecovery7d:  6 * time.Minute,
 				},
 				{
 					DefinitionID: "alert_and_metric_mixing",
@@ -89,6 +91,8 @@ func TestDefinition(t *testing.T) {
 					ErrorBudgetSize:        3 * time.Minute,
 					ErrorBudget:            -1 * time.Minute,
 					ErrorBudgetConsumption: 0 * time.Minute,
+					ErrorBudgetRecovery24h: 4 * time.Minute,
+					ErrorBudgetRecovery7d:  4 * time.Minute,
 				},
 				{
 					DefinitionID: "alert_and_metric_mixing",
@@ -105,6 +109,8 @@ func TestDefinition(t *testing.T) {
 					ErrorBudgetSize:        3 * time.Minute,
 					ErrorBudget:            -2 * time.Minute,
 					ErrorBudgetConsumption: 5 * time.Minute,
+					ErrorBudgetRecovery24h: 5 * time.Minute,
+					ErrorBudgetRecovery7d:  5 * time.Minute,
 				},
 			},
 		},
@@ -190,6 +196,52 @@ func TestSLODefinitionStartAt(t *testing.T) {
 			},
 			expected: time.Date(2021, 1, 11, 0, 0, 0, 0, time.UTC),
 		},
+		{
+			now:      time.Date(2022, 2, 1, 3, 13, 23, 999, time.UTC),
+			backfill: 3,
+			cfg: &shimesaba.SLOConfig{
+				ID:            "test",
+				RollingPeriod: "28d",
+				Window:        "calendar_month",
+				Destination: &shimesaba.DestinationConfig{
+					ServiceName: "shimesaba",
+				},
+				CalculateInterval: "1h",
+				ErrorBudgetSize:   0.05,
+			},
+			expected: time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			now:      time.Date(2022, 2, 1, 1, 13, 23, 999, time.UTC),
+			backfill: 3,
+			cfg: &shimesaba.SLOConfig{
+				ID:            "test",
+				RollingPeriod: "28d",
+				Window:        "calendar_month",
+				Destination: &shimesaba.DestinationConfig{
+					ServiceName: "shimesaba",
+				},
+				CalculateInterval: "1h",
+				ErrorBudgetSize:   0.05,
+			},
+			expected: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			now:      time.Date(2022, 5, 14, 3, 13, 23, 999, time.UTC),
+			backfill: 3,
+			cfg: &shimesaba.SLOConfig{
+				ID:            "test",
+				RollingPeriod: "28d",
+				Window:        "calendar_quarter",
+				TimeZone:      "Asia/Tokyo",
+				Destination: &shimesaba.DestinationConfig{
+					ServiceName: "shimesaba",
+				},
+				CalculateInterval: "1h",
+				ErrorBudgetSize:   0.05,
+			},
+			expected: time.Date(2022, 3, 31, 15, 0, 0, 0, time.UTC),
+		},
 	}
 
 	for i, c := range cases {
@@ -202,3 +254,180 @@ func TestSLODefinitionStartAt(t *testing.T) {
 		})
 	}
 }
+
+type stubAlertDataProvider struct {
+	shimesaba.DataProvider
+	alerts        shimesaba.Alerts
+	virtualAlerts shimesaba.Alerts
+	downtimes     shimesaba.Downtimes
+	monitors      []*shimesaba.Monitor
+}
+
+func (p *stubAlertDataProvider) FetchAlerts(_ context.Context, _ time.Time, _ time.Time) (shimesaba.Alerts, error) {
+	return p.alerts, nil
+}
+
+func (p *stubAlertDataProvider) FetchVirtualAlerts(_ context.Context, _ string, _ string, _ time.Time, _ time.Time) (shimesaba.Alerts, error) {
+	return p.virtualAlerts, nil
+}
+
+func (p *stubAlertDataProvider) FetchDowntimes(_ context.Context, _ time.Time, _ time.Time) (shimesaba.Downtimes, error) {
+	return p.downtimes, nil
+}
+
+func (p *stubAlertDataProvider) FetchMonitors(_ context.Context) ([]*shimesaba.Monitor, error) {
+	return p.monitors, nil
+}
+
+func TestDefinitionDowntimePolicy(t *testing.T) {
+	restore := flextime.Fix(time.Date(2021, 10, 1, 1, 0, 0, 0, time.UTC))
+	defer restore()
+	inDowntime := shimesaba.NewMonitor("1", "SLO in downtime", "host")
+	muted := shimesaba.NewMonitor("2", "SLO muted", "host").WithMuted(true)
+	normal := shimesaba.NewMonitor("3", "SLO normal", "host").WithHostScopes([]string{"web: app"})
+	provider := &stubAlertDataProvider{
+		alerts: shimesaba.Alerts{
+			shimesaba.NewAlert(inDowntime, time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC), ptrTime(time.Date(2021, 10, 1, 0, 10, 0, 0, time.UTC))),
+			shimesaba.NewAlert(muted, time.Date(2021, 10, 1, 0, 30, 0, 0, time.UTC), ptrTime(time.Date(2021, 10, 1, 0, 35, 0, 0, time.UTC))),
+			shimesaba.NewAlert(normal, time.Date(2021, 10, 1, 0, 40, 0, 0, time.UTC), ptrTime(time.Date(2021, 10, 1, 0, 45, 0, 0, time.UTC))),
+			shimesaba.NewAlert(muted, time.Date(2021, 10, 1, 0, 55, 0, 0, time.UTC), nil),
+		},
+		downtimes: shimesaba.Downtimes{
+			shimesaba.NewDowntime("dt", "deploy", shimesaba.MaintenanceWindows{
+				shimesaba.NewMaintenanceWindow(time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 10, 1, 0, 20, 0, 0, time.UTC)),
+			}).WithMonitorScopes([]*shimesaba.Monitor{inDowntime}, nil),
+			shimesaba.NewDowntime("unrelated", "other service", shimesaba.MaintenanceWindows{
+				shimesaba.NewMaintenanceWindow(time.Date(2021, 10, 1, 0, 50, 0, 0, time.UTC), time.Date(2021, 10, 1, 1, 0, 0, 0, time.UTC)),
+			}).WithMonitorScopes([]*shimesaba.Monitor{shimesaba.NewMonitor("4", "batch", "host")}, nil),
+			shimesaba.NewDowntime("web", "web deploy", shimesaba.MaintenanceWindows{
+				shimesaba.NewMaintenanceWindow(time.Date(2021, 10, 1, 0, 20, 0, 0, time.UTC), time.Date(2021, 10, 1, 0, 25, 0, 0, time.UTC)),
+			}).WithServiceScopes(nil, []string{"web:app"}),
+			shimesaba.NewDowntime("batch", "batch service", shimesaba.MaintenanceWindows{
+				shimesaba.NewMaintenanceWindow(time.Date(2021, 10, 1, 0, 50, 0, 0, time.UTC), time.Date(2021, 10, 1, 1, 0, 0, 0, time.UTC)),
+			}).WithServiceScopes([]string{"batch"}, nil),
+		},
+		monitors: []*shimesaba.Monitor{
+			shimesaba.NewMonitor("4", "batch", "host").WithHostScopes([]string{"batch"}),
+		},
+	}
+	cases := []struct {
+		policy              string
+		expectedFailureTime time.Duration
+		expectedExcluded    time.Duration
+	}{
+		{policy: "", expectedFailureTime: 25 * time.Minute},
+		{policy: "exclude_alerts", expectedFailureTime: 10 * time.Minute},
+		{policy: "exclude_minutes", expectedFailureTime: 10 * time.Minute, expectedExcluded: 25 * time.Minute},
+	}
+	for _, c := range cases {
+		t.Run(c.policy, func(t *testing.T) {
+			cfg := &shimesaba.SLOConfig{
+				ID: "test",
+				Destination: &shimesaba.DestinationConfig{
+					ServiceName: "test",
+				},
+				RollingPeriod:     "1h",
+				CalculateInterval: "1h",
+				ErrorBudgetSize:   "50%",
+				DowntimePolicy:    c.policy,
+				AlertBasedSLI: []*shimesaba.AlertBasedSLIConfig{
+					{MonitorNamePrefix: "SLO"},
+				},
+			}
+			require.NoError(t, cfg.Restrict())
+			d, err := shimesaba.NewDefinition(cfg)
+			require.NoError(t, err)
+			reports, err := d.CreateReports(context.Background(), provider, flextime.Now(), 1)
+			require.NoError(t, err)
+			require.NotEmpty(t, reports)
+			report := reports[len(reports)-1]
+			require.EqualValues(t, time.Date(2021, 10, 1, 1, 0, 0, 0, time.UTC), report.DataPoint)
+			require.EqualValues(t, c.expectedFailureTime, report.FailureTime)
+			require.EqualValues(t, c.expectedExcluded, report.ExcludedTime)
+			require.EqualValues(t, time.Hour-c.expectedExcluded, report.UpTime+report.FailureTime)
+		})
+	}
+}
+
+func TestDefinitionVirtualAlerts(t *testing.T) {
+	restore := flextime.Fix(time.Date(2021, 10, 1, 1, 0, 0, 0, time.UTC))
+	defer restore()
+	provider := &stubAlertDataProvider{
+		alerts: shimesaba.Alerts{
+			shimesaba.NewAlert(shimesaba.NewMonitor("1", "SLO upstream", "host"), time.Date(2021, 10, 1, 0, 40, 0, 0, time.UTC), ptrTime(time.Date(2021, 10, 1, 0, 45, 0, 0, time.UTC))),
+		},
+		virtualAlerts: shimesaba.Alerts{
+			shimesaba.NewVirtualAlert("Partial outage SLO:test impact:50%", time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 10, 1, 0, 10, 0, 0, time.UTC)),
+			shimesaba.NewVirtualExclusionAlert("Upstream provider outage SLO-exclude:test", time.Date(2021, 10, 1, 0, 40, 0, 0, time.UTC), time.Date(2021, 10, 1, 0, 50, 0, 0, time.UTC)),
+		},
+	}
+	cfg := &shimesaba.SLOConfig{
+		ID: "test",
+		Destination: &shimesaba.DestinationConfig{
+			ServiceName: "test",
+		},
+		RollingPeriod:     "1h",
+		CalculateInterval: "1h",
+		ErrorBudgetSize:   "50%",
+		AlertBasedSLI: []*shimesaba.AlertBasedSLIConfig{
+			{MonitorNamePrefix: "SLO"},
+		},
+	}
+	require.NoError(t, cfg.Restrict())
+	d, err := shimesaba.NewDefinition(cfg)
+	require.NoError(t, err)
+	reports, err := d.CreateReports(context.Background(), provider, flextime.Now(), 1)
+	require.NoError(t, err)
+	require.NotEmpty(t, reports)
+	report := reports[len(reports)-1]
+	require.EqualValues(t, 5*time.Minute, report.FailureTime)
+	require.EqualValues(t, 10*time.Minute, report.ExcludedTime)
+}
+
+func TestDefinitionVirtualAlertsWithoutAlertBasedSLI(t *testing.T) {
+	restore := flextime.Fix(time.Date(2021, 10, 1, 1, 0, 0, 0, time.UTC))
+	defer restore()
+	latency := make(shimesaba.MetricValues)
+	for i := 0; i < 60; i++ {
+		latency[time.Date(2021, 10, 1, 0, i, 0, 0, time.UTC)] = 0.1
+	}
+	latency[time.Date(2021, 10, 1, 0, 20, 0, 0, time.UTC)] = 1.0
+	provider := &stubAlertDataProvider{
+		DataProvider: &stubDataProvider{
+			metrics: map[string]shimesaba.MetricValues{
+				"test/latency": latency,
+			},
+		},
+		virtualAlerts: shimesaba.Alerts{
+			shimesaba.NewVirtualAlert("Partial outage SLO:test impact:50%", time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 10, 1, 0, 10, 0, 0, time.UTC)),
+			shimesaba.NewVirtualExclusionAlert("Upstream provider outage SLO-exclude:test", time.Date(2021, 10, 1, 0, 40, 0, 0, time.UTC), time.Date(2021, 10, 1, 0, 50, 0, 0, time.UTC)),
+		},
+	}
+	threshold := 0.5
+	cfg := &shimesaba.SLOConfig{
+		ID: "test",
+		Destination: &shimesaba.DestinationConfig{
+			ServiceName: "test",
+		},
+		RollingPeriod:     "1h",
+		CalculateInterval: "1h",
+		ErrorBudgetSize:   "50%",
+		ThresholdBasedSLI: []*shimesaba.ThresholdBasedSLIConfig{
+			{
+				ServiceName: "test",
+				MetricName:  "latency",
+				Operator:    ">",
+				Threshold:   &threshold,
+			},
+		},
+	}
+	require.NoError(t, cfg.Restrict())
+	d, err := shimesaba.NewDefinition(cfg)
+	require.NoError(t, err)
+	reports, err := d.CreateReports(context.Background(), provider, flextime.Now(), 1)
+	require.NoError(t, err)
+	require.NotEmpty(t, reports)
+	report := reports[len(reports)-1]
+	require.EqualValues(t, 6*time.Minute, report.FailureTime, "the virtual alert is evaluated with the threshold based SLI")
+	require.EqualValues(t, 10*time.Minute, report.ExcludedTime)
+}
@@ -1,6 +1,11 @@
 package shimesaba
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+
+	"github.com/mashiike/shimesaba/internal/timeutils"
+)
 
 type Destination struct {
 	ServiceName       string
@@ -8,6 +13,9 @@ type Destination struct {
 	MetricSuffix      string
 	MetricTypeNames   map[DestinationMetricType]string
 	MetricTypeEnabled map[DestinationMetricType]bool
+	BurnRateLookbacks []time.Duration
+	// ExhaustionLookback is the period of the history used for the forecast of the error budget exhaustion. zero means default.
+	ExhaustionLookback time.Duration
 }
 
 func NewDestination(cfg *DestinationConfig) *Destination {
@@ -29,6 +37,12 @@ func NewDestination(cfg *DestinationConfig) *Destination {
 			} else {
 				ret.MetricTypeEnabled[metricType] = *metricCfg.Enabled
 			}
+			if metricType == BurnRate && ret.MetricTypeEnabled[metricType] {
+				ret.BurnRateLookbacks = metricCfg.DurationLookbacks()
+			}
+			if metricType == TimeUntilExhaustion {
+				ret.ExhaustionLookback = metricCfg.DurationLookback()
+			}
 		}
 	}
 	return ret
@@ -44,6 +58,17 @@ func (d *Destination) MetricName(metricType DestinationMetricType) string {
 	return fmt.Sprintf("%s.%s.%s", d.MetricPrefix, metricType.DefaultTypeName(), d.MetricSuffix)
 }
 
+// BurnRateMetricName returns the metric name of the burn rate for the lookback. example: shimesaba.burn_rate_1h.availability
+func (d *Destination) BurnRateMetricName(lookback time.Duration) string {
+	name := BurnRate.DefaultTypeName()
+	if d.MetricTypeNames != nil {
+		if n, ok := d.MetricTypeNames[BurnRate]; ok {
+			name = n
+		}
+	}
+	return fmt.Sprintf("%s.%s_%s.%s", d.MetricPrefix, name, timeutils.DurationString(lookback), d.MetricSuffix)
+}
+
 func (d *Destination) MetricEnabled(metricType DestinationMetricType) bool {
 	if d.MetricTypeEnabled == nil {
 		return true
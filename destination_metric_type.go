@@ -13,6 +13,10 @@ const (
 	ErrorBudgetConsumptionPercentage
 	UpTime //uptime
 	FailureTime
+	BurnRate
+	TimeUntilExhaustion
+	ErrorBudgetRecovery24h //error_budget_recovery_24h
+	ErrorBudgetRecovery7d  //error_budget_recovery_7d
 )
 
 func (t DestinationMetricType) ID() string {
@@ -25,7 +29,7 @@ func (t DestinationMetricType) DefaultTypeName() string {
 
 func (t DestinationMetricType) DefaultEnabled() bool {
 	switch t {
-	case UpTime, FailureTime:
+	case UpTime, FailureTime, BurnRate, TimeUntilExhaustion, ErrorBudgetRecovery24h, ErrorBudgetRecovery7d:
 		return false
 	default:
 		return true
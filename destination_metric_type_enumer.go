@@ -7,11 +7,11 @@ import (
 	"strings"
 )
 
-const _DestinationMetricTypeName = "error_budgeterror_budget_remaining_percentageerror_budget_percentageerror_budget_consumptionerror_budget_consumption_percentageuptimefailure_time"
+const _DestinationMetricTypeName = "error_budgeterror_budget_remaining_percentageerror_budget_percentageerror_budget_consumptionerror_budget_consumption_percentageuptimefailure_timeburn_ratetime_until_exhaustionerror_budget_recovery_24herror_budget_recovery_7d"
 
-var _DestinationMetricTypeIndex = [...]uint8{0, 12, 45, 68, 92, 127, 133, 145}
+var _DestinationMetricTypeIndex = [...]uint8{0, 12, 45, 68, 92, 127, 133, 145, 154, 175, 200, 224}
 
-const _DestinationMetricTypeLowerName = "error_budgeterror_budget_remaining_percentageerror_budget_percentageerror_budget_consumptionerror_budget_consumption_percentageuptimefailure_time"
+const _DestinationMetricTypeLowerName = "error_budgeterror_budget_remaining_percentageerror_budget_percentageerror_budget_consumptionerror_budget_consumption_percentageuptimefailure_timeburn_ratetime_until_exhaustionerror_budget_recovery_24herror_budget_recovery_7d"
 
 func (i DestinationMetricType) String() string {
 	if i < 0 || i >= DestinationMetricType(len(_DestinationMetricTypeIndex)-1) {
@@ -31,9 +31,13 @@ func _DestinationMetricTypeNoOp() {
 	_ = x[ErrorBudgetConsumptionPercentage-(4)]
 	_ = x[UpTime-(5)]
 	_ = x[FailureTime-(6)]
+	_ = x[BurnRate-(7)]
+	_ = x[TimeUntilExhaustion-(8)]
+	_ = x[ErrorBudgetRecovery24h-(9)]
+	_ = x[ErrorBudgetRecovery7d-(10)]
 }
 
-var _DestinationMetricTypeValues = []DestinationMetricType{ErrorBudget, ErrorBudgetRemainingPercentage, ErrorBudgetPercentage, ErrorBudgetConsumption, ErrorBudgetConsumptionPercentage, UpTime, FailureTime}
+var _DestinationMetricTypeValues = []DestinationMetricType{ErrorBudget, ErrorBudgetRemainingPercentage, ErrorBudgetPercentage, ErrorBudgetConsumption, ErrorBudgetConsumptionPercentage, UpTime, FailureTime, BurnRate, TimeUntilExhaustion, ErrorBudgetRecovery24h, ErrorBudgetRecovery7d}
 
 var _DestinationMetricTypeNameToValueMap = map[string]DestinationMetricType{
 	_DestinationMetricTypeName[0:12]:         ErrorBudget,
@@ -50,6 +54,14 @@ var _DestinationMetricTypeNameToValueMap = map[string]DestinationMetricType{
 	_DestinationMetricTypeLowerName[127:133]: UpTime,
 	_DestinationMetricTypeName[133:145]:      FailureTime,
 	_DestinationMetricTypeLowerName[133:145]: FailureTime,
+	_DestinationMetricTypeName[145:154]:      BurnRate,
+	_DestinationMetricTypeLowerName[145:154]: BurnRate,
+	_DestinationMetricTypeName[154:175]:      TimeUntilExhaustion,
+	_DestinationMetricTypeLowerName[154:175]: TimeUntilExhaustion,
+	_DestinationMetricTypeName[175:200]:      ErrorBudgetRecovery24h,
+	_DestinationMetricTypeLowerName[175:200]: ErrorBudgetRecovery24h,
+	_DestinationMetricTypeName[200:224]:      ErrorBudgetRecovery7d,
+	_DestinationMetricTypeLowerName[200:224]: ErrorBudgetRecovery7d,
 }
 
 var _DestinationMetricTypeNames = []string{
@@ -60,6 +72,10 @@ var _DestinationMetricTypeNames = []string{
 	_DestinationMetricTypeName[92:127],
 	_DestinationMetricTypeName[127:133],
 	_DestinationMetricTypeName[133:145],
+	_DestinationMetricTypeName[145:154],
+	_DestinationMetricTypeName[154:175],
+	_DestinationMetricTypeName[175:200],
+	_DestinationMetricTypeName[200:224],
 }
 
 // DestinationMetricTypeString retrieves an enum value from the enum constants string name.
@@ -0,0 +1,186 @@
+package shimesaba
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Downtime is a Mackerel downtime, the periods in which alerts of the monitors in the scopes are silenced.
+type Downtime struct {
+	id      string
+	name    string
+	windows MaintenanceWindows
+
+	monitors          []*Monitor
+	excludeMonitorIDs map[string]bool
+	hostIDs           map[string]bool
+	excludeHostIDs    map[string]bool
+	hostScopes        []hostScope
+}
+
+// NewDowntime creates Downtime. windows are the occurrences of the downtime.
+// Without scopes, the downtime covers all monitors and hosts.
+func NewDowntime(id, name string, windows MaintenanceWindows) *Downtime {
+	return &Downtime{
+		id:      id,
+		name:    name,
+		windows: windows,
+	}
+}
+
+// WithMonitorScopes returns Downtime that covers only the monitors, except for excludeMonitorIDs.
+// If monitors is nil, all monitors are covered.
+func (d *Downtime) WithMonitorScopes(monitors []*Monitor, excludeMonitorIDs []string) *Downtime {
+	cloned := *d
+	cloned.monitors = monitors
+	cloned.excludeMonitorIDs = make(map[string]bool, len(excludeMonitorIDs))
+	for _, id := range excludeMonitorIDs {
+		cloned.excludeMonitorIDs[id] = true
+	}
+	return &cloned
+}
+
+// WithHostScopes returns Downtime that covers only the alerts of hostIDs, except for excludeHostIDs.
+// If hostIDs is nil, alerts of all hosts and alerts without host are covered.
+func (d *Downtime) WithHostScopes(hostIDs, excludeHostIDs []string) *Downtime {
+	cloned := *d
+	cloned.hostIDs = nil
+	if hostIDs != nil {
+		cloned.hostIDs = make(map[string]bool, len(hostIDs))
+		for _, id := range hostIDs {
+			cloned.hostIDs[id] = true
+		}
+	}
+	cloned.excludeHostIDs = make(map[string]bool, len(excludeHostIDs))
+	for _, id := range excludeHostIDs {
+		cloned.excludeHostIDs[id] = true
+	}
+	return &cloned
+}
+
+// WithServiceScopes returns Downtime that records the service and role scopes, formatted as `service: role`.
+// They are used to find the monitors whose hosts can be in the downtime, the hosts of the scopes are set by WithHostScopes.
+func (d *Downtime) WithServiceScopes(services, roles []string) *Downtime {
+	cloned := *d
+	cloned.hostScopes = append(parseHostScopes(services), parseHostScopes(roles)...)
+	return &cloned
+}
+
+func (d *Downtime) String() string {
+	return fmt.Sprintf("downtime[%s:%s]", d.id, d.name)
+}
+
+// Windows returns the occurrences of the downtime
+func (d *Downtime) Windows() MaintenanceWindows {
+	return d.windows
+}
+
+// Monitors returns the monitors in the scopes. nil means all monitors.
+func (d *Downtime) Monitors() []*Monitor {
+	return d.monitors
+}
+
+// CoversMonitor reports whether the monitor is in the scopes of the downtime, regardless of hosts.
+func (d *Downtime) CoversMonitor(monitor *Monitor) bool {
+	if monitor == nil || d.excludeMonitorIDs[monitor.ID()] {
+		return false
+	}
+	if d.monitors == nil {
+		return true
+	}
+	for _, m := range d.monitors {
+		if m.ID() == monitor.ID() {
+			return true
+		}
+	}
+	return false
+}
+
+// OverlapsMonitor reports whether the monitor is in the scopes of the downtime, and it can monitor the hosts in the service and role scopes.
+func (d *Downtime) OverlapsMonitor(monitor *Monitor) bool {
+	if !d.CoversMonitor(monitor) {
+		return false
+	}
+	if len(d.hostScopes) == 0 {
+		return true
+	}
+	if !monitor.forHosts {
+		return false
+	}
+	if len(monitor.hostScopes) == 0 {
+		return true
+	}
+	for _, scope := range d.hostScopes {
+		for _, monitorScope := range monitor.hostScopes {
+			if scope.overlaps(monitorScope) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Covers reports whether the alert was opened during the downtime and is in the scopes.
+func (d *Downtime) Covers(alert *Alert) bool {
+	if alert.IsVirtual() || !d.CoversMonitor(alert.Monitor) {
+		return false
+	}
+	if d.excludeHostIDs[alert.HostID] {
+		return false
+	}
+	if d.hostIDs != nil && !d.hostIDs[alert.HostID] {
+		return false
+	}
+	return d.windows.Contains(alert.OpenedAt)
+}
+
+// Downtimes is a collection of Downtime
+type Downtimes []*Downtime
+
+// Covers reports whether any of the downtimes covers the alert.
+func (downtimes Downtimes) Covers(alert *Alert) bool {
+	for _, d := range downtimes {
+		if d.Covers(alert) {
+			return true
+		}
+	}
+	return false
+}
+
+// Windows returns the occurrences of all downtimes that match the filter.
+func (downtimes Downtimes) Windows(filter func(*Downtime) bool) MaintenanceWindows {
+	windows := make(MaintenanceWindows, 0)
+	for _, d := range downtimes {
+		if filter(d) {
+			windows = append(windows, d.windows...)
+		}
+	}
+	return windows
+}
+
+// hostScope is a service, or a role of the service if role is not empty.
+type hostScope struct {
+	service string
+	role    string
+}
+
+// parseHostScopes parses the scopes formatted as `service` or `service: role`.
+func parseHostScopes(scopes []string) []hostScope {
+	parsed := make([]hostScope, 0, len(scopes))
+	for _, scope := range scopes {
+		service, role, _ := strings.Cut(scope, ":")
+		parsed = append(parsed, hostScope{
+			service: strings.TrimSpace(service),
+			role:    strings.TrimSpace(role),
+		})
+	}
+	return parsed
+}
+
+// overlaps reports whether some hosts can be in both scopes.
+func (s hostScope) overlaps(other hostScope) bool {
+	if s.service != other.service {
+		return false
+	}
+	return s.role == "" || other.role == "" || s.role == other.role
+}
@@ -0,0 +1,12 @@
+package shimesaba
+
+// DowntimePolicy is how an SLO treats Mackerel downtimes and muted monitors
+type DowntimePolicy int
+
+//go:generate enumer -type=DowntimePolicy -yaml -linecomment -output downtime_policy_enumer.go
+
+const (
+	DowntimePolicyNone           DowntimePolicy = iota //none
+	DowntimePolicyExcludeAlerts                        //exclude_alerts
+	DowntimePolicyExcludeMinutes                       //exclude_minutes
+)
@@ -0,0 +1,99 @@
+// Code generated by "enumer -type=DowntimePolicy -yaml -linecomment -output downtime_policy_enumer.go"; DO NOT EDIT.
+
+package shimesaba
+
+import (
+	"fmt"
+	"strings"
+)
+
+const _DowntimePolicyName = "noneexclude_alertsexclude_minutes"
+
+var _DowntimePolicyIndex = [...]uint8{0, 4, 18, 33}
+
+const _DowntimePolicyLowerName = "noneexclude_alertsexclude_minutes"
+
+func (i DowntimePolicy) String() string {
+	if i < 0 || i >= DowntimePolicy(len(_DowntimePolicyIndex)-1) {
+		return fmt.Sprintf("DowntimePolicy(%d)", i)
+	}
+	return _DowntimePolicyName[_DowntimePolicyIndex[i]:_DowntimePolicyIndex[i+1]]
+}
+
+// An "invalid array index" compiler error signifies that the constant values have changed.
+// Re-run the stringer command to generate them again.
+func _DowntimePolicyNoOp() {
+	var x [1]struct{}
+	_ = x[DowntimePolicyNone-(0)]
+	_ = x[DowntimePolicyExcludeAlerts-(1)]
+	_ = x[DowntimePolicyExcludeMinutes-(2)]
+}
+
+var _DowntimePolicyValues = []DowntimePolicy{DowntimePolicyNone, DowntimePolicyExcludeAlerts, DowntimePolicyExcludeMinutes}
+
+var _DowntimePolicyNameToValueMap = map[string]DowntimePolicy{
+	_DowntimePolicyName[0:4]:        DowntimePolicyNone,
+	_DowntimePolicyLowerName[0:4]:   DowntimePolicyNone,
+	_DowntimePolicyName[4:18]:       DowntimePolicyExcludeAlerts,
+	_DowntimePolicyLowerName[4:18]:  DowntimePolicyExcludeAlerts,
+	_DowntimePolicyName[18:33]:      DowntimePolicyExcludeMinutes,
+	_DowntimePolicyLowerName[18:33]: DowntimePolicyExcludeMinutes,
+}
+
+var _DowntimePolicyNames = []string{
+	_DowntimePolicyName[0:4],
+	_DowntimePolicyName[4:18],
+	_DowntimePolicyName[18:33],
+}
+
+// DowntimePolicyString retrieves an enum value from the enum constants string name.
+// Throws an error if the param is not part of the enum.
+func DowntimePolicyString(s string) (DowntimePolicy, error) {
+	if val, ok := _DowntimePolicyNameToValueMap[s]; ok {
+		return val, nil
+	}
+
+	if val, ok := _DowntimePolicyNameToValueMap[strings.ToLower(s)]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("%s does not belong to DowntimePolicy values", s)
+}
+
+// DowntimePolicyValues returns all values of the enum
+func DowntimePolicyValues() []DowntimePolicy {
+	return _DowntimePolicyValues
+}
+
+// DowntimePolicyStrings returns a slice of all String values of the enum
+func DowntimePolicyStrings() []string {
+	strs := make([]string, len(_DowntimePolicyNames))
+	copy(strs, _DowntimePolicyNames)
+	return strs
+}
+
+// IsADowntimePolicy returns "true" if the value is listed in the enum definition. "false" otherwise
+func (i DowntimePolicy) IsADowntimePolicy() bool {
+	for _, v := range _DowntimePolicyValues {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalYAML implements a YAML Marshaler for DowntimePolicy
+func (i DowntimePolicy) MarshalYAML() (interface{}, error) {
+	return i.String(), nil
+}
+
+// UnmarshalYAML implements a YAML Unmarshaler for DowntimePolicy
+func (i *DowntimePolicy) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+
+	var err error
+	*i, err = DowntimePolicyString(s)
+	return err
+}
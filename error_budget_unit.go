@@ -0,0 +1,11 @@
+package shimesaba
+
+// ErrorBudgetUnit is the unit in which the error budget is expressed
+type ErrorBudgetUnit int
+
+//go:generate enumer -type=ErrorBudgetUnit -yaml -linecomment -output error_budget_unit_enumer.go
+
+const (
+	ErrorBudgetUnitMinutes ErrorBudgetUnit = iota //minutes
+	ErrorBudgetUnitEvents                         //events
+)
@@ -0,0 +1,95 @@
+// Code generated by "enumer -type=ErrorBudgetUnit -yaml -linecomment -output error_budget_unit_enumer.go"; DO NOT EDIT.
+
+package shimesaba
+
+import (
+	"fmt"
+	"strings"
+)
+
+const _ErrorBudgetUnitName = "minutesevents"
+
+var _ErrorBudgetUnitIndex = [...]uint8{0, 7, 13}
+
+const _ErrorBudgetUnitLowerName = "minutesevents"
+
+func (i ErrorBudgetUnit) String() string {
+	if i < 0 || i >= ErrorBudgetUnit(len(_ErrorBudgetUnitIndex)-1) {
+		return fmt.Sprintf("ErrorBudgetUnit(%d)", i)
+	}
+	return _ErrorBudgetUnitName[_ErrorBudgetUnitIndex[i]:_ErrorBudgetUnitIndex[i+1]]
+}
+
+// An "invalid array index" compiler error signifies that the constant values have changed.
+// Re-run the stringer command to generate them again.
+func _ErrorBudgetUnitNoOp() {
+	var x [1]struct{}
+	_ = x[ErrorBudgetUnitMinutes-(0)]
+	_ = x[ErrorBudgetUnitEvents-(1)]
+}
+
+var _ErrorBudgetUnitValues = []ErrorBudgetUnit{ErrorBudgetUnitMinutes, ErrorBudgetUnitEvents}
+
+var _ErrorBudgetUnitNameToValueMap = map[string]ErrorBudgetUnit{
+	_ErrorBudgetUnitName[0:7]:       ErrorBudgetUnitMinutes,
+	_ErrorBudgetUnitLowerName[0:7]:  ErrorBudgetUnitMinutes,
+	_ErrorBudgetUnitName[7:13]:      ErrorBudgetUnitEvents,
+	_ErrorBudgetUnitLowerName[7:13]: ErrorBudgetUnitEvents,
+}
+
+var _ErrorBudgetUnitNames = []string{
+	_ErrorBudgetUnitName[0:7],
+	_ErrorBudgetUnitName[7:13],
+}
+
+// ErrorBudgetUnitString retrieves an enum value from the enum constants string name.
+// Throws an error if the param is not part of the enum.
+func ErrorBudgetUnitString(s string) (ErrorBudgetUnit, error) {
+	if val, ok := _ErrorBudgetUnitNameToValueMap[s]; ok {
+		return val, nil
+	}
+
+	if val, ok := _ErrorBudgetUnitNameToValueMap[strings.ToLower(s)]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("%s does not belong to ErrorBudgetUnit values", s)
+}
+
+// ErrorBudgetUnitValues returns all values of the enum
+func ErrorBudgetUnitValues() []ErrorBudgetUnit {
+	return _ErrorBudgetUnitValues
+}
+
+// ErrorBudgetUnitStrings returns a slice of all String values of the enum
+func ErrorBudgetUnitStrings() []string {
+	strs := make([]string, len(_ErrorBudgetUnitNames))
+	copy(strs, _ErrorBudgetUnitNames)
+	return strs
+}
+
+// IsAErrorBudgetUnit returns "true" if the value is listed in the enum definition. "false" otherwise
+func (i ErrorBudgetUnit) IsAErrorBudgetUnit() bool {
+	for _, v := range _ErrorBudgetUnitValues {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalYAML implements a YAML Marshaler for ErrorBudgetUnit
+func (i ErrorBudgetUnit) MarshalYAML() (interface{}, error) {
+	return i.String(), nil
+}
+
+// UnmarshalYAML implements a YAML Unmarshaler for ErrorBudgetUnit
+func (i *ErrorBudgetUnit) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+
+	var err error
+	*i, err = ErrorBudgetUnitString(s)
+	return err
+}
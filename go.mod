@@ -11,6 +11,7 @@ require (
 	github.com/hashicorp/go-version v1.7.0
 	github.com/kayac/go-config v0.7.0
 	github.com/mackerelio/mackerel-client-go v0.34.0
+	github.com/robfig/cron/v3 v3.0.1
 	github.com/shogo82148/go-retry v1.3.1
 	github.com/stretchr/testify v1.10.0
 	github.com/urfave/cli/v2 v2.27.6
@@ -0,0 +1,121 @@
+package shimesaba
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	gc "github.com/kayac/go-config"
+)
+
+// IncidentLedger is a list of reviewed incidents, such as the output of the postmortem process.
+type IncidentLedger struct {
+	Incidents []*Incident `json:"incidents" yaml:"incidents"`
+}
+
+// Incident is a reviewed incident in the incident ledger.
+type Incident struct {
+	Title  string      `json:"title,omitempty" yaml:"title,omitempty"`
+	Start  string      `json:"start" yaml:"start"`
+	End    string      `json:"end" yaml:"end"`
+	SLO    []string    `json:"slo" yaml:"slo"`
+	Impact interface{} `json:"impact,omitempty" yaml:"impact,omitempty"`
+	Link   string      `json:"link,omitempty" yaml:"link,omitempty"`
+
+	startAt time.Time
+	endAt   time.Time
+	impact  float64
+}
+
+// LoadIncidentLedger loads the incident ledger from a YAML or JSON file.
+func LoadIncidentLedger(path string) (*IncidentLedger, error) {
+	ledger := &IncidentLedger{}
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = gc.LoadJSON(ledger, path)
+	} else {
+		err = gc.Load(ledger, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load incident ledger `%s`: %w", path, err)
+	}
+	if err := ledger.Restrict(); err != nil {
+		return nil, fmt.Errorf("incident ledger `%s` is invalid: %w", path, err)
+	}
+	return ledger, nil
+}
+
+// Restrict restricts the incident ledger.
+func (l *IncidentLedger) Restrict() error {
+	for i, incident := range l.Incidents {
+		if err := incident.Restrict(); err != nil {
+			return fmt.Errorf("incidents[%d] %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Restrict restricts an incident.
+func (incident *Incident) Restrict() error {
+	if incident.Start == "" || incident.End == "" {
+		return errors.New("start and end are required")
+	}
+	var err error
+	incident.startAt, err = parseMaintenanceWindowTime(incident.Start, time.UTC)
+	if err != nil {
+		return fmt.Errorf("start is invalid format: %w", err)
+	}
+	incident.endAt, err = parseMaintenanceWindowTime(incident.End, time.UTC)
+	if err != nil {
+		return fmt.Errorf("end is invalid format: %w", err)
+	}
+	if !incident.endAt.After(incident.startAt) {
+		return errors.New("end must be after start")
+	}
+	if len(incident.SLO) == 0 {
+		return errors.New("slo is required")
+	}
+	incident.impact, err = parseImpact(incident.Impact)
+	if err != nil {
+		return fmt.Errorf("impact is invalid: %w", err)
+	}
+	return nil
+}
+
+// Affects reports whether the incident affects the SLO definition of sloID.
+func (incident *Incident) Affects(sloID string) bool {
+	for _, id := range incident.SLO {
+		if id == "*" || id == sloID {
+			return true
+		}
+	}
+	return false
+}
+
+// Alert returns the incident as a virtual alert.
+// The title and the link are the description of the alert, so they are not parsed as the manual corrections.
+func (incident *Incident) Alert() *Alert {
+	description := strings.TrimSpace(incident.Title + " " + incident.Link)
+	alert := NewVirtualAlert("", incident.startAt, incident.endAt).WithDescription(description)
+	if incident.Impact != nil {
+		alert = alert.WithImpact(incident.impact)
+	}
+	return alert
+}
+
+// Alerts returns the incidents that affect the SLO definition of sloID in [startAt, endAt) as virtual alerts.
+func (l *IncidentLedger) Alerts(sloID string, startAt, endAt time.Time) Alerts {
+	alerts := make(Alerts, 0)
+	for _, incident := range l.Incidents {
+		if !incident.Affects(sloID) {
+			continue
+		}
+		if !incident.startAt.Before(endAt) || !incident.endAt.After(startAt) {
+			continue
+		}
+		alerts = append(alerts, incident.Alert())
+	}
+	return alerts
+}
@@ -0,0 +1,134 @@
+package shimesaba_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mashiike/shimesaba"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadIncidentLedger(t *testing.T) {
+	cases := []struct {
+		path     string
+		sloID    string
+		expected shimesaba.Alerts
+	}{
+		{
+			path:  "testdata/incident_ledger.yaml",
+			sloID: "availability",
+			expected: shimesaba.Alerts{
+				shimesaba.NewVirtualAlert(
+					"",
+					time.Date(2021, 10, 1, 0, 10, 0, 0, time.UTC),
+					time.Date(2021, 10, 1, 0, 40, 0, 0, time.UTC),
+				).WithDescription("DNS provider outage https://example.com/postmortems/1").WithImpact(0.3),
+				shimesaba.NewVirtualAlert(
+					"",
+					time.Date(2021, 10, 1, 0, 50, 0, 0, time.UTC),
+					time.Date(2021, 10, 1, 1, 0, 0, 0, time.UTC),
+				).WithDescription("Full outage"),
+			},
+		},
+		{
+			path:  "testdata/incident_ledger.yaml",
+			sloID: "latency",
+			expected: shimesaba.Alerts{
+				shimesaba.NewVirtualAlert(
+					"",
+					time.Date(2021, 10, 1, 0, 50, 0, 0, time.UTC),
+					time.Date(2021, 10, 1, 1, 0, 0, 0, time.UTC),
+				).WithDescription("Full outage"),
+			},
+		},
+		{
+			path:  "testdata/incident_ledger.json",
+			sloID: "availability",
+			expected: shimesaba.Alerts{
+				shimesaba.NewVirtualAlert(
+					"",
+					time.Date(2021, 10, 1, 0, 10, 0, 0, time.UTC),
+					time.Date(2021, 10, 1, 0, 40, 0, 0, time.UTC),
+				).WithDescription("DNS provider outage https://example.com/postmortems/1").WithImpact(0.3),
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.path+":"+c.sloID, func(t *testing.T) {
+			ledger, err := shimesaba.LoadIncidentLedger(c.path)
+			require.NoError(t, err)
+			actual := ledger.Alerts(c.sloID, time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 10, 1, 1, 0, 0, 0, time.UTC))
+			require.EqualValues(t, c.expected, actual)
+			for _, alert := range actual {
+				impact, ok := alert.Impact()
+				if ok {
+					require.InDelta(t, 0.3, impact, 1e-9)
+				}
+			}
+		})
+	}
+}
+
+func TestIncidentRestrict(t *testing.T) {
+	cases := []struct {
+		name     string
+		incident *shimesaba.Incident
+	}{
+		{
+			name:     "no end",
+			incident: &shimesaba.Incident{Start: "2021-10-01T00:10:00Z", SLO: []string{"*"}},
+		},
+		{
+			name:     "end before start",
+			incident: &shimesaba.Incident{Start: "2021-10-01T00:10:00Z", End: "2021-10-01T00:00:00Z", SLO: []string{"*"}},
+		},
+		{
+			name:     "no slo",
+			incident: &shimesaba.Incident{Start: "2021-10-01T00:10:00Z", End: "2021-10-01T00:20:00Z"},
+		},
+		{
+			name:     "invalid impact",
+			incident: &shimesaba.Incident{Start: "2021-10-01T00:10:00Z", End: "2021-10-01T00:20:00Z", SLO: []string{"*"}, Impact: "150%"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Error(t, c.incident.Restrict())
+		})
+	}
+}
+
+func TestIncidentLedgerDataProvider(t *testing.T) {
+	ledger, err := shimesaba.LoadIncidentLedger("testdata/incident_ledger.yaml")
+	require.NoError(t, err)
+	base := &stubAlertDataProvider{
+		virtualAlerts: shimesaba.Alerts{
+			shimesaba.NewVirtualAlert("SLO:availability", time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 10, 1, 0, 5, 0, 0, time.UTC)),
+		},
+	}
+	provider := shimesaba.NewAlertSourceDataProvider(base, ledger)
+	alerts, err := provider.FetchVirtualAlerts(context.Background(), "shimesaba", "availability", time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 10, 1, 0, 30, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, alerts, 2)
+	require.EqualValues(t, "SLO:availability", alerts[0].Reason)
+	require.EqualValues(t, "DNS provider outage https://example.com/postmortems/1", alerts[1].Description())
+}
+
+func TestIncidentAlert(t *testing.T) {
+	incident := &shimesaba.Incident{
+		Title:  "Rollback of slo:latency, nodowntime was wrong impact:10% downtime:1m",
+		Start:  "2021-10-01T00:10:00Z",
+		End:    "2021-10-01T00:40:00Z",
+		SLO:    []string{"*"},
+		Impact: "30%",
+	}
+	require.NoError(t, incident.Restrict())
+	alert := incident.Alert()
+	_, ok := alert.Correction()
+	require.False(t, ok, "the title is not parsed as a manual correction")
+	impact, ok := alert.Impact()
+	require.True(t, ok)
+	require.InDelta(t, 0.3, impact, 1e-9, "the impact of the incident is not overridden by the title")
+	require.Empty(t, alert.Reason)
+}
@@ -10,7 +10,7 @@ import (
 
 	"github.com/Songmu/flextime"
 	mackerel "github.com/mackerelio/mackerel-client-go"
-	retry "github.com/shogo82148/go-retry"
+	"github.com/mashiike/shimesaba/internal/timeutils"
 )
 
 // MackerelClient is an abstraction interface for mackerel-client-go.Client
@@ -27,6 +27,7 @@ type MackerelClient interface {
 	FindMonitors() ([]mackerel.Monitor, error)
 
 	FindGraphAnnotations(service string, from int64, to int64) ([]*mackerel.GraphAnnotation, error)
+	FindDowntimes() ([]*mackerel.Downtime, error)
 }
 
 // Repository handles reading and writing data
@@ -40,6 +41,20 @@ type Repository struct {
 	alertCache     Alerts
 	alertCurrentAt time.Time
 	alertNextID    string
+
+	persistentCache  *AlertCache
+	rawAlerts        []*mackerel.Alert
+	rawAlertIDs      map[string]bool
+	rawOldestAt      int64
+	cacheMerged      bool
+	alertRetainFrom  time.Time
+	alertCacheUpdate bool
+
+	// the downtimes, the monitors and the hosts of the scopes are fetched once, and shared among the SLO definitions.
+	downtimeMu      sync.Mutex
+	rawDowntimes    []*mackerel.Downtime
+	monitors        []*Monitor
+	hostIDsByScopes map[string][]string
 }
 
 // NewRepository creates Repository
@@ -51,6 +66,15 @@ func NewRepository(client MackerelClient) *Repository {
 	}
 }
 
+// WithAlertCache returns Repository that reuses the closed alerts and the monitor definitions in the on-disk cache.
+func (repo *Repository) WithAlertCache(cache *AlertCache) *Repository {
+	return &Repository{
+		client:          repo.client,
+		monitorByID:     repo.monitorByID,
+		persistentCache: cache,
+	}
+}
+
 func (repo *Repository) GetOrgName(ctx context.Context) (string, error) {
 	org, err := repo.client.GetOrg()
 	if err != nil {
@@ -85,12 +109,6 @@ func (repo *Repository) SaveReports(ctx context.Context, reports []*Report) erro
 
 const batchSize = 100
 
-var policy = retry.Policy{
-	MinDelay: time.Second,
-	MaxDelay: 10 * time.Second,
-	MaxCount: 10,
-}
-
 func (repo *Repository) postServiceMetricValues(ctx context.Context, service string, values []*mackerel.MetricValue) error {
 	size := len(values)
 	for i := 0; i < size; i += batchSize {
@@ -99,14 +117,8 @@ func (repo *Repository) postServiceMetricValues(ctx context.Context, service str
 			end = size
 		}
 		log.Printf("[debug] PostServiceMetricValues to Mackerel  %s values[%d:%d]\n", service, start, end)
-		err := policy.Do(ctx, func() error {
-			err := repo.client.PostServiceMetricValues(service, values[start:end])
-			if err != nil {
-				log.Printf("[warn] PostServiceMetricValues to Mackerel failed, retry because: %s\n", err)
-			}
-			return err
-		})
-		if err != nil {
+		// retried by PolicyMackerelClient
+		if err := repo.client.PostServiceMetricValues(service, values[start:end]); err != nil {
 			log.Printf("[warn] PostServiceMetricValues to Mackerel failed:%s %s\n", service, err)
 		}
 	}
@@ -117,6 +129,9 @@ func newMackerelMetricValuesFromReport(report *Report) []*mackerel.MetricValue {
 	metricTypes := DestinationMetricTypeValues()
 	values := make([]*mackerel.MetricValue, 0, len(metricTypes))
 	for _, metricType := range metricTypes {
+		if metricType == BurnRate {
+			continue
+		}
 		if report.Destination.MetricEnabled(metricType) {
 			values = append(values, &mackerel.MetricValue{
 				Name:  report.Destination.MetricName(metricType),
@@ -125,6 +140,19 @@ func newMackerelMetricValuesFromReport(report *Report) []*mackerel.MetricValue {
 			})
 		}
 	}
+	if report.Destination.MetricEnabled(BurnRate) {
+		for _, lookback := range report.Destination.BurnRateLookbacks {
+			burnRate, ok := report.BurnRates[lookback]
+			if !ok {
+				continue
+			}
+			values = append(values, &mackerel.MetricValue{
+				Name:  report.Destination.BurnRateMetricName(lookback),
+				Time:  report.DataPoint.Unix(),
+				Value: burnRate,
+			})
+		}
+	}
 	return values
 }
 
@@ -143,6 +171,9 @@ func (repo *Repository) FetchAlerts(ctx context.Context, startAt time.Time, endA
 			return nil, err
 		}
 	}
+	if err := repo.savePersistentCache(startAt); err != nil {
+		log.Printf("[warn] %s", err)
+	}
 	alerts := make(Alerts, 0, 100)
 	for _, alert := range repo.alertCache {
 		if alert.OpenedAt.After(endAt) {
@@ -156,9 +187,13 @@ func (repo *Repository) FetchAlerts(ctx context.Context, startAt time.Time, endA
 	return alerts, nil
 }
 
-const virtualAlertKeyword = "SLO:"
+const (
+	virtualAlertKeyword          = "SLO:"
+	virtualExclusionAlertKeyword = "SLO-exclude:"
+)
 
 // FetchVirtualAlerts retrieves graph annotations for a specified time period and returns them as virtual alerts.
+// Annotations with `SLO:<ids>` violate the SLO, and annotations with `SLO-exclude:<ids>` exclude the period from the SLO.
 func (repo *Repository) FetchVirtualAlerts(ctx context.Context, serviceName string, sloID string, startAt time.Time, endAt time.Time) (Alerts, error) {
 	log.Printf("[debug] call MackerelClient.FindGraphAnnotations(%s, %s, %s)", serviceName, startAt, endAt)
 	annotations, err := repo.client.FindGraphAnnotations(serviceName, startAt.Unix(), endAt.Unix())
@@ -168,37 +203,258 @@ func (repo *Repository) FetchVirtualAlerts(ctx context.Context, serviceName stri
 	log.Printf("[debug] get %d graph annotations", len(annotations))
 	vAlerts := make(Alerts, 0)
 	for _, annotation := range annotations {
-		i := strings.Index(annotation.Description, virtualAlertKeyword)
+		openedAt, closedAt := time.Unix(annotation.From, 0), time.Unix(annotation.To, 0)
+		for i := 0; i < countAnnotatedSLOs(annotation.Description, virtualAlertKeyword, sloID); i++ {
+			vAlerts = append(vAlerts, NewVirtualAlert(annotation.Description, openedAt, closedAt))
+		}
+		if countAnnotatedSLOs(annotation.Description, virtualExclusionAlertKeyword, sloID) > 0 {
+			vAlerts = append(vAlerts, NewVirtualExclusionAlert(annotation.Description, openedAt, closedAt))
+		}
+	}
+	return vAlerts, nil
+}
+
+// countAnnotatedSLOs returns how many times the SLO ids after the keyword in the description match sloID.
+func countAnnotatedSLOs(description string, keyword string, sloID string) int {
+	i := strings.Index(description, keyword)
+	if i < 0 {
+		i = strings.Index(description, strings.ToLower(keyword))
 		if i < 0 {
-			i = strings.Index(annotation.Description, strings.ToLower(virtualAlertKeyword))
-			if i < 0 {
-				continue
+			return 0
+		}
+	}
+	str := description[i+len(keyword):]
+	j := strings.IndexRune(str, ' ')
+	if j >= 0 {
+		str = str[:j]
+	}
+	count := 0
+	if strings.EqualFold(strings.TrimSpace(str), "*") {
+		count++
+	}
+	for _, slo := range strings.Split(str, ",") {
+		if strings.HasPrefix(slo, sloID) {
+			count++
+		}
+	}
+	return count
+}
+
+// FetchDowntimes retrieves Mackerel downtimes that occur in a specified period of time.
+// The downtimes and their scopes are fetched from Mackerel only once for the Repository.
+func (repo *Repository) FetchDowntimes(ctx context.Context, startAt time.Time, endAt time.Time) (Downtimes, error) {
+	repo.downtimeMu.Lock()
+	defer repo.downtimeMu.Unlock()
+
+	if repo.rawDowntimes == nil {
+		log.Printf("[debug] call MackerelClient.FindDowntimes()")
+		downtimes, err := repo.client.FindDowntimes()
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("[debug] get %d downtimes", len(downtimes))
+		repo.rawDowntimes = append(make([]*mackerel.Downtime, 0, len(downtimes)), downtimes...)
+	}
+	var monitorByID map[string]*Monitor
+	ret := make(Downtimes, 0, len(repo.rawDowntimes))
+	for _, downtime := range repo.rawDowntimes {
+		windows := newDowntimeWindows(downtime, startAt, endAt)
+		if len(windows) == 0 {
+			continue
+		}
+		d := NewDowntime(downtime.ID, downtime.Name, windows)
+		var monitors []*Monitor
+		if len(downtime.MonitorScopes) != 0 {
+			if monitorByID == nil {
+				all, err := repo.fetchMonitors()
+				if err != nil {
+					return nil, fmt.Errorf("find monitors for downtime `%s`: %w", downtime.ID, err)
+				}
+				monitorByID = make(map[string]*Monitor, len(all))
+				for _, m := range all {
+					monitorByID[m.ID()] = m
+				}
+			}
+			monitors = make([]*Monitor, 0, len(downtime.MonitorScopes))
+			for _, id := range downtime.MonitorScopes {
+				if m, ok := monitorByID[id]; ok {
+					monitors = append(monitors, m)
+				}
 			}
 		}
-		str := annotation.Description[i+len(virtualAlertKeyword):]
-		j := strings.IndexRune(str, ' ')
-		if j >= 0 {
-			str = str[:j]
-		}
-		if strings.EqualFold(strings.TrimSpace(str), "*") {
-			vAlerts = append(vAlerts, NewVirtualAlert(
-				annotation.Description,
-				time.Unix(annotation.From, 0),
-				time.Unix(annotation.To, 0),
-			))
-		}
-		slos := strings.Split(str, ",")
-		for _, slo := range slos {
-			if strings.HasPrefix(slo, sloID) {
-				vAlerts = append(vAlerts, NewVirtualAlert(
-					annotation.Description,
-					time.Unix(annotation.From, 0),
-					time.Unix(annotation.To, 0),
-				))
+		d = d.WithMonitorScopes(monitors, downtime.MonitorExcludeScopes)
+		var hostIDs []string
+		if len(downtime.ServiceScopes) != 0 || len(downtime.RoleScopes) != 0 {
+			var err error
+			hostIDs, err = repo.findHostIDs(downtime.ServiceScopes, downtime.RoleScopes)
+			if err != nil {
+				return nil, fmt.Errorf("find hosts for downtime `%s`: %w", downtime.ID, err)
 			}
 		}
+		excludeHostIDs, err := repo.findHostIDs(downtime.ServiceExcludeScopes, downtime.RoleExcludeScopes)
+		if err != nil {
+			return nil, fmt.Errorf("find excluded hosts for downtime `%s`: %w", downtime.ID, err)
+		}
+		d = d.WithHostScopes(hostIDs, excludeHostIDs).WithServiceScopes(downtime.ServiceScopes, downtime.RoleScopes)
+		log.Printf("[debug] %s occurs %d times", d, len(windows))
+		ret = append(ret, d)
 	}
-	return vAlerts, nil
+	return ret, nil
+}
+
+// findHostIDs returns the IDs of hosts belonging to the services or the roles. a role is formatted as `service: role`.
+// The hosts of each scope are fetched only once for the Repository, the caller must hold downtimeMu.
+func (repo *Repository) findHostIDs(services []string, roles []string) ([]string, error) {
+	scopes := make(map[string]*mackerel.FindHostsParam, len(services)+len(roles))
+	keys := make([]string, 0, len(services)+len(roles))
+	for _, service := range services {
+		keys = append(keys, service)
+		scopes[service] = &mackerel.FindHostsParam{Service: service}
+	}
+	for _, role := range roles {
+		parts := strings.SplitN(role, ":", 2)
+		if len(parts) != 2 {
+			log.Printf("[warn] role scope `%s` is invalid format, ignored", role)
+			continue
+		}
+		keys = append(keys, role)
+		scopes[role] = &mackerel.FindHostsParam{
+			Service: strings.TrimSpace(parts[0]),
+			Roles:   []string{strings.TrimSpace(parts[1])},
+		}
+	}
+	if repo.hostIDsByScopes == nil {
+		repo.hostIDsByScopes = make(map[string][]string)
+	}
+	hostIDs := make([]string, 0)
+	for _, key := range keys {
+		if ids, ok := repo.hostIDsByScopes[key]; ok {
+			hostIDs = append(hostIDs, ids...)
+			continue
+		}
+		param := scopes[key]
+		log.Printf("[debug] call MackerelClient.FindHosts(%#v)", param)
+		hosts, err := repo.client.FindHosts(param)
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]string, 0, len(hosts))
+		for _, host := range hosts {
+			ids = append(ids, host.ID)
+		}
+		repo.hostIDsByScopes[key] = ids
+		hostIDs = append(hostIDs, ids...)
+	}
+	return hostIDs, nil
+}
+
+// newDowntimeWindows expands the downtime into the occurrences overlapping between startAt and endAt.
+func newDowntimeWindows(downtime *mackerel.Downtime, startAt, endAt time.Time) MaintenanceWindows {
+	start := time.Unix(downtime.Start, 0).UTC()
+	duration := time.Duration(downtime.Duration) * time.Minute
+	windows := make(MaintenanceWindows, 0)
+	appendWindow := func(t time.Time) {
+		if t.Add(duration).After(startAt) && t.Before(endAt) {
+			windows = append(windows, NewMaintenanceWindow(t, t.Add(duration)))
+		}
+	}
+	recurrence := downtime.Recurrence
+	if recurrence == nil {
+		appendWindow(start)
+		return windows
+	}
+	until := endAt
+	if recurrence.Until != 0 {
+		if t := time.Unix(recurrence.Until, 0); t.Before(until) {
+			until = t
+		}
+	}
+	interval := int(recurrence.Interval)
+	if interval <= 0 {
+		interval = 1
+	}
+	for i := 0; ; i++ {
+		var t time.Time
+		switch recurrence.Type {
+		case mackerel.DowntimeRecurrenceTypeHourly:
+			t = start.Add(time.Duration(i*interval) * time.Hour)
+		case mackerel.DowntimeRecurrenceTypeDaily:
+			t = start.AddDate(0, 0, i*interval)
+		case mackerel.DowntimeRecurrenceTypeWeekly:
+			t = start.AddDate(0, 0, 7*i*interval)
+		case mackerel.DowntimeRecurrenceTypeMonthly:
+			t = start.AddDate(0, i*interval, 0)
+		case mackerel.DowntimeRecurrenceTypeYearly:
+			t = start.AddDate(i*interval, 0, 0)
+		default:
+			log.Printf("[warn] downtime `%s` has unknown recurrence type %d, only the first occurrence is used", downtime.ID, recurrence.Type)
+			appendWindow(start)
+			return windows
+		}
+		if recurrence.Type == mackerel.DowntimeRecurrenceTypeWeekly && len(recurrence.Weekdays) != 0 {
+			// t is the same weekday as start, weekdays of the week are within 6 days before and after t.
+			if t.AddDate(0, 0, -6).After(until) {
+				break
+			}
+			for _, weekday := range recurrence.Weekdays {
+				occurrence := t.AddDate(0, 0, int(weekday)-int(start.Weekday()))
+				if !occurrence.Before(start) && !occurrence.After(until) {
+					appendWindow(occurrence)
+				}
+			}
+			continue
+		}
+		if t.After(until) {
+			break
+		}
+		appendWindow(t)
+	}
+	return windows
+}
+
+// metricFetchChunkSize is the maximum period of a single metric fetch.
+// Mackerel returns coarse-grained values when a long period is requested.
+const metricFetchChunkSize = 24 * time.Hour
+
+// FetchServiceMetricValues retrieves service metric values for a specified period of time
+func (repo *Repository) FetchServiceMetricValues(ctx context.Context, serviceName string, metricName string, startAt time.Time, endAt time.Time) (MetricValues, error) {
+	return repo.fetchMetricValues(ctx, startAt, endAt, func(from, to int64) ([]mackerel.MetricValue, error) {
+		log.Printf("[debug] call MackerelClient.FetchServiceMetricValues(%s, %s, %d, %d)", serviceName, metricName, from, to)
+		return repo.client.FetchServiceMetricValues(serviceName, metricName, from, to)
+	})
+}
+
+// FetchHostMetricValues retrieves host metric values for a specified period of time
+func (repo *Repository) FetchHostMetricValues(ctx context.Context, hostID string, metricName string, startAt time.Time, endAt time.Time) (MetricValues, error) {
+	return repo.fetchMetricValues(ctx, startAt, endAt, func(from, to int64) ([]mackerel.MetricValue, error) {
+		log.Printf("[debug] call MackerelClient.FetchHostMetricValues(%s, %s, %d, %d)", hostID, metricName, from, to)
+		return repo.client.FetchHostMetricValues(hostID, metricName, from, to)
+	})
+}
+
+func (repo *Repository) fetchMetricValues(ctx context.Context, startAt time.Time, endAt time.Time, fetch func(from, to int64) ([]mackerel.MetricValue, error)) (MetricValues, error) {
+	values := make(MetricValues)
+	iter := timeutils.NewIterator(startAt, endAt, metricFetchChunkSize)
+	for iter.HasNext() {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		from, to := iter.Next()
+		metrics, err := fetch(from.Unix(), to.Unix())
+		if err != nil {
+			return nil, err
+		}
+		for _, metric := range metrics {
+			value, ok := metric.Value.(float64)
+			if !ok {
+				continue
+			}
+			values[time.Unix(metric.Time, 0).UTC()] = value
+		}
+	}
+	return values, nil
 }
 
 func (repo *Repository) fetchAlertsInitial(ctx context.Context) error {
@@ -218,7 +474,7 @@ func (repo *Repository) fetchAlertsInitial(ctx context.Context) error {
 	}
 	repo.alertCurrentAt = currentAt
 	repo.alertNextID = resp.NextID
-	return nil
+	return repo.mergePersistentCache()
 }
 
 func (repo *Repository) fetchAlertsIncremental(ctx context.Context) error {
@@ -237,12 +493,74 @@ func (repo *Repository) fetchAlertsIncremental(ctx context.Context) error {
 		repo.alertCurrentAt = converted[len(converted)-1].OpenedAt
 		repo.alertNextID = resp.NextID
 	}
+	return repo.mergePersistentCache()
+}
+
+// mergePersistentCache appends the cached closed alerts, once the fetched alerts cover all alerts that may have changed since the cache was saved.
+func (repo *Repository) mergePersistentCache() error {
+	if repo.persistentCache == nil || repo.cacheMerged || repo.persistentCache.IsEmpty() {
+		return nil
+	}
+	if len(repo.rawAlerts) == 0 || repo.rawOldestAt >= repo.persistentCache.RefreshFrom.Unix() {
+		return nil
+	}
+	repo.cacheMerged = true
+	cached := make([]*mackerel.Alert, 0)
+	for _, alert := range repo.persistentCache.alertsBefore(time.Unix(repo.rawOldestAt, 0)) {
+		if !repo.rawAlertIDs[alert.ID] {
+			cached = append(cached, alert)
+		}
+	}
+	log.Printf("[debug] merge %d alerts from alert cache", len(cached))
+	converted, err := repo.convertAlerts(&mackerel.AlertsResp{Alerts: cached})
+	if err != nil {
+		return err
+	}
+	repo.alertCache = append(repo.alertCache, converted...)
+	if len(converted) != 0 {
+		repo.alertCurrentAt = converted[len(converted)-1].OpenedAt
+	}
+	repo.alertNextID = repo.persistentCache.NextID
+	return nil
+}
+
+// savePersistentCache saves the alerts fetched so far to the on-disk cache.
+func (repo *Repository) savePersistentCache(startAt time.Time) error {
+	if repo.persistentCache == nil {
+		return nil
+	}
+	if repo.alertRetainFrom.IsZero() || startAt.Before(repo.alertRetainFrom) {
+		repo.alertRetainFrom = startAt
+	}
+	if !repo.alertCacheUpdate {
+		return nil
+	}
+	if !repo.cacheMerged && !repo.persistentCache.IsEmpty() && repo.alertNextID != "" {
+		log.Printf("[debug] fetched alerts do not reach the alert cache, skip to save")
+		return nil
+	}
+	repo.alertCacheUpdate = false
+	repo.persistentCache.update(repo.rawAlerts, repo.alertNextID, repo.alertRetainFrom)
+	if err := repo.persistentCache.Save(); err != nil {
+		return fmt.Errorf("alert cache: %w", err)
+	}
 	return nil
 }
 
 func (repo *Repository) convertAlerts(resp *mackerel.AlertsResp) ([]*Alert, error) {
 	alerts := make([]*Alert, 0, len(resp.Alerts))
 	for _, alert := range resp.Alerts {
+		if repo.persistentCache != nil && !repo.rawAlertIDs[alert.ID] {
+			if repo.rawAlertIDs == nil {
+				repo.rawAlertIDs = make(map[string]bool)
+			}
+			repo.rawAlertIDs[alert.ID] = true
+			repo.rawAlerts = append(repo.rawAlerts, alert)
+			if len(repo.rawAlerts) == 1 || alert.OpenedAt < repo.rawOldestAt {
+				repo.rawOldestAt = alert.OpenedAt
+			}
+			repo.alertCacheUpdate = true
+		}
 		if alert.MonitorID == "" {
 			continue
 		}
@@ -273,13 +591,28 @@ func (repo *Repository) convertAlerts(resp *mackerel.AlertsResp) ([]*Alert, erro
 			openedAt,
 			closedAt,
 		)
-		a = a.WithHostID(alert.HostID).WithReason(alert.Reason)
+		a = a.WithHostID(alert.HostID).WithReason(alert.Reason).WithSeverity(newAlertSeverity(alert, monitor))
 		log.Printf("[debug] %s", a)
 		alerts = append(alerts, a)
 	}
 	return alerts, nil
 }
 
+// newAlertSeverity returns the severity of the alert.
+// Since the status of a closed alert is OK, the severity is inferred from the value and the thresholds of the monitor.
+func newAlertSeverity(alert *mackerel.Alert, monitor *Monitor) AlertSeverity {
+	switch alert.Status {
+	case "CRITICAL":
+		return AlertSeverityCritical
+	case "WARNING":
+		return AlertSeverityWarning
+	case "OK":
+		return monitor.SeverityOf(alert.Value)
+	default:
+		return AlertSeverityUnknown
+	}
+}
+
 func (repo *Repository) getMonitor(id string, monitorType string) (*Monitor, error) {
 	repo.mu.Lock()
 	defer repo.mu.Unlock()
@@ -292,17 +625,47 @@ func (repo *Repository) getMonitor(id string, monitorType string) (*Monitor, err
 		repo.monitorByID[id] = NewMonitor(id, fmt.Sprintf("check monitor %s", id), "check")
 		return repo.monitorByID[id], nil
 	default:
+		if repo.persistentCache != nil {
+			if monitor, ok := repo.persistentCache.getMonitor(id); ok {
+				log.Printf("[debug] monitor[%s] from alert cache", id)
+				repo.monitorByID[id] = repo.convertMonitor(monitor)
+				return repo.monitorByID[id], nil
+			}
+		}
 		log.Printf("[debug] call GetMonitor(%s)", id)
 		monitor, err := repo.client.GetMonitor(id)
 		if err != nil {
 			return nil, err
 		}
 		log.Printf("[debug] catch monitor[%s] = %#v", id, monitor)
+		if repo.persistentCache != nil {
+			repo.persistentCache.setMonitor(id, monitor)
+		}
 		repo.monitorByID[id] = repo.convertMonitor(monitor)
 		return repo.monitorByID[id], nil
 	}
 }
 
+// FetchMonitors retrieves all monitors of the organization. The monitors are fetched from Mackerel only once for the Repository.
+func (repo *Repository) FetchMonitors(ctx context.Context) ([]*Monitor, error) {
+	repo.downtimeMu.Lock()
+	defer repo.downtimeMu.Unlock()
+	return repo.fetchMonitors()
+}
+
+// fetchMonitors returns the monitors fetched once for the Repository, the caller must hold downtimeMu.
+func (repo *Repository) fetchMonitors() ([]*Monitor, error) {
+	if repo.monitors != nil {
+		return repo.monitors, nil
+	}
+	monitors, err := repo.FindMonitors()
+	if err != nil {
+		return nil, err
+	}
+	repo.monitors = monitors
+	return monitors, nil
+}
+
 func (repo *Repository) FindMonitors() ([]*Monitor, error) {
 	repo.mu.Lock()
 	defer repo.mu.Unlock()
@@ -325,10 +688,15 @@ func (repo *Repository) convertMonitor(monitor mackerel.Monitor) *Monitor {
 		monitor.MonitorID(),
 		monitor.MonitorName(),
 		monitor.MonitorType(),
-	)
+	).WithMuted(isMuteMonitor(monitor))
 	switch monitor := monitor.(type) {
+	case *mackerel.MonitorConnectivity:
+		m = m.WithHostScopes(monitor.Scopes)
+	case *mackerel.MonitorAnomalyDetection:
+		m = m.WithHostScopes(monitor.Scopes)
 	case *mackerel.MonitorHostMetric:
-		m = m.WithEvaluator(func(hostID string, timeFrame time.Duration, startAt, endAt time.Time) (Reliabilities, bool) {
+		m = m.WithThresholds(monitor.Operator, monitor.Warning, monitor.Critical).WithHostScopes(monitor.Scopes)
+		m = m.WithEvaluator(func(hostID string, timeFrame time.Duration, minSeverity AlertSeverity, startAt, endAt time.Time) (Reliabilities, bool) {
 			log.Printf("[debug] try evaluate host metric, host_id=`%s`, monitor=`%s` time=%s~%s", hostID, monitor.Name, startAt, endAt)
 			metrics, err := repo.client.FetchHostMetricValues(hostID, monitor.Metric, startAt.Unix(), endAt.Unix())
 			if err != nil {
@@ -336,59 +704,19 @@ func (repo *Repository) convertMonitor(monitor mackerel.Monitor) *Monitor {
 				log.Printf("[warn] monitor `%s`, can not get host metric = `%s`, reliability reassessment based on metric is not enabled.", monitor.Name, monitor.Metric)
 				return nil, false
 			}
-			isNoViolation := make(IsNoViolationCollection, endAt.Sub(startAt)/time.Minute)
-			for _, metric := range metrics {
-				cursorAt := time.Unix(metric.Time, 0).UTC()
-				value, ok := metric.Value.(float64)
-				if !ok {
-					continue
-				}
-				switch monitor.Operator {
-				case ">":
-					if monitor.Warning != nil {
-						if value > *monitor.Warning {
-							isNoViolation[cursorAt] = false
-							log.Printf("[debug] monitor `%s`, SLO Violation, host_id=`%s`, time=`%s`,  value[%f] > warning[%f]", monitor.Name, hostID, cursorAt, value, *monitor.Warning)
-							continue
-						}
-					}
-					if monitor.Critical != nil {
-						if value > *monitor.Critical {
-							isNoViolation[cursorAt] = false
-							log.Printf("[debug] monitor `%s`, SLO Violation, hostId=`%s`, time=`%s`,  value[%f] > critical[%f]", monitor.Name, hostID, cursorAt, value, *monitor.Critical)
-							continue
-						}
-					}
-				case "<":
-					if monitor.Warning != nil {
-						if value < *monitor.Warning {
-							isNoViolation[cursorAt] = false
-							log.Printf("[debug] monitor `%s`, SLO Violation, hostId=`%s`, time=`%s`,  value[%f] < warning[%f]", monitor.Name, hostID, cursorAt, value, *monitor.Warning)
-							continue
-						}
-					}
-					if monitor.Critical != nil {
-						if value < *monitor.Critical {
-							isNoViolation[cursorAt] = false
-							log.Printf("[debug] monitor `%s`, SLO Violation, hostId=`%s`, time=`%s`,  value[%f] < critical[%f]", monitor.Name, hostID, cursorAt, value, *monitor.Warning)
-							continue
-						}
-					}
-				default:
-					log.Printf("[warn] monitor `%s`, unknown operator `%s`, reliability reassessment based on metric is not enabled.", monitor.Name, monitor.Operator)
-					return nil, false
-				}
-			}
-			reliabilities, err := isNoViolation.NewReliabilities(timeFrame, startAt, endAt)
-			if err != nil {
-				log.Printf("[debug] NewReliabilities failed: %s", err)
-				log.Printf("[warn] monitor `%s`, reliability reassessment based on metric is not enabled.", monitor.Name)
-				return nil, false
-			}
-			return reliabilities, true
+			return reassessReliabilities(
+				monitor.Name, fmt.Sprintf("host_id=`%s`", hostID), metrics,
+				monitor.Operator, monitor.Warning, monitor.Critical, minSeverity,
+				timeFrame, startAt, endAt,
+			)
 		})
+	case *mackerel.MonitorExpression:
+		m = m.WithThresholds(monitor.Operator, monitor.Warning, monitor.Critical)
+	case *mackerel.MonitorQuery:
+		m = m.WithThresholds(monitor.Operator, monitor.Warning, monitor.Critical)
 	case *mackerel.MonitorServiceMetric:
-		m = m.WithEvaluator(func(_ string, timeFrame time.Duration, startAt, endAt time.Time) (Reliabilities, bool) {
+		m = m.WithThresholds(monitor.Operator, monitor.Warning, monitor.Critical)
+		m = m.WithEvaluator(func(_ string, timeFrame time.Duration, minSeverity AlertSeverity, startAt, endAt time.Time) (Reliabilities, bool) {
 			log.Printf("[debug] try evaluate service metric, service=%s monitor=`%s` time=%s~%s", monitor.Service, monitor.Name, startAt, endAt)
 			metrics, err := repo.client.FetchServiceMetricValues(monitor.Service, monitor.Metric, startAt.Unix(), endAt.Unix())
 			if err != nil {
@@ -396,67 +724,93 @@ func (repo *Repository) convertMonitor(monitor mackerel.Monitor) *Monitor {
 				log.Printf("[warn] monitor `%s`, can not get service metric = `%s`, reliability reassessment based on metric is not enabled.", monitor.Name, monitor.Metric)
 				return nil, false
 			}
-			isNoViolation := make(IsNoViolationCollection, endAt.Sub(startAt)/time.Minute)
-			for _, metric := range metrics {
-				cursorAt := time.Unix(metric.Time, 0).UTC()
-				value, ok := metric.Value.(float64)
-				if !ok {
-					continue
-				}
-				switch monitor.Operator {
-				case ">":
-					if monitor.Warning != nil {
-						if value > *monitor.Warning {
-							isNoViolation[cursorAt] = false
-							log.Printf("[debug] monitor `%s`, SLO Violation, service=`%s`, time=`%s`,  value[%f] > warning[%f]", monitor.Name, monitor.Service, cursorAt, value, *monitor.Warning)
-							continue
-						}
-					}
-					if monitor.Critical != nil {
-						if value > *monitor.Critical {
-							isNoViolation[cursorAt] = false
-							log.Printf("[debug] monitor `%s`, SLO Violation, service=`%s`, time=`%s`,  value[%f] > critical[%f]", monitor.Name, monitor.Service, cursorAt, value, *monitor.Critical)
-							continue
-						}
-					}
-				case "<":
-					if monitor.Warning != nil {
-						if value < *monitor.Warning {
-							isNoViolation[cursorAt] = false
-							log.Printf("[debug] monitor `%s`, SLO Violation, service=`%s`, time=`%s`,  value[%f] < warning[%f]", monitor.Name, monitor.Service, cursorAt, value, *monitor.Warning)
-							continue
-						}
-					}
-					if monitor.Critical != nil {
-						if value < *monitor.Critical {
-							isNoViolation[cursorAt] = false
-							log.Printf("[debug] monitor `%s`, SLO Violation, service=`%s`, time=`%s`,  value[%f] < critical[%f]", monitor.Name, monitor.Service, cursorAt, value, *monitor.Warning)
-							continue
-						}
-					}
-				default:
-					log.Printf("[warn] monitor `%s`, unknown operator `%s`, reliability reassessment based on metric is not enabled.", monitor.Name, monitor.Operator)
-					return nil, false
-				}
+			return reassessReliabilities(
+				monitor.Name, fmt.Sprintf("service=`%s`", monitor.Service), metrics,
+				monitor.Operator, monitor.Warning, monitor.Critical, minSeverity,
+				timeFrame, startAt, endAt,
+			)
+		})
+	}
+	return m
+}
+
+func isMuteMonitor(monitor mackerel.Monitor) bool {
+	switch monitor := monitor.(type) {
+	case *mackerel.MonitorConnectivity:
+		return monitor.IsMute
+	case *mackerel.MonitorHostMetric:
+		return monitor.IsMute
+	case *mackerel.MonitorServiceMetric:
+		return monitor.IsMute
+	case *mackerel.MonitorExternalHTTP:
+		return monitor.IsMute
+	case *mackerel.MonitorExpression:
+		return monitor.IsMute
+	case *mackerel.MonitorAnomalyDetection:
+		return monitor.IsMute
+	case *mackerel.MonitorQuery:
+		return monitor.IsMute
+	default:
+		return false
+	}
+}
+
+// reassessReliabilities evaluates metric values with the warning and critical thresholds of the monitor.
+// The threshold lower than minSeverity is not used.
+func reassessReliabilities(monitorName string, target string, metrics []mackerel.MetricValue, operator string, warning, critical *float64, minSeverity AlertSeverity, timeFrame time.Duration, startAt, endAt time.Time) (Reliabilities, bool) {
+	thresholds := []struct {
+		severity AlertSeverity
+		value    *float64
+	}{
+		{severity: AlertSeverityWarning, value: warning},
+		{severity: AlertSeverityCritical, value: critical},
+	}
+	isNoViolation := make(IsNoViolationCollection, endAt.Sub(startAt)/time.Minute)
+	for _, metric := range metrics {
+		cursorAt := time.Unix(metric.Time, 0).UTC()
+		value, ok := metric.Value.(float64)
+		if !ok {
+			continue
+		}
+		for _, threshold := range thresholds {
+			if threshold.value == nil || !threshold.severity.Satisfies(minSeverity) {
+				continue
 			}
-			reliabilities, err := isNoViolation.NewReliabilities(timeFrame, startAt, endAt)
+			violated, err := isThresholdViolated(operator, value, *threshold.value)
 			if err != nil {
-				log.Printf("[debug] NewReliabilities failed: %s", err)
-				log.Printf("[warn] monitor `%s`, reliability reassessment based on metric is not enabled.", monitor.Name)
+				log.Printf("[warn] monitor `%s`, %s, reliability reassessment based on metric is not enabled.", monitorName, err)
 				return nil, false
 			}
-			return reliabilities, true
-		})
+			if violated {
+				isNoViolation[cursorAt] = false
+				log.Printf("[debug] monitor `%s`, SLO Violation, %s, time=`%s`,  value[%f] %s %s[%f]", monitorName, target, cursorAt, value, operator, threshold.severity, *threshold.value)
+				break
+			}
+		}
 	}
-	return m
+	reliabilities, err := isNoViolation.NewReliabilities(timeFrame, startAt, endAt)
+	if err != nil {
+		log.Printf("[debug] NewReliabilities failed: %s", err)
+		log.Printf("[warn] monitor `%s`, reliability reassessment based on metric is not enabled.", monitorName)
+		return nil, false
+	}
+	return reliabilities, true
 }
 
 func (repo *Repository) WithDryRun() *Repository {
+	return repo.WithClient(DryRunMackerelClient{
+		MackerelClient: repo.client,
+	})
+}
+
+// WithClient returns Repository that calls Mackerel API with client. Only the persistent alert cache (alert_cache) is shared;
+// the in-memory alerts, the downtimes and the monitors start empty and are fetched again with client.
+// The monitors are not shared, because their evaluators for reassessment call Mackerel API with the client of the Repository that fetched them.
+func (repo *Repository) WithClient(client MackerelClient) *Repository {
 	return &Repository{
-		client: DryRunMackerelClient{
-			MackerelClient: repo.client,
-		},
-		monitorByID: repo.monitorByID,
+		client:          client,
+		monitorByID:     make(map[string]*Monitor),
+		persistentCache: repo.persistentCache,
 	}
 }
 
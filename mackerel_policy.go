@@ -0,0 +1,279 @@
+package shimesaba
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+	retry "github.com/shogo82148/go-retry"
+)
+
+// PolicyMackerelClient is a MackerelClient that applies the retry, the rate limit and the timeout to every call of the client.
+// The calls that are rate limited (429), failed on the server side (5xx), timed out or failed on the network are retried with backoff.
+// It also counts the calls of each endpoint for the run summary.
+type PolicyMackerelClient struct {
+	client  MackerelClient
+	ctx     context.Context
+	policy  retry.Policy
+	timeout time.Duration
+	limiter *rateLimiter
+	counter *callCounter
+}
+
+// NewPolicyMackerelClient creates PolicyMackerelClient. If cfg is nil, the default policy is used.
+func NewPolicyMackerelClient(client MackerelClient, cfg *MackerelAPIConfig) (*PolicyMackerelClient, error) {
+	if cfg == nil {
+		cfg = &MackerelAPIConfig{}
+	}
+	if err := cfg.Restrict(); err != nil {
+		return nil, fmt.Errorf("mackerel_api is invalid: %w", err)
+	}
+	return &PolicyMackerelClient{
+		client: client,
+		ctx:    context.Background(),
+		policy: retry.Policy{
+			MinDelay: cfg.minDelay,
+			MaxDelay: cfg.maxDelay,
+			MaxCount: cfg.MaxAttempts,
+		},
+		timeout: cfg.timeout,
+		limiter: newRateLimiter(cfg.RateLimit, cfg.Burst),
+		counter: newCallCounter(),
+	}, nil
+}
+
+// WithContext returns PolicyMackerelClient whose calls are canceled with ctx.
+// The rate limit is shared with the original client, and the calls are counted separately from it.
+func (c *PolicyMackerelClient) WithContext(ctx context.Context) *PolicyMackerelClient {
+	cloned := *c
+	cloned.ctx = ctx
+	cloned.counter = newCallCounter()
+	return &cloned
+}
+
+// CallCounts returns the number of calls of each endpoint, including the retries.
+func (c *PolicyMackerelClient) CallCounts() map[string]int {
+	return c.counter.counts()
+}
+
+// CallSummary returns the number of calls of each endpoint as `endpoint=count`, sorted by endpoint.
+func (c *PolicyMackerelClient) CallSummary() string {
+	counts := c.CallCounts()
+	endpoints := make([]string, 0, len(counts))
+	for endpoint := range counts {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	parts := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		parts = append(parts, fmt.Sprintf("%s=%d", endpoint, counts[endpoint]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// callWithPolicy calls fn with the retry, the rate limit and the timeout of the client.
+func callWithPolicy[T any](c *PolicyMackerelClient, endpoint string, fn func() (T, error)) (T, error) {
+	return retry.DoValue(c.ctx, &c.policy, func() (T, error) {
+		var zero T
+		if err := c.limiter.wait(c.ctx); err != nil {
+			return zero, retry.MarkPermanent(err)
+		}
+		c.counter.add(endpoint)
+		v, err := callWithTimeout(c.ctx, c.timeout, fn)
+		if err == nil {
+			return v, nil
+		}
+		if c.ctx.Err() != nil || !isTemporaryAPIError(err) {
+			return zero, retry.MarkPermanent(err)
+		}
+		log.Printf("[warn] %s to Mackerel failed, retry because: %s", endpoint, err)
+		return zero, retry.MarkTemporary(err)
+	})
+}
+
+// callWithTimeout calls fn, and returns without waiting fn if it does not finish in timeout or ctx is canceled.
+// mackerel-client-go does not accept context, so the abandoned call finishes with the timeout of its HTTP client.
+func callWithTimeout[T any](ctx context.Context, timeout time.Duration, fn func() (T, error)) (T, error) {
+	if timeout <= 0 && ctx.Done() == nil {
+		return fn()
+	}
+	callCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	type result struct {
+		v   T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err := fn()
+		done <- result{v: v, err: err}
+	}()
+	select {
+	case r := <-done:
+		return r.v, r.err
+	case <-callCtx.Done():
+		var zero T
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+		return zero, fmt.Errorf("timed out after %s: %w", timeout, context.DeadlineExceeded)
+	}
+}
+
+// isTemporaryAPIError reports whether the call may succeed if it is retried.
+func isTemporaryAPIError(err error) bool {
+	var apiErr *mackerel.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+	// the network errors and context.DeadlineExceeded of the timeout
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func (c *PolicyMackerelClient) GetOrg() (*mackerel.Org, error) {
+	return callWithPolicy(c, "GetOrg", c.client.GetOrg)
+}
+
+func (c *PolicyMackerelClient) FindHosts(param *mackerel.FindHostsParam) ([]*mackerel.Host, error) {
+	return callWithPolicy(c, "FindHosts", func() ([]*mackerel.Host, error) {
+		return c.client.FindHosts(param)
+	})
+}
+
+func (c *PolicyMackerelClient) FetchHostMetricValues(hostID string, metricName string, from int64, to int64) ([]mackerel.MetricValue, error) {
+	return callWithPolicy(c, "FetchHostMetricValues", func() ([]mackerel.MetricValue, error) {
+		return c.client.FetchHostMetricValues(hostID, metricName, from, to)
+	})
+}
+
+func (c *PolicyMackerelClient) FetchServiceMetricValues(serviceName string, metricName string, from int64, to int64) ([]mackerel.MetricValue, error) {
+	return callWithPolicy(c, "FetchServiceMetricValues", func() ([]mackerel.MetricValue, error) {
+		return c.client.FetchServiceMetricValues(serviceName, metricName, from, to)
+	})
+}
+
+func (c *PolicyMackerelClient) PostServiceMetricValues(serviceName string, metricValues []*mackerel.MetricValue) error {
+	_, err := callWithPolicy(c, "PostServiceMetricValues", func() (struct{}, error) {
+		return struct{}{}, c.client.PostServiceMetricValues(serviceName, metricValues)
+	})
+	return err
+}
+
+func (c *PolicyMackerelClient) FindWithClosedAlerts() (*mackerel.AlertsResp, error) {
+	return callWithPolicy(c, "FindWithClosedAlerts", c.client.FindWithClosedAlerts)
+}
+
+func (c *PolicyMackerelClient) FindWithClosedAlertsByNextID(nextID string) (*mackerel.AlertsResp, error) {
+	return callWithPolicy(c, "FindWithClosedAlertsByNextID", func() (*mackerel.AlertsResp, error) {
+		return c.client.FindWithClosedAlertsByNextID(nextID)
+	})
+}
+
+func (c *PolicyMackerelClient) GetMonitor(monitorID string) (mackerel.Monitor, error) {
+	return callWithPolicy(c, "GetMonitor", func() (mackerel.Monitor, error) {
+		return c.client.GetMonitor(monitorID)
+	})
+}
+
+func (c *PolicyMackerelClient) FindMonitors() ([]mackerel.Monitor, error) {
+	return callWithPolicy(c, "FindMonitors", c.client.FindMonitors)
+}
+
+func (c *PolicyMackerelClient) FindGraphAnnotations(service string, from int64, to int64) ([]*mackerel.GraphAnnotation, error) {
+	return callWithPolicy(c, "FindGraphAnnotations", func() ([]*mackerel.GraphAnnotation, error) {
+		return c.client.FindGraphAnnotations(service, from, to)
+	})
+}
+
+func (c *PolicyMackerelClient) FindDowntimes() ([]*mackerel.Downtime, error) {
+	return callWithPolicy(c, "FindDowntimes", c.client.FindDowntimes)
+}
+
+// rateLimiter spaces the calls at the rate, allowing burst calls at once.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	burst    int
+	next     time.Time
+}
+
+// newRateLimiter creates rateLimiter of rate calls per second. If rate is 0, the calls are not limited.
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	return &rateLimiter{
+		interval: time.Duration(float64(time.Second) / rate),
+		burst:    burst,
+	}
+}
+
+// wait blocks until the next call is allowed, or ctx is canceled.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	// the calls before the time that the burst is refilled are not counted.
+	if earliest := now.Add(-l.interval * time.Duration(l.burst-1)); l.next.Before(earliest) {
+		l.next = earliest
+	}
+	at := l.next
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	d := at.Sub(now)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type callCounter struct {
+	mu     sync.Mutex
+	byName map[string]int
+}
+
+func newCallCounter() *callCounter {
+	return &callCounter{byName: make(map[string]int)}
+}
+
+func (c *callCounter) add(endpoint string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.byName[endpoint]++
+}
+
+func (c *callCounter) counts() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := make(map[string]int, len(c.byName))
+	for endpoint, n := range c.byName {
+		counts[endpoint] = n
+	}
+	return counts
+}
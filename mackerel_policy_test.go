@@ -0,0 +1,146 @@
+package shimesaba_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+	"github.com/mashiike/shimesaba"
+	"github.com/mashiike/shimesaba/internal/logger"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyMackerelClient returns the errors in order for GetOrg, and succeeds after them.
+type flakyMackerelClient struct {
+	shimesaba.MackerelClient
+	mu     sync.Mutex
+	errs   []error
+	delay  time.Duration
+	called int
+}
+
+func (c *flakyMackerelClient) GetOrg() (*mackerel.Org, error) {
+	c.mu.Lock()
+	c.called++
+	var err error
+	if len(c.errs) > 0 {
+		err, c.errs = c.errs[0], c.errs[1:]
+	}
+	c.mu.Unlock()
+	time.Sleep(c.delay)
+	if err != nil {
+		return nil, err
+	}
+	return &mackerel.Org{Name: "dummy"}, nil
+}
+
+func TestPolicyMackerelClient(t *testing.T) {
+	logger.Setup(io.Discard, "info")
+	defer logger.Setup(os.Stderr, "info")
+	cfg := func() *shimesaba.MackerelAPIConfig {
+		return &shimesaba.MackerelAPIConfig{
+			MaxAttempts: 3,
+			MinDelay:    "1ms",
+			MaxDelay:    "1ms",
+			Timeout:     "50ms",
+		}
+	}
+	cases := []struct {
+		name          string
+		errs          []error
+		delay         time.Duration
+		expectedErr   string
+		expectedCalls int
+	}{
+		{
+			name:          "success",
+			expectedCalls: 1,
+		},
+		{
+			name: "retry server errors",
+			errs: []error{
+				&mackerel.APIError{StatusCode: 503, Message: "Service Unavailable"},
+				&mackerel.APIError{StatusCode: 429, Message: "Too Many Requests"},
+			},
+			expectedCalls: 3,
+		},
+		{
+			name: "give up after max attempts",
+			errs: []error{
+				&mackerel.APIError{StatusCode: 500, Message: "Internal Server Error"},
+				&mackerel.APIError{StatusCode: 502, Message: "Bad Gateway"},
+				&mackerel.APIError{StatusCode: 504, Message: "Gateway Timeout"},
+			},
+			expectedErr:   "Gateway Timeout",
+			expectedCalls: 3,
+		},
+		{
+			name: "not retry client errors",
+			errs: []error{
+				&mackerel.APIError{StatusCode: 403, Message: "Forbidden"},
+			},
+			expectedErr:   "Forbidden",
+			expectedCalls: 1,
+		},
+		{
+			name:          "retry timeouts",
+			delay:         time.Second,
+			expectedErr:   "timed out after 50ms",
+			expectedCalls: 3,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client := &flakyMackerelClient{errs: c.errs, delay: c.delay}
+			policyClient, err := shimesaba.NewPolicyMackerelClient(client, cfg())
+			require.NoError(t, err)
+			org, err := policyClient.GetOrg()
+			if c.expectedErr != "" {
+				require.ErrorContains(t, err, c.expectedErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, "dummy", org.Name)
+			}
+			require.Equal(t, map[string]int{"GetOrg": c.expectedCalls}, policyClient.CallCounts())
+			require.Equal(t, fmt.Sprintf("GetOrg=%d", c.expectedCalls), policyClient.CallSummary())
+		})
+	}
+}
+
+func TestPolicyMackerelClientContext(t *testing.T) {
+	client := &flakyMackerelClient{delay: time.Second}
+	policyClient, err := shimesaba.NewPolicyMackerelClient(client, nil)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	withCtx := policyClient.WithContext(ctx)
+	time.AfterFunc(10*time.Millisecond, cancel)
+	startAt := time.Now()
+	_, err = withCtx.GetOrg()
+	require.True(t, errors.Is(err, context.Canceled), "canceled call is not retried: %v", err)
+	require.Less(t, time.Since(startAt), 500*time.Millisecond)
+	require.Equal(t, map[string]int{"GetOrg": 1}, withCtx.CallCounts())
+	require.Empty(t, policyClient.CallCounts(), "the calls are counted for each context")
+}
+
+func TestPolicyMackerelClientRateLimit(t *testing.T) {
+	client := &flakyMackerelClient{}
+	policyClient, err := shimesaba.NewPolicyMackerelClient(client, &shimesaba.MackerelAPIConfig{
+		RateLimit: 50,
+		Burst:     2,
+	})
+	require.NoError(t, err)
+	startAt := time.Now()
+	for i := 0; i < 6; i++ {
+		_, err := policyClient.GetOrg()
+		require.NoError(t, err)
+	}
+	// 2 calls at once, and the other 4 calls at 20ms intervals.
+	require.GreaterOrEqual(t, time.Since(startAt), 70*time.Millisecond)
+	require.Equal(t, 6, client.called)
+}
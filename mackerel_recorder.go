@@ -0,0 +1,479 @@
+package shimesaba
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Songmu/flextime"
+	mackerel "github.com/mackerelio/mackerel-client-go"
+)
+
+const recordingMetaFile = "meta.json"
+
+// recordingMeta is the metadata of the fixture directory.
+type recordingMeta struct {
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// fixture is a recorded response of Mackerel API.
+type fixture struct {
+	Method   string          `json:"method"`
+	Args     []interface{}   `json:"args"`
+	Response json.RawMessage `json:"response"`
+}
+
+// monitorFixture keeps the type of mackerel.Monitor, to decode the interface.
+type monitorFixture struct {
+	Type    string          `json:"type"`
+	Monitor json.RawMessage `json:"monitor"`
+}
+
+// fixtureFileName returns the file name of the fixture of the method called with args.
+// The time range arguments are not a part of args, because they change with the time of the run,
+// so the responses of the time range are merged into the fixture and filtered by the range on replay.
+func fixtureFileName(method string, args ...interface{}) (string, error) {
+	bs, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(bs)
+	return method + "-" + hex.EncodeToString(sum[:8]) + ".json", nil
+}
+
+// RecordingMackerelClient is a MackerelClient that saves every response of the client to the fixture directory.
+// The fixture directory can be served by ReplayingMackerelClient.
+type RecordingMackerelClient struct {
+	client MackerelClient
+	dir    string
+	mu     sync.Mutex
+}
+
+// NewRecordingMackerelClient creates RecordingMackerelClient. The recording time is saved to dir,
+// and App updates it to the time of each run.
+func NewRecordingMackerelClient(client MackerelClient, dir string) (*RecordingMackerelClient, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	c := &RecordingMackerelClient{
+		client: client,
+		dir:    dir,
+	}
+	if err := c.saveRecordedAt(flextime.Now()); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// saveRecordedAt saves the time of the run, the replay must be evaluated at this time.
+func (c *RecordingMackerelClient) saveRecordedAt(now time.Time) error {
+	bs, err := json.MarshalIndent(recordingMeta{RecordedAt: now}, "", "  ")
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.WriteFile(filepath.Join(c.dir, recordingMetaFile), bs, 0644); err != nil {
+		return fmt.Errorf("failed to write recording meta: %w", err)
+	}
+	return nil
+}
+
+func (c *RecordingMackerelClient) record(response interface{}, method string, args ...interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.save(response, method, args...)
+}
+
+// save writes the fixture, the caller must hold the lock.
+func (c *RecordingMackerelClient) save(response interface{}, method string, args ...interface{}) error {
+	name, err := fixtureFileName(method, args...)
+	if err != nil {
+		return err
+	}
+	bs, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	bs, err = json.MarshalIndent(fixture{Method: method, Args: args, Response: bs}, "", "  ")
+	if err != nil {
+		return err
+	}
+	log.Printf("[debug] record %s to %s", method, name)
+	if err := os.WriteFile(filepath.Join(c.dir, name), bs, 0644); err != nil {
+		return fmt.Errorf("failed to record %s: %w", method, err)
+	}
+	return nil
+}
+
+// load reads the response of the fixture recorded before, the caller must hold the lock.
+// It returns false if the fixture is not recorded yet.
+func (c *RecordingMackerelClient) load(response interface{}, method string, args ...interface{}) (bool, error) {
+	name, err := fixtureFileName(method, args...)
+	if err != nil {
+		return false, err
+	}
+	bs, err := os.ReadFile(filepath.Join(c.dir, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	var f fixture
+	if err := json.Unmarshal(bs, &f); err != nil {
+		return false, fmt.Errorf("failed to parse fixture %s: %w", name, err)
+	}
+	return true, json.Unmarshal(f.Response, response)
+}
+
+// recordMetricValues merges the metric values into the values recorded before.
+func (c *RecordingMackerelClient) recordMetricValues(values []mackerel.MetricValue, method string, args ...interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var recorded []mackerel.MetricValue
+	if _, err := c.load(&recorded, method, args...); err != nil {
+		return err
+	}
+	byTime := make(map[int64]mackerel.MetricValue, len(recorded)+len(values))
+	for _, value := range recorded {
+		byTime[value.Time] = value
+	}
+	for _, value := range values {
+		byTime[value.Time] = value
+	}
+	merged := make([]mackerel.MetricValue, 0, len(byTime))
+	for _, value := range byTime {
+		merged = append(merged, value)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Time < merged[j].Time
+	})
+	return c.save(merged, method, args...)
+}
+
+// recordGraphAnnotations merges the graph annotations into the annotations recorded before.
+func (c *RecordingMackerelClient) recordGraphAnnotations(annotations []*mackerel.GraphAnnotation, method string, args ...interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var recorded []*mackerel.GraphAnnotation
+	if _, err := c.load(&recorded, method, args...); err != nil {
+		return err
+	}
+	byID := make(map[string]*mackerel.GraphAnnotation, len(recorded)+len(annotations))
+	for _, annotation := range recorded {
+		byID[annotation.ID] = annotation
+	}
+	for _, annotation := range annotations {
+		byID[annotation.ID] = annotation
+	}
+	merged := make([]*mackerel.GraphAnnotation, 0, len(byID))
+	for _, annotation := range byID {
+		merged = append(merged, annotation)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].From != merged[j].From {
+			return merged[i].From < merged[j].From
+		}
+		return merged[i].ID < merged[j].ID
+	})
+	return c.save(merged, method, args...)
+}
+
+func (c *RecordingMackerelClient) GetOrg() (*mackerel.Org, error) {
+	org, err := c.client.GetOrg()
+	if err != nil {
+		return nil, err
+	}
+	return org, c.record(org, "GetOrg")
+}
+
+func (c *RecordingMackerelClient) FindHosts(param *mackerel.FindHostsParam) ([]*mackerel.Host, error) {
+	hosts, err := c.client.FindHosts(param)
+	if err != nil {
+		return nil, err
+	}
+	return hosts, c.record(hosts, "FindHosts", param)
+}
+
+func (c *RecordingMackerelClient) FetchHostMetricValues(hostID string, metricName string, from int64, to int64) ([]mackerel.MetricValue, error) {
+	values, err := c.client.FetchHostMetricValues(hostID, metricName, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return values, c.recordMetricValues(values, "FetchHostMetricValues", hostID, metricName)
+}
+
+func (c *RecordingMackerelClient) FetchServiceMetricValues(serviceName string, metricName string, from int64, to int64) ([]mackerel.MetricValue, error) {
+	values, err := c.client.FetchServiceMetricValues(serviceName, metricName, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return values, c.recordMetricValues(values, "FetchServiceMetricValues", serviceName, metricName)
+}
+
+// PostServiceMetricValues posts to the client as it is, it is not recorded.
+func (c *RecordingMackerelClient) PostServiceMetricValues(serviceName string, metricValues []*mackerel.MetricValue) error {
+	return c.client.PostServiceMetricValues(serviceName, metricValues)
+}
+
+func (c *RecordingMackerelClient) FindWithClosedAlerts() (*mackerel.AlertsResp, error) {
+	resp, err := c.client.FindWithClosedAlerts()
+	if err != nil {
+		return nil, err
+	}
+	return resp, c.record(resp, "FindWithClosedAlerts")
+}
+
+func (c *RecordingMackerelClient) FindWithClosedAlertsByNextID(nextID string) (*mackerel.AlertsResp, error) {
+	resp, err := c.client.FindWithClosedAlertsByNextID(nextID)
+	if err != nil {
+		return nil, err
+	}
+	return resp, c.record(resp, "FindWithClosedAlertsByNextID", nextID)
+}
+
+func (c *RecordingMackerelClient) GetMonitor(monitorID string) (mackerel.Monitor, error) {
+	monitor, err := c.client.GetMonitor(monitorID)
+	if err != nil {
+		return nil, err
+	}
+	f, err := newMonitorFixture(monitor)
+	if err != nil {
+		return nil, err
+	}
+	return monitor, c.record(f, "GetMonitor", monitorID)
+}
+
+func (c *RecordingMackerelClient) FindMonitors() ([]mackerel.Monitor, error) {
+	monitors, err := c.client.FindMonitors()
+	if err != nil {
+		return nil, err
+	}
+	fs := make([]*monitorFixture, 0, len(monitors))
+	for _, monitor := range monitors {
+		f, err := newMonitorFixture(monitor)
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, f)
+	}
+	return monitors, c.record(fs, "FindMonitors")
+}
+
+func (c *RecordingMackerelClient) FindGraphAnnotations(service string, from int64, to int64) ([]*mackerel.GraphAnnotation, error) {
+	annotations, err := c.client.FindGraphAnnotations(service, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return annotations, c.recordGraphAnnotations(annotations, "FindGraphAnnotations", service)
+}
+
+func (c *RecordingMackerelClient) FindDowntimes() ([]*mackerel.Downtime, error) {
+	downtimes, err := c.client.FindDowntimes()
+	if err != nil {
+		return nil, err
+	}
+	return downtimes, c.record(downtimes, "FindDowntimes")
+}
+
+func newMonitorFixture(monitor mackerel.Monitor) (*monitorFixture, error) {
+	bs, err := json.Marshal(monitor)
+	if err != nil {
+		return nil, err
+	}
+	return &monitorFixture{Type: monitor.MonitorType(), Monitor: bs}, nil
+}
+
+func (f *monitorFixture) decode() (mackerel.Monitor, error) {
+	var m mackerel.Monitor
+	switch f.Type {
+	case "connectivity":
+		m = &mackerel.MonitorConnectivity{}
+	case "host":
+		m = &mackerel.MonitorHostMetric{}
+	case "service":
+		m = &mackerel.MonitorServiceMetric{}
+	case "external":
+		m = &mackerel.MonitorExternalHTTP{}
+	case "expression":
+		m = &mackerel.MonitorExpression{}
+	case "anomalyDetection":
+		m = &mackerel.MonitorAnomalyDetection{}
+	case "query":
+		m = &mackerel.MonitorQuery{}
+	default:
+		return nil, fmt.Errorf("unknown monitor type `%s`", f.Type)
+	}
+	if err := json.Unmarshal(f.Monitor, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ReplayingMackerelClient is a MackerelClient that serves the responses in the fixture directory recorded by RecordingMackerelClient.
+// It does not access Mackerel API at all.
+type ReplayingMackerelClient struct {
+	dir        string
+	recordedAt time.Time
+}
+
+// NewReplayingMackerelClient creates ReplayingMackerelClient
+func NewReplayingMackerelClient(dir string) (*ReplayingMackerelClient, error) {
+	bs, err := os.ReadFile(filepath.Join(dir, recordingMetaFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording meta: %w", err)
+	}
+	var meta recordingMeta
+	if err := json.Unmarshal(bs, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse recording meta: %w", err)
+	}
+	return &ReplayingMackerelClient{
+		dir:        dir,
+		recordedAt: meta.RecordedAt,
+	}, nil
+}
+
+// RecordedAt returns the time when the fixtures were recorded. the evaluation must be done at this time to replay.
+func (c *ReplayingMackerelClient) RecordedAt() time.Time {
+	return c.recordedAt
+}
+
+func (c *ReplayingMackerelClient) replay(response interface{}, method string, args ...interface{}) error {
+	name, err := fixtureFileName(method, args...)
+	if err != nil {
+		return err
+	}
+	bs, err := os.ReadFile(filepath.Join(c.dir, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("fixture of %s%v is not recorded", method, args)
+		}
+		return err
+	}
+	var f fixture
+	if err := json.Unmarshal(bs, &f); err != nil {
+		return fmt.Errorf("failed to parse fixture %s: %w", name, err)
+	}
+	log.Printf("[debug] replay %s from %s", method, name)
+	return json.Unmarshal(f.Response, response)
+}
+
+func (c *ReplayingMackerelClient) GetOrg() (*mackerel.Org, error) {
+	var org *mackerel.Org
+	if err := c.replay(&org, "GetOrg"); err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+func (c *ReplayingMackerelClient) FindHosts(param *mackerel.FindHostsParam) ([]*mackerel.Host, error) {
+	var hosts []*mackerel.Host
+	if err := c.replay(&hosts, "FindHosts", param); err != nil {
+		return nil, err
+	}
+	return hosts, nil
+}
+
+func (c *ReplayingMackerelClient) FetchHostMetricValues(hostID string, metricName string, from int64, to int64) ([]mackerel.MetricValue, error) {
+	var values []mackerel.MetricValue
+	if err := c.replay(&values, "FetchHostMetricValues", hostID, metricName); err != nil {
+		return nil, err
+	}
+	return metricValuesInRange(values, from, to), nil
+}
+
+func (c *ReplayingMackerelClient) FetchServiceMetricValues(serviceName string, metricName string, from int64, to int64) ([]mackerel.MetricValue, error) {
+	var values []mackerel.MetricValue
+	if err := c.replay(&values, "FetchServiceMetricValues", serviceName, metricName); err != nil {
+		return nil, err
+	}
+	return metricValuesInRange(values, from, to), nil
+}
+
+// PostServiceMetricValues does nothing, the replay never changes Mackerel.
+func (c *ReplayingMackerelClient) PostServiceMetricValues(serviceName string, metricValues []*mackerel.MetricValue) error {
+	log.Printf("[info] replay mode, %d metric values are not posted to service `%s`", len(metricValues), serviceName)
+	return nil
+}
+
+func (c *ReplayingMackerelClient) FindWithClosedAlerts() (*mackerel.AlertsResp, error) {
+	var resp *mackerel.AlertsResp
+	if err := c.replay(&resp, "FindWithClosedAlerts"); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ReplayingMackerelClient) FindWithClosedAlertsByNextID(nextID string) (*mackerel.AlertsResp, error) {
+	var resp *mackerel.AlertsResp
+	if err := c.replay(&resp, "FindWithClosedAlertsByNextID", nextID); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ReplayingMackerelClient) GetMonitor(monitorID string) (mackerel.Monitor, error) {
+	var f monitorFixture
+	if err := c.replay(&f, "GetMonitor", monitorID); err != nil {
+		return nil, err
+	}
+	return f.decode()
+}
+
+func (c *ReplayingMackerelClient) FindMonitors() ([]mackerel.Monitor, error) {
+	var fs []*monitorFixture
+	if err := c.replay(&fs, "FindMonitors"); err != nil {
+		return nil, err
+	}
+	monitors := make([]mackerel.Monitor, 0, len(fs))
+	for _, f := range fs {
+		monitor, err := f.decode()
+		if err != nil {
+			return nil, err
+		}
+		monitors = append(monitors, monitor)
+	}
+	return monitors, nil
+}
+
+func (c *ReplayingMackerelClient) FindGraphAnnotations(service string, from int64, to int64) ([]*mackerel.GraphAnnotation, error) {
+	var annotations []*mackerel.GraphAnnotation
+	if err := c.replay(&annotations, "FindGraphAnnotations", service); err != nil {
+		return nil, err
+	}
+	ret := make([]*mackerel.GraphAnnotation, 0, len(annotations))
+	for _, annotation := range annotations {
+		if annotation.To >= from && annotation.From <= to {
+			ret = append(ret, annotation)
+		}
+	}
+	return ret, nil
+}
+
+func (c *ReplayingMackerelClient) FindDowntimes() ([]*mackerel.Downtime, error) {
+	var downtimes []*mackerel.Downtime
+	if err := c.replay(&downtimes, "FindDowntimes"); err != nil {
+		return nil, err
+	}
+	return downtimes, nil
+}
+
+// metricValuesInRange returns the metric values between from and to, both inclusive like Mackerel API.
+func metricValuesInRange(values []mackerel.MetricValue, from int64, to int64) []mackerel.MetricValue {
+	ret := make([]mackerel.MetricValue, 0, len(values))
+	for _, value := range values {
+		if value.Time >= from && value.Time <= to {
+			ret = append(ret, value)
+		}
+	}
+	return ret
+}
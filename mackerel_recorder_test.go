@@ -0,0 +1,93 @@
+package shimesaba_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Songmu/flextime"
+	mackerel "github.com/mackerelio/mackerel-client-go"
+	"github.com/mashiike/shimesaba"
+	"github.com/stretchr/testify/require"
+)
+
+type postCapturingClient struct {
+	shimesaba.MackerelClient
+	posted []*mackerel.MetricValue
+}
+
+func (c *postCapturingClient) PostServiceMetricValues(serviceName string, metricValues []*mackerel.MetricValue) error {
+	c.posted = append(c.posted, metricValues...)
+	return c.MackerelClient.PostServiceMetricValues(serviceName, metricValues)
+}
+
+// tickingClock is a clock that moves forward by step every time it is read, like a run that takes time.
+type tickingClock struct {
+	mu   sync.Mutex
+	now  time.Time
+	step time.Duration
+}
+
+func (c *tickingClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now
+	c.now = c.now.Add(c.step)
+	return now
+}
+
+func (c *tickingClock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestRecordAndReplayMackerelClient(t *testing.T) {
+	for _, configFile := range []string{
+		"testdata/app_test.yaml",
+		"testdata/app_metric_based_test.yaml",
+	} {
+		t.Run(configFile, func(t *testing.T) {
+			dir := t.TempDir()
+			restore := flextime.Switch(flextime.NewFakeClock(&tickingClock{
+				now:  time.Date(2021, 10, 1, 0, 20, 30, 0, time.UTC),
+				step: time.Second,
+			}))
+			defer restore()
+
+			cfg := shimesaba.NewDefaultConfig()
+			require.NoError(t, cfg.Load(configFile))
+			recorded := &postCapturingClient{MackerelClient: newMockMackerelClient(t)}
+			recorder, err := shimesaba.NewRecordingMackerelClient(recorded, dir)
+			require.NoError(t, err)
+			app, err := shimesaba.NewWithMackerelClient(recorder, cfg)
+			require.NoError(t, err)
+			require.NoError(t, app.Run(context.Background()))
+			require.NotEmpty(t, recorded.posted)
+
+			restore()
+			replayer, err := shimesaba.NewReplayingMackerelClient(dir)
+			require.NoError(t, err)
+			require.True(t, replayer.RecordedAt().After(time.Date(2021, 10, 1, 0, 20, 30, 0, time.UTC)), "the time of the run is recorded, not the time the client was created")
+			restore = flextime.Fix(replayer.RecordedAt())
+			replayed := &postCapturingClient{MackerelClient: replayer}
+			cfg = shimesaba.NewDefaultConfig()
+			require.NoError(t, cfg.Load(configFile))
+			app, err = shimesaba.NewWithMackerelClient(replayed, cfg)
+			require.NoError(t, err)
+			require.NoError(t, app.Run(context.Background()))
+			require.ElementsMatch(t, recorded.posted, replayed.posted)
+		})
+	}
+}
+
+func TestReplayingMackerelClientNotRecorded(t *testing.T) {
+	dir := t.TempDir()
+	_, err := shimesaba.NewRecordingMackerelClient(newMockMackerelClient(t), dir)
+	require.NoError(t, err)
+	replayer, err := shimesaba.NewReplayingMackerelClient(dir)
+	require.NoError(t, err)
+	_, err = replayer.FindMonitors()
+	require.Error(t, err)
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	mackerel "github.com/mackerelio/mackerel-client-go"
 	"github.com/mashiike/shimesaba"
 	"github.com/stretchr/testify/require"
 )
@@ -54,6 +55,25 @@ func TestRepositoryFetchVirtualAlerts(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:        "SLO-exclude:upstream",
+			serviceName: "shimesaba",
+			sloID:       "upstream",
+			startAt:     time.Date(2021, 10, 1, 0, 5, 0, 0, time.UTC),
+			endAt:       time.Date(2021, 10, 1, 0, 15, 0, 0, time.UTC),
+			expected: shimesaba.Alerts{
+				{
+					Reason:   "SLO:*",
+					OpenedAt: time.Date(2021, 10, 1, 0, 10, 0, 0, time.UTC),
+					ClosedAt: ptrTime(time.Date(2021, 10, 1, 0, 15, 0, 0, time.UTC)),
+				},
+				shimesaba.NewVirtualExclusionAlert(
+					"Upstream provider outage SLO-exclude:upstream",
+					time.Date(2021, 10, 1, 0, 10, 0, 0, time.UTC),
+					time.Date(2021, 10, 1, 0, 15, 0, 0, time.UTC),
+				),
+			},
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -101,6 +121,7 @@ func TestRepositoryFetchAlerts(t *testing.T) {
 					OpenedAt: time.Date(2021, 10, 1, 0, 17, 0, 0, time.UTC),
 					Monitor:  shimesaba.NewMonitor("dummyCheckMonitorID", "check monitor dummyCheckMonitorID", "check"),
 					ClosedAt: nil,
+					Severity: shimesaba.AlertSeverityWarning,
 				},
 			},
 		},
@@ -118,3 +139,108 @@ func TestRepositoryFetchAlerts(t *testing.T) {
 		})
 	}
 }
+
+func TestRepositoryFetchDowntimes(t *testing.T) {
+	client := newMockMackerelClient(t)
+	repo := shimesaba.NewRepository(client)
+	downtimes, err := repo.FetchDowntimes(
+		context.Background(),
+		time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 10, 1, 1, 0, 0, 0, time.UTC),
+	)
+	require.NoError(t, err)
+	require.Len(t, downtimes, 2, "past downtime is not returned")
+
+	monitor := shimesaba.NewMonitor("dummyMonitorID", "Dummy Service Metric Monitor", "service")
+	other := shimesaba.NewMonitor("otherMonitorID", "Other Monitor", "host")
+	cases := []struct {
+		name     string
+		alert    *shimesaba.Alert
+		expected bool
+	}{
+		{
+			name:     "weekly on friday, in monitor scopes",
+			alert:    shimesaba.NewAlert(monitor, time.Date(2021, 10, 1, 0, 10, 0, 0, time.UTC), nil),
+			expected: true,
+		},
+		{
+			name:     "weekly on friday, after the downtime",
+			alert:    shimesaba.NewAlert(monitor, time.Date(2021, 10, 1, 0, 30, 0, 0, time.UTC), nil),
+			expected: false,
+		},
+		{
+			name:     "weekly on friday, out of monitor scopes",
+			alert:    shimesaba.NewAlert(other, time.Date(2021, 10, 1, 0, 10, 0, 0, time.UTC), nil),
+			expected: false,
+		},
+		{
+			name:     "daily, host in service scopes",
+			alert:    shimesaba.NewAlert(other, time.Date(2021, 10, 1, 0, 45, 0, 0, time.UTC), nil).WithHostID("dummyHostID"),
+			expected: true,
+		},
+		{
+			name:     "daily, host out of service scopes",
+			alert:    shimesaba.NewAlert(other, time.Date(2021, 10, 1, 0, 45, 0, 0, time.UTC), nil).WithHostID("otherHostID"),
+			expected: false,
+		},
+		{
+			name:     "daily, alert without host",
+			alert:    shimesaba.NewAlert(monitor, time.Date(2021, 10, 1, 0, 45, 0, 0, time.UTC), nil),
+			expected: false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.expected, downtimes.Covers(c.alert))
+		})
+	}
+	require.True(t, downtimes[0].Monitors()[0].IsMuted(), "mute state of the monitor")
+}
+
+func TestRepositoryFetchDowntimesOnce(t *testing.T) {
+	client, err := shimesaba.NewPolicyMackerelClient(newMockMackerelClient(t), nil)
+	require.NoError(t, err)
+	repo := shimesaba.NewRepository(client)
+	for i := 0; i < 3; i++ {
+		startAt := time.Date(2021, 10, 1, i, 0, 0, 0, time.UTC)
+		_, err := repo.FetchDowntimes(context.Background(), startAt, startAt.Add(time.Hour))
+		require.NoError(t, err)
+		monitors, err := repo.FetchMonitors(context.Background())
+		require.NoError(t, err)
+		require.Len(t, monitors, 1)
+	}
+	require.Equal(t, map[string]int{
+		"FindDowntimes": 1,
+		"FindMonitors":  1,
+		"FindHosts":     1,
+	}, client.CallCounts(), "the downtimes and the scopes are fetched once for the repository")
+}
+
+// metricCountingClient counts the calls of FetchServiceMetricValues for reassessment.
+type metricCountingClient struct {
+	*mockMackerelClient
+	fetched int
+}
+
+func (c *metricCountingClient) FetchServiceMetricValues(serviceName string, metricName string, from int64, to int64) ([]mackerel.MetricValue, error) {
+	c.fetched++
+	return []mackerel.MetricValue{}, nil
+}
+
+func TestRepositoryWithClient(t *testing.T) {
+	repo := shimesaba.NewRepository(newMockMackerelClient(t))
+	startAt := time.Date(2021, 10, 1, 0, 5, 0, 0, time.UTC)
+	endAt := time.Date(2021, 10, 1, 0, 15, 0, 0, time.UTC)
+	clients := []*metricCountingClient{
+		{mockMackerelClient: newMockMackerelClient(t)},
+		{mockMackerelClient: newMockMackerelClient(t)},
+	}
+	for i, client := range clients {
+		alerts, err := repo.WithClient(client).FetchAlerts(context.Background(), startAt, endAt)
+		require.NoError(t, err)
+		require.Len(t, alerts, 1)
+		_, ok := alerts[0].Monitor.EvaluateReliabilities("", time.Minute, shimesaba.AlertSeverityUnknown, startAt, endAt)
+		require.True(t, ok)
+		require.Equal(t, 1, client.fetched, "run %d reassesses with its own client", i)
+	}
+}
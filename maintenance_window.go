@@ -0,0 +1,120 @@
+package shimesaba
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// MaintenanceWindow is a scheduled period excluded from the SLO.
+// It is either a one-off range or a recurring schedule.
+type MaintenanceWindow struct {
+	startAt  time.Time
+	endAt    time.Time
+	schedule cron.Schedule
+	duration time.Duration
+}
+
+// NewMaintenanceWindow creates a one-off MaintenanceWindow of [startAt, endAt).
+func NewMaintenanceWindow(startAt, endAt time.Time) *MaintenanceWindow {
+	return &MaintenanceWindow{
+		startAt: startAt,
+		endAt:   endAt,
+	}
+}
+
+// NewRecurringMaintenanceWindow creates a MaintenanceWindow that starts at each time of the cron-like schedule in loc and lasts duration.
+func NewRecurringMaintenanceWindow(schedule string, loc *time.Location, duration time.Duration) (*MaintenanceWindow, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	s, err := cron.ParseStandard(fmt.Sprintf("CRON_TZ=%s %s", loc, schedule))
+	if err != nil {
+		return nil, err
+	}
+	return &MaintenanceWindow{
+		schedule: s,
+		duration: duration,
+	}, nil
+}
+
+// Contains reports whether t is in the maintenance window.
+func (w *MaintenanceWindow) Contains(t time.Time) bool {
+	if w.schedule == nil {
+		return !t.Before(w.startAt) && t.Before(w.endAt)
+	}
+	// the latest occurrence started in (t - duration, t] covers t.
+	return !w.schedule.Next(t.Add(-w.duration)).After(t)
+}
+
+// timeline returns the minutes in [startAt, endAt) that are in the maintenance window as the spans of the value 1.0.
+// The occurrences of the recurring schedule are iterated with the schedule, not for each minute.
+func (w *MaintenanceWindow) timeline(startAt, endAt time.Time) timeline {
+	if w.schedule == nil {
+		return newMinuteSpanTimeline(w.startAt, w.endAt).clip(startAt, endAt)
+	}
+	var tl timeline
+	// the occurrences started in (startAt - duration, endAt) overlap the period.
+	for at := w.schedule.Next(startAt.Add(-w.duration)); at.Before(endAt); at = w.schedule.Next(at) {
+		occurrence := newMinuteSpanTimeline(at, at.Add(w.duration))
+		if len(occurrence) == 0 {
+			continue
+		}
+		if n := len(tl); n > 0 && !occurrence[0].startAt.After(tl[n-1].endAt) {
+			// the occurrence overlaps the previous one, if the duration is longer than the interval.
+			if occurrence[0].endAt.After(tl[n-1].endAt) {
+				tl[n-1].endAt = occurrence[0].endAt
+			}
+			continue
+		}
+		tl = append(tl, occurrence[0])
+	}
+	return tl.clip(startAt, endAt)
+}
+
+// newMinuteSpanTimeline creates timeline of the minutes in [startAt, endAt), that is the minutes whose start is in the period.
+func newMinuteSpanTimeline(startAt, endAt time.Time) timeline {
+	ceil := func(t time.Time) time.Time {
+		if rounded := t.Truncate(time.Minute); rounded.Before(t) {
+			return rounded.Add(time.Minute).UTC()
+		}
+		return t.UTC()
+	}
+	return timeline(nil).append(span{startAt: ceil(startAt), endAt: ceil(endAt), value: 1.0})
+}
+
+// MaintenanceWindows is a collection of MaintenanceWindow
+type MaintenanceWindows []*MaintenanceWindow
+
+// Contains reports whether t is in any of the maintenance windows.
+func (windows MaintenanceWindows) Contains(t time.Time) bool {
+	for _, w := range windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExcludedMinutes returns the minutes between startAt and endAt that are in any of the maintenance windows.
+func (windows MaintenanceWindows) ExcludedMinutes(startAt, endAt time.Time) IsExcludedCollection {
+	excluded := make(IsExcludedCollection)
+	for t := range windows.timeline(startAt.Truncate(time.Minute), endAt).minuteValues() {
+		excluded[t] = true
+	}
+	return excluded
+}
+
+// timeline returns the minutes in [startAt, endAt) that are in any of the maintenance windows as the spans of the value 1.0.
+func (windows MaintenanceWindows) timeline(startAt, endAt time.Time) timeline {
+	if len(windows) == 0 {
+		return nil
+	}
+	startAt, endAt = startAt.UTC(), endAt.UTC()
+	timelines := make([]timeline, 0, len(windows))
+	for _, w := range windows {
+		timelines = append(timelines, w.timeline(startAt, endAt))
+	}
+	return combineTimelines(startAt, endAt, maxValue, timelines...)
+}
@@ -0,0 +1,105 @@
+package shimesaba_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mashiike/shimesaba"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenanceWindowContains(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	// every Sunday 03:00~05:00 in Asia/Tokyo, that is Saturday 18:00~20:00 in UTC.
+	recurring, err := shimesaba.NewRecurringMaintenanceWindow("0 3 * * 0", tokyo, 2*time.Hour)
+	require.NoError(t, err)
+	oneOff := shimesaba.NewMaintenanceWindow(
+		time.Date(2022, 1, 5, 10, 0, 0, 0, time.UTC),
+		time.Date(2022, 1, 5, 10, 30, 0, 0, time.UTC),
+	)
+	cases := []struct {
+		window   *shimesaba.MaintenanceWindow
+		t        time.Time
+		expected bool
+	}{
+		{window: oneOff, t: time.Date(2022, 1, 5, 9, 59, 0, 0, time.UTC), expected: false},
+		{window: oneOff, t: time.Date(2022, 1, 5, 10, 0, 0, 0, time.UTC), expected: true},
+		{window: oneOff, t: time.Date(2022, 1, 5, 10, 29, 0, 0, time.UTC), expected: true},
+		{window: oneOff, t: time.Date(2022, 1, 5, 10, 30, 0, 0, time.UTC), expected: false},
+		{window: recurring, t: time.Date(2022, 1, 8, 17, 59, 0, 0, time.UTC), expected: false},
+		{window: recurring, t: time.Date(2022, 1, 8, 18, 0, 0, 0, time.UTC), expected: true},
+		{window: recurring, t: time.Date(2022, 1, 8, 19, 59, 0, 0, time.UTC), expected: true},
+		{window: recurring, t: time.Date(2022, 1, 8, 20, 0, 0, 0, time.UTC), expected: false},
+		{window: recurring, t: time.Date(2022, 1, 15, 18, 30, 0, 0, time.UTC), expected: true},
+		{window: recurring, t: time.Date(2022, 1, 9, 18, 30, 0, 0, time.UTC), expected: false},
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case.%d", i), func(t *testing.T) {
+			require.Equal(t, c.expected, c.window.Contains(c.t))
+		})
+	}
+
+	windows := shimesaba.MaintenanceWindows{oneOff, recurring}
+	excluded := windows.ExcludedMinutes(
+		time.Date(2022, 1, 5, 0, 0, 0, 0, time.UTC),
+		time.Date(2022, 1, 12, 0, 0, 0, 0, time.UTC),
+	)
+	require.Len(t, excluded, 30+120)
+	require.True(t, excluded.IsExcluded(time.Date(2022, 1, 8, 18, 0, 0, 0, time.UTC)))
+}
+
+func TestMaintenanceWindowsExcludedMinutes(t *testing.T) {
+	everyTenMinutes, err := shimesaba.NewRecurringMaintenanceWindow("*/10 * * * *", time.UTC, 15*time.Minute)
+	require.NoError(t, err)
+	hourly, err := shimesaba.NewRecurringMaintenanceWindow("0 * * * *", time.UTC, 5*time.Minute)
+	require.NoError(t, err)
+	cases := []struct {
+		name     string
+		windows  shimesaba.MaintenanceWindows
+		expected int
+	}{
+		{
+			name: "one-off not aligned to minutes",
+			windows: shimesaba.MaintenanceWindows{
+				shimesaba.NewMaintenanceWindow(
+					time.Date(2022, 1, 5, 10, 0, 30, 0, time.UTC),
+					time.Date(2022, 1, 5, 10, 2, 30, 0, time.UTC),
+				),
+			},
+			expected: 2,
+		},
+		{
+			name:     "occurrences overlapping each other",
+			windows:  shimesaba.MaintenanceWindows{everyTenMinutes},
+			expected: 24 * 60,
+		},
+		{
+			name:     "hourly occurrences",
+			windows:  shimesaba.MaintenanceWindows{hourly},
+			expected: 24 * 5,
+		},
+		{
+			name: "overlapping windows",
+			windows: shimesaba.MaintenanceWindows{
+				hourly,
+				shimesaba.NewMaintenanceWindow(
+					time.Date(2022, 1, 5, 10, 0, 0, 0, time.UTC),
+					time.Date(2022, 1, 5, 10, 30, 0, 0, time.UTC),
+				),
+			},
+			expected: 24*5 + 25,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			startAt := time.Date(2022, 1, 5, 0, 0, 0, 0, time.UTC)
+			excluded := c.windows.ExcludedMinutes(startAt, startAt.Add(24*time.Hour))
+			require.Len(t, excluded, c.expected)
+			for minute := range excluded {
+				require.True(t, c.windows.Contains(minute), "%s is in the maintenance windows", minute)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package shimesaba
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mashiike/shimesaba/internal/timeutils"
+)
+
+// MetricBasedSLI is an SLI based on the ratio of good events to total events posted as Mackerel service metrics.
+type MetricBasedSLI struct {
+	cfg                *MetricBasedSLIConfig
+	maintenanceWindows MaintenanceWindows
+}
+
+func NewMetricBasedSLI(cfg *MetricBasedSLIConfig) *MetricBasedSLI {
+	return &MetricBasedSLI{cfg: cfg}
+}
+
+// WithMaintenanceWindows returns MetricBasedSLI that ignores the events in the maintenance windows.
+func (o *MetricBasedSLI) WithMaintenanceWindows(windows MaintenanceWindows) *MetricBasedSLI {
+	return &MetricBasedSLI{cfg: o.cfg, maintenanceWindows: windows}
+}
+
+func (o MetricBasedSLI) String() string {
+	return fmt.Sprintf("metric_based_sli[service=%s, good=%s, total=%s]", o.cfg.ServiceName, o.cfg.GoodEventMetric, o.cfg.TotalEventMetric)
+}
+
+// EvaluateReliabilities sums up good and total events for each tumbling window.
+func (o MetricBasedSLI) EvaluateReliabilities(ctx context.Context, provider DataProvider, timeFrame time.Duration, startAt, endAt time.Time) (Reliabilities, error) {
+	goodValues, err := provider.FetchServiceMetricValues(ctx, o.cfg.ServiceName, o.cfg.GoodEventMetric, startAt, endAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch good event metric `%s`: %w", o.cfg.GoodEventMetric, err)
+	}
+	totalValues, err := provider.FetchServiceMetricValues(ctx, o.cfg.ServiceName, o.cfg.TotalEventMetric, startAt, endAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch total event metric `%s`: %w", o.cfg.TotalEventMetric, err)
+	}
+	log.Printf("[debug] %s get %d good values, %d total values", o, len(goodValues), len(totalValues))
+	goodEvents := goodValues.Exclude(o.maintenanceWindows).SumByTimeFrame(timeFrame)
+	totalEvents := totalValues.Exclude(o.maintenanceWindows).SumByTimeFrame(timeFrame)
+
+	iter := timeutils.NewIterator(startAt, endAt, timeFrame)
+	iter.SetEnableOverWindow(true)
+	rc := make([]*Reliability, 0)
+	for iter.HasNext() {
+		cursorAt, _ := iter.Next()
+		cursorAt = cursorAt.Truncate(timeFrame)
+		good, total := goodEvents[cursorAt], totalEvents[cursorAt]
+		if good > total {
+			log.Printf("[warn] %s good events %f greater than total events %f at %s, treat as all good", o, good, total, cursorAt)
+			good = total
+		}
+		rc = append(rc, NewReliabilityWithEvents(cursorAt, timeFrame, good, total))
+	}
+	return NewReliabilities(rc)
+}
+
+// MetricValues is a time series of metric values. the key is the time of the data point.
+type MetricValues map[time.Time]float64
+
+// Exclude returns MetricValues without the data points in the maintenance windows.
+func (values MetricValues) Exclude(windows MaintenanceWindows) MetricValues {
+	if len(windows) == 0 {
+		return values
+	}
+	ret := make(MetricValues, len(values))
+	for t, v := range values {
+		if !windows.Contains(t) {
+			ret[t] = v
+		}
+	}
+	return ret
+}
+
+// SumByTimeFrame sums up values for each tumbling window. the key is the start time of the tumbling window.
+func (values MetricValues) SumByTimeFrame(timeFrame time.Duration) map[time.Time]float64 {
+	sums := make(map[time.Time]float64)
+	for t, v := range values {
+		sums[t.Truncate(timeFrame).UTC()] += v
+	}
+	return sums
+}
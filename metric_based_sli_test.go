@@ -0,0 +1,94 @@
+package shimesaba_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mashiike/shimesaba"
+	"github.com/stretchr/testify/require"
+)
+
+type stubDataProvider struct {
+	shimesaba.DataProvider
+	metrics map[string]shimesaba.MetricValues
+}
+
+func (p *stubDataProvider) FetchServiceMetricValues(_ context.Context, serviceName string, metricName string, startAt time.Time, endAt time.Time) (shimesaba.MetricValues, error) {
+	values := make(shimesaba.MetricValues)
+	for t, v := range p.metrics[serviceName+"/"+metricName] {
+		if t.Before(startAt) || t.After(endAt) {
+			continue
+		}
+		values[t] = v
+	}
+	return values, nil
+}
+
+func (p *stubDataProvider) FetchVirtualAlerts(_ context.Context, _ string, _ string, _ time.Time, _ time.Time) (shimesaba.Alerts, error) {
+	return nil, nil
+}
+
+func (p *stubDataProvider) FetchDowntimes(_ context.Context, _ time.Time, _ time.Time) (shimesaba.Downtimes, error) {
+	return nil, nil
+}
+
+func (p *stubDataProvider) FetchMonitors(_ context.Context) ([]*shimesaba.Monitor, error) {
+	return nil, nil
+}
+
+func (p *stubDataProvider) FetchHostMetricValues(_ context.Context, hostID string, metricName string, startAt time.Time, endAt time.Time) (shimesaba.MetricValues, error) {
+	values := make(shimesaba.MetricValues)
+	for t, v := range p.metrics["host:"+hostID+"/"+metricName] {
+		if t.Before(startAt) || t.After(endAt) {
+			continue
+		}
+		values[t] = v
+	}
+	return values, nil
+}
+
+func TestMetricBasedSLI(t *testing.T) {
+	provider := &stubDataProvider{
+		metrics: map[string]shimesaba.MetricValues{
+			"shimesaba/requests.2xx": {
+				time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC): 100,
+				time.Date(2021, time.October, 1, 0, 1, 0, 0, time.UTC): 100,
+				time.Date(2021, time.October, 1, 0, 2, 0, 0, time.UTC): 50,
+				time.Date(2021, time.October, 1, 0, 3, 0, 0, time.UTC): 100,
+			},
+			"shimesaba/requests.total": {
+				time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC): 100,
+				time.Date(2021, time.October, 1, 0, 1, 0, 0, time.UTC): 100,
+				time.Date(2021, time.October, 1, 0, 2, 0, 0, time.UTC): 100,
+				time.Date(2021, time.October, 1, 0, 3, 0, 0, time.UTC): 100,
+			},
+		},
+	}
+	obj := shimesaba.NewMetricBasedSLI(&shimesaba.MetricBasedSLIConfig{
+		ServiceName:      "shimesaba",
+		GoodEventMetric:  "requests.2xx",
+		TotalEventMetric: "requests.total",
+	})
+	actual, err := obj.EvaluateReliabilities(
+		context.Background(),
+		provider,
+		2*time.Minute,
+		time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, time.October, 1, 0, 3, 59, 0, time.UTC),
+	)
+	require.NoError(t, err)
+	require.Equal(t, 2, actual.Len())
+
+	require.EqualValues(t, time.Date(2021, time.October, 1, 0, 4, 0, 0, time.UTC), actual[0].CursorAt())
+	require.EqualValues(t, 150.0, actual[0].GoodEvents())
+	require.EqualValues(t, 200.0, actual[0].TotalEvents())
+	require.EqualValues(t, 30*time.Second, actual[0].FailureTime())
+	require.EqualValues(t, 90*time.Second, actual[0].UpTime())
+
+	require.EqualValues(t, time.Date(2021, time.October, 1, 0, 2, 0, 0, time.UTC), actual[1].CursorAt())
+	require.EqualValues(t, 200.0, actual[1].GoodEvents())
+	require.EqualValues(t, 200.0, actual[1].TotalEvents())
+	require.EqualValues(t, time.Duration(0), actual[1].FailureTime())
+	require.EqualValues(t, 2*time.Minute, actual[1].UpTime())
+}
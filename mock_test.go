@@ -2,6 +2,7 @@ package shimesaba_test
 
 import (
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 
 type mockMackerelClient struct {
 	shimesaba.MackerelClient
+	mu     sync.Mutex
 	posted []*mackerel.MetricValue
 	t      *testing.T
 }
@@ -34,7 +36,6 @@ func (m *mockMackerelClient) FindHosts(param *mackerel.FindHostsParam) ([]*macke
 		m.t,
 		&mackerel.FindHostsParam{
 			Service: "shimesaba",
-			Name:    "dummy-alb",
 		},
 		param,
 	)
@@ -47,6 +48,8 @@ func (m *mockMackerelClient) FindHosts(param *mackerel.FindHostsParam) ([]*macke
 
 func (m *mockMackerelClient) PostServiceMetricValues(serviceName string, metricValues []*mackerel.MetricValue) error {
 	require.Equal(m.t, "shimesaba", serviceName)
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.posted = append(m.posted, metricValues...)
 	return nil
 }
@@ -134,6 +137,13 @@ var graphAnnotations = []*mackerel.GraphAnnotation{
 		From:        time.Date(2021, 10, 1, 0, 10, 0, 0, time.UTC).Unix(),
 		To:          time.Date(2021, 10, 1, 0, 15, 0, 0, time.UTC).Unix(),
 	},
+	{
+		ID:          "wwwwwwwwwww",
+		Title:       "hogehogehoge",
+		Description: "Upstream provider outage SLO-exclude:upstream",
+		From:        time.Date(2021, 10, 1, 0, 10, 0, 0, time.UTC).Unix(),
+		To:          time.Date(2021, 10, 1, 0, 15, 0, 0, time.UTC).Unix(),
+	},
 }
 
 func (m *mockMackerelClient) FindGraphAnnotations(service string, from int64, to int64) ([]*mackerel.GraphAnnotation, error) {
@@ -141,3 +151,74 @@ func (m *mockMackerelClient) FindGraphAnnotations(service string, from int64, to
 
 	return graphAnnotations, nil
 }
+
+func (m *mockMackerelClient) FetchServiceMetricValues(serviceName string, metricName string, from int64, to int64) ([]mackerel.MetricValue, error) {
+	require.Equal(m.t, "shimesaba", serviceName)
+	if metricName == "requests.broken" {
+		return nil, &mackerel.APIError{
+			StatusCode: 404,
+			Message:    "Metric not found",
+		}
+	}
+	values := make([]mackerel.MetricValue, 0)
+	for t := time.Unix(from, 0).Truncate(time.Minute); t.Unix() <= to; t = t.Add(time.Minute) {
+		value := 100.0
+		if metricName == "requests.2xx" && t.Minute()%5 == 0 {
+			value = 90.0
+		}
+		values = append(values, mackerel.MetricValue{
+			Name:  metricName,
+			Time:  t.Unix(),
+			Value: value,
+		})
+	}
+	return values, nil
+}
+
+func (m *mockMackerelClient) FindMonitors() ([]mackerel.Monitor, error) {
+	return []mackerel.Monitor{
+		&mackerel.MonitorServiceMetric{
+			ID:     "dummyMonitorID",
+			Name:   "Dummy Service Metric Monitor",
+			Type:   "service",
+			IsMute: true,
+		},
+	}, nil
+}
+
+func (m *mockMackerelClient) FindDowntimes() ([]*mackerel.Downtime, error) {
+	return []*mackerel.Downtime{
+		{
+			ID:       "weekly",
+			Name:     "weekly maintenance",
+			Start:    time.Date(2021, 9, 26, 0, 0, 0, 0, time.UTC).Unix(),
+			Duration: 30,
+			Recurrence: &mackerel.DowntimeRecurrence{
+				Type:     mackerel.DowntimeRecurrenceTypeWeekly,
+				Interval: 1,
+				Weekdays: []mackerel.DowntimeWeekday{
+					mackerel.DowntimeWeekday(time.Wednesday),
+					mackerel.DowntimeWeekday(time.Friday),
+				},
+			},
+			MonitorScopes: []string{"dummyMonitorID"},
+		},
+		{
+			ID:       "daily",
+			Name:     "daily batch",
+			Start:    time.Date(2021, 9, 29, 0, 40, 0, 0, time.UTC).Unix(),
+			Duration: 10,
+			Recurrence: &mackerel.DowntimeRecurrence{
+				Type:     mackerel.DowntimeRecurrenceTypeDaily,
+				Interval: 1,
+			},
+			ServiceScopes: []string{"shimesaba"},
+		},
+		{
+			ID:       "past",
+			Name:     "past one-off",
+			Start:    time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC).Unix(),
+			Duration: 60,
+		},
+	}, nil
+}
@@ -9,7 +9,13 @@ type Monitor struct {
 	id          string
 	name        string
 	monitorType string
-	evaluator   func(hostID string, timeFrame time.Duration, startAt, endAt time.Time) (Reliabilities, bool)
+	evaluator   func(hostID string, timeFrame time.Duration, minSeverity AlertSeverity, startAt, endAt time.Time) (Reliabilities, bool)
+	operator    string
+	warning     *float64
+	critical    *float64
+	muted       bool
+	forHosts    bool
+	hostScopes  []hostScope
 }
 
 func NewMonitor(id, name, monitorType string) *Monitor {
@@ -20,15 +26,58 @@ func NewMonitor(id, name, monitorType string) *Monitor {
 	}
 }
 
-func (m *Monitor) WithEvaluator(evaluator func(hostID string, timeFrame time.Duration, startAt, endAt time.Time) (Reliabilities, bool)) *Monitor {
+func (m *Monitor) WithEvaluator(evaluator func(hostID string, timeFrame time.Duration, minSeverity AlertSeverity, startAt, endAt time.Time) (Reliabilities, bool)) *Monitor {
 	return &Monitor{
 		id:          m.id,
 		name:        m.name,
 		monitorType: m.monitorType,
 		evaluator:   evaluator,
+		operator:    m.operator,
+		warning:     m.warning,
+		critical:    m.critical,
+		muted:       m.muted,
+		forHosts:    m.forHosts,
+		hostScopes:  m.hostScopes,
 	}
 }
 
+// WithThresholds returns Monitor with the thresholds, used to infer the severity of closed alerts.
+func (m *Monitor) WithThresholds(operator string, warning, critical *float64) *Monitor {
+	return &Monitor{
+		id:          m.id,
+		name:        m.name,
+		monitorType: m.monitorType,
+		evaluator:   m.evaluator,
+		operator:    operator,
+		warning:     warning,
+		critical:    critical,
+		muted:       m.muted,
+		forHosts:    m.forHosts,
+		hostScopes:  m.hostScopes,
+	}
+}
+
+// WithMuted returns Monitor with the mute state
+func (m *Monitor) WithMuted(muted bool) *Monitor {
+	cloned := *m
+	cloned.muted = muted
+	return &cloned
+}
+
+// WithHostScopes returns Monitor that monitors the hosts in the scopes, the services or the roles formatted as `service: role`.
+// If scopes is empty, the monitor monitors all hosts.
+func (m *Monitor) WithHostScopes(scopes []string) *Monitor {
+	cloned := *m
+	cloned.forHosts = true
+	cloned.hostScopes = parseHostScopes(scopes)
+	return &cloned
+}
+
+// IsMuted reports whether the monitor is muted on Mackerel
+func (m *Monitor) IsMuted() bool {
+	return m.muted
+}
+
 func (m *Monitor) ID() string {
 	return m.id
 }
@@ -45,9 +94,28 @@ func (m *Monitor) String() string {
 	return fmt.Sprintf("[%s]%s", m.monitorType, m.name)
 }
 
-func (m *Monitor) EvaluateReliabilities(hostID string, timeFrame time.Duration, startAt, endAt time.Time) (Reliabilities, bool) {
+func (m *Monitor) EvaluateReliabilities(hostID string, timeFrame time.Duration, minSeverity AlertSeverity, startAt, endAt time.Time) (Reliabilities, bool) {
 	if m.evaluator == nil {
 		return nil, false
 	}
-	return m.evaluator(hostID, timeFrame, startAt, endAt)
+	return m.evaluator(hostID, timeFrame, minSeverity, startAt, endAt)
+}
+
+// SeverityOf infers the severity of the alert from the value, using the thresholds of the monitor.
+func (m *Monitor) SeverityOf(value float64) AlertSeverity {
+	switch {
+	case m.warning == nil && m.critical == nil:
+		return AlertSeverityUnknown
+	case m.warning == nil:
+		return AlertSeverityCritical
+	case m.critical == nil:
+		return AlertSeverityWarning
+	}
+	if violated, err := isThresholdViolated(m.operator, value, *m.critical); err == nil && violated {
+		return AlertSeverityCritical
+	}
+	if violated, err := isThresholdViolated(m.operator, value, *m.warning); err == nil && violated {
+		return AlertSeverityWarning
+	}
+	return AlertSeverityUnknown
 }
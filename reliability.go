@@ -3,6 +3,7 @@ package shimesaba
 import (
 	"errors"
 	"log"
+	"math"
 	"sort"
 	"time"
 
@@ -11,11 +12,18 @@ import (
 
 // Reliability represents a group of values related to reliability per tumbling window.
 type Reliability struct {
-	cursorAt      time.Time
-	timeFrame     time.Duration
-	isNoViolation IsNoViolationCollection
-	upTime        time.Duration
-	failureTime   time.Duration
+	cursorAt     time.Time
+	timeFrame    time.Duration
+	failureRates timeline
+	excluded     timeline
+	goodEvents   float64
+	totalEvents  float64
+	upTime       time.Duration
+	failureTime  time.Duration
+
+	// restored is true if the reliability is restored from the totals, without the failure rate of each minute.
+	restored     bool
+	excludedTime time.Duration
 }
 
 type IsNoViolationCollection map[time.Time]bool
@@ -27,61 +35,182 @@ func (c IsNoViolationCollection) IsUp(t time.Time) bool {
 	return true
 }
 
+// FailureRates converts to FailureRateCollection, violated minutes are treated as failure rate 1.0
+func (c IsNoViolationCollection) FailureRates() FailureRateCollection {
+	rates := make(FailureRateCollection, len(c))
+	for t, isUp := range c {
+		if !isUp {
+			rates[t] = 1.0
+		}
+	}
+	return rates
+}
+
 func (c IsNoViolationCollection) NewReliabilities(timeFrame time.Duration, startAt, endAt time.Time) (Reliabilities, error) {
+	return c.FailureRates().NewReliabilities(timeFrame, startAt, endAt)
+}
+
+// IsExcludedCollection is the set of minutes excluded from the SLO, such as maintenance windows.
+// Excluded minutes are treated as neither up nor failure.
+type IsExcludedCollection map[time.Time]bool
+
+// IsExcluded returns whether the minute is excluded
+func (c IsExcludedCollection) IsExcluded(t time.Time) bool {
+	return c[t]
+}
+
+func (c IsExcludedCollection) timeline() timeline {
+	values := make(map[time.Time]float64, len(c))
+	for t, excluded := range c {
+		if excluded {
+			values[t] = 1.0
+		}
+	}
+	return newTimeline(values)
+}
+
+// FailureRateCollection is the failure rate of each minute.
+// 0.0 means that the minute kept the SLO, 1.0 means that the minute was fully violated.
+type FailureRateCollection map[time.Time]float64
+
+// FailureRate returns the failure rate of the minute
+func (c FailureRateCollection) FailureRate(t time.Time) float64 {
+	return c[t]
+}
+
+func (c FailureRateCollection) NewReliabilities(timeFrame time.Duration, startAt, endAt time.Time) (Reliabilities, error) {
+	return newTimeline(c).newReliabilities(timeFrame, startAt, endAt)
+}
+
+// newReliabilities creates Reliabilities of the tumbling windows between startAt and endAt, from the timeline of failure rates.
+func (tl timeline) newReliabilities(timeFrame time.Duration, startAt, endAt time.Time) (Reliabilities, error) {
 	startAt = startAt.Truncate(timeFrame)
 	iter := timeutils.NewIterator(startAt, endAt, timeFrame)
 	reliabilitySlice := make([]*Reliability, 0)
 	for iter.HasNext() {
 		cursorAt, _ := iter.Next()
-		reliabilitySlice = append(reliabilitySlice, NewReliability(cursorAt, timeFrame, c))
+		reliabilitySlice = append(reliabilitySlice, newReliabilityWithTimeline(cursorAt, timeFrame, tl))
 	}
 	return NewReliabilities(reliabilitySlice)
 }
 
 func NewReliability(cursorAt time.Time, timeFrame time.Duration, isNoViolation IsNoViolationCollection) *Reliability {
+	return NewReliabilityWithFailureRates(cursorAt, timeFrame, isNoViolation.FailureRates())
+}
+
+// NewReliabilityWithFailureRates creates Reliability from the failure rate of each minute.
+func NewReliabilityWithFailureRates(cursorAt time.Time, timeFrame time.Duration, failureRates FailureRateCollection) *Reliability {
+	startAt := cursorAt.Truncate(timeFrame)
+	rates := make(FailureRateCollection)
+	for t, rate := range failureRates {
+		if !t.Before(startAt) && t.Before(startAt.Add(timeFrame)) {
+			rates[t] = rate
+		}
+	}
+	return newReliabilityWithTimeline(cursorAt, timeFrame, newTimeline(rates))
+}
+
+// newReliabilityWithTimeline creates Reliability from the part of the timeline of failure rates in the tumbling window.
+func newReliabilityWithTimeline(cursorAt time.Time, timeFrame time.Duration, failureRates timeline) *Reliability {
 	cursorAt = cursorAt.Truncate(timeFrame).Add(timeFrame).UTC()
 	r := &Reliability{
-		cursorAt:      cursorAt,
-		timeFrame:     timeFrame,
-		isNoViolation: isNoViolation,
+		cursorAt:  cursorAt,
+		timeFrame: timeFrame,
 	}
-	r = r.Clone()
+	r.failureRates = failureRates.clip(r.TimeFrameStartAt(), r.cursorAt)
 	r.calc()
 	return r
 }
 
-func (r *Reliability) Clone() *Reliability {
-	cloned := &Reliability{
-		cursorAt:    r.cursorAt,
-		timeFrame:   r.timeFrame,
-		upTime:      r.upTime,
-		failureTime: r.failureTime,
+// NewReliabilityWithEvents creates Reliability from the number of good events and total events in the tumbling window.
+// The ratio of bad events is treated as the failure rate of every minute in the tumbling window.
+func NewReliabilityWithEvents(cursorAt time.Time, timeFrame time.Duration, goodEvents, totalEvents float64) *Reliability {
+	cursorAt = cursorAt.Truncate(timeFrame).Add(timeFrame).UTC()
+	r := &Reliability{
+		cursorAt:    cursorAt,
+		timeFrame:   timeFrame,
+		goodEvents:  goodEvents,
+		totalEvents: totalEvents,
 	}
-	iter := timeutils.NewIterator(r.TimeFrameStartAt(), r.TimeFrameEndAt(), time.Minute)
-	clonedIsNoViolation := make(IsNoViolationCollection, r.timeFrame/time.Minute)
-	for iter.HasNext() {
-		t, _ := iter.Next()
-		clonedIsNoViolation[t] = r.isNoViolation.IsUp(t)
+	r.calc()
+	return r
+}
+
+// NewReliabilityWithTotals creates Reliability from the totals of the tumbling window, such as restored from StateStore.
+// It has no failure rate of each minute, so the totals are kept as they are.
+func NewReliabilityWithTotals(cursorAt time.Time, timeFrame time.Duration, upTime, failureTime, excludedTime time.Duration, goodEvents, totalEvents float64) *Reliability {
+	cursorAt = cursorAt.Truncate(timeFrame).Add(timeFrame).UTC()
+	return &Reliability{
+		cursorAt:     cursorAt,
+		timeFrame:    timeFrame,
+		goodEvents:   goodEvents,
+		totalEvents:  totalEvents,
+		upTime:       upTime,
+		failureTime:  failureTime,
+		restored:     true,
+		excludedTime: excludedTime,
 	}
-	cloned.isNoViolation = clonedIsNoViolation
-	return cloned
 }
 
+func (r *Reliability) Clone() *Reliability {
+	return &Reliability{
+		cursorAt:     r.cursorAt,
+		timeFrame:    r.timeFrame,
+		failureRates: r.failureRates,
+		excluded:     r.excluded,
+		goodEvents:   r.goodEvents,
+		totalEvents:  r.totalEvents,
+		upTime:       r.upTime,
+		failureTime:  r.failureTime,
+		restored:     r.restored,
+		excludedTime: r.excludedTime,
+	}
+}
+
+// calc sums up the failure time of each minute that is not excluded.
+// The failure rate of a minute is the larger one of the failure rates and the ratio of bad events.
 func (r *Reliability) calc() {
-	iter := timeutils.NewIterator(r.TimeFrameStartAt(), r.TimeFrameEndAt(), time.Minute)
+	if r.restored {
+		return
+	}
+	eventFailureRate := r.eventFailureRate()
 	var upTime, failureTime time.Duration
-	for iter.HasNext() {
-		t, _ := iter.Next()
-		if r.isNoViolation.IsUp(t) {
-			upTime += time.Minute
-		} else {
-			failureTime += time.Minute
-		}
+	add := func(rate float64, minutes int64) {
+		failure := time.Duration(rate*float64(time.Minute)) * time.Duration(minutes)
+		failureTime += failure
+		upTime += time.Duration(minutes)*time.Minute - failure
+	}
+	restMinutes := int64((r.timeFrame+time.Minute-1)/time.Minute) - int64(r.excluded.duration()/time.Minute)
+	for _, s := range r.includedFailureRates() {
+		add(math.Max(s.value, eventFailureRate), s.minutes())
+		restMinutes -= s.minutes()
 	}
+	add(eventFailureRate, restMinutes)
 	r.upTime = upTime
 	r.failureTime = failureTime
 }
 
+// includedFailureRates returns the failure rates of the minutes that are not excluded.
+func (r *Reliability) includedFailureRates() timeline {
+	if len(r.excluded) == 0 {
+		return r.failureRates
+	}
+	return combineTimelines(r.TimeFrameStartAt(), r.cursorAt, func(values []float64) float64 {
+		if values[1] > 0.0 {
+			return 0.0
+		}
+		return values[0]
+	}, r.failureRates, r.excluded)
+}
+
+func (r *Reliability) eventFailureRate() float64 {
+	if r.totalEvents <= 0.0 {
+		return 0.0
+	}
+	rate := (r.totalEvents - r.goodEvents) / r.totalEvents
+	return math.Min(math.Max(rate, 0.0), 1.0)
+}
+
 //CursorAt is a representative value of the time shown by the tumbling window
 func (r *Reliability) CursorAt() time.Time {
 	return r.cursorAt
@@ -112,7 +241,46 @@ func (r *Reliability) FailureTime() time.Duration {
 	return r.failureTime
 }
 
-//Merge must be the same tumbling window
+//ExcludedTime is the time excluded from the SLO, such as maintenance windows.
+func (r *Reliability) ExcludedTime() time.Duration {
+	if r.restored {
+		return r.excludedTime
+	}
+	return r.excluded.duration()
+}
+
+//GoodEvents is the number of good events in the tumbling window
+func (r *Reliability) GoodEvents() float64 {
+	return r.goodEvents
+}
+
+//TotalEvents is the number of total events in the tumbling window
+func (r *Reliability) TotalEvents() float64 {
+	return r.totalEvents
+}
+
+//FailureRates is the failure rate of each minute in the tumbling window, including the ratio of bad events.
+func (r *Reliability) FailureRates() FailureRateCollection {
+	return FailureRateCollection(r.effectiveFailureRates().minuteValues())
+}
+
+// effectiveFailureRates returns the failure rates of the minutes that are not excluded, including the ratio of bad events.
+func (r *Reliability) effectiveFailureRates() timeline {
+	eventFailureRate := r.eventFailureRate()
+	if eventFailureRate == 0.0 {
+		return r.includedFailureRates()
+	}
+	events := timeline{{startAt: r.TimeFrameStartAt(), endAt: r.cursorAt, value: eventFailureRate}}
+	return combineTimelines(r.TimeFrameStartAt(), r.cursorAt, func(values []float64) float64 {
+		if values[1] > 0.0 {
+			return 0.0
+		}
+		return math.Max(values[0], values[2])
+	}, r.failureRates, r.excluded, events)
+}
+
+//Merge must be the same tumbling window.
+//Failure rates of each minute take the larger one, the number of events is summed up, and the excluded minutes are united.
 func (r *Reliability) Merge(other *Reliability) (*Reliability, error) {
 	if r.cursorAt != other.cursorAt {
 		return r, errors.New("mismatch cursorAt")
@@ -121,13 +289,66 @@ func (r *Reliability) Merge(other *Reliability) (*Reliability, error) {
 		return r, errors.New("mismatch timeFrame")
 	}
 	cloned := r.Clone()
-	for t, isUp2 := range other.isNoViolation {
-		cloned.isNoViolation[t] = r.isNoViolation.IsUp(t) && isUp2
-	}
+	cloned.failureRates = combineTimelines(r.TimeFrameStartAt(), r.cursorAt, maxValue, r.failureRates, other.failureRates)
+	cloned.excluded = combineTimelines(r.TimeFrameStartAt(), r.cursorAt, maxValue, r.excluded, other.excluded)
+	cloned.goodEvents += other.goodEvents
+	cloned.totalEvents += other.totalEvents
 	cloned.calc()
 	return cloned, nil
 }
 
+// WithImpact returns Reliability whose failure rate of each minute is multiplied by impact.
+// The number of events is not changed.
+func (r *Reliability) WithImpact(impact float64) *Reliability {
+	cloned := r.Clone()
+	cloned.failureRates = r.failureRates.mapValues(func(rate float64) float64 {
+		return rate * impact
+	})
+	cloned.calc()
+	return cloned
+}
+
+// Exclude returns Reliability that the minutes are excluded from.
+func (r *Reliability) Exclude(excluded IsExcludedCollection) *Reliability {
+	return r.exclude(excluded.timeline())
+}
+
+func (r *Reliability) exclude(excluded timeline) *Reliability {
+	cloned := r.Clone()
+	cloned.excluded = combineTimelines(r.TimeFrameStartAt(), r.cursorAt, maxValue, r.excluded, excluded)
+	cloned.calc()
+	return cloned
+}
+
+// clipFrom returns Reliability that counts only the minutes from startAt, for the tumbling window straddling the start of a calendar period.
+// The events can not be divided into minutes, so they are counted in proportion to the length from startAt.
+// The returned Reliability has the totals only, like the one restored from StateStore.
+func (r *Reliability) clipFrom(startAt time.Time) *Reliability {
+	if !startAt.After(r.TimeFrameStartAt()) || !startAt.Before(r.cursorAt) {
+		return r
+	}
+	ratio := float64(r.cursorAt.Sub(startAt)) / float64(r.timeFrame)
+	clipped := &Reliability{
+		cursorAt:    r.cursorAt,
+		timeFrame:   r.timeFrame,
+		goodEvents:  r.goodEvents * ratio,
+		totalEvents: r.totalEvents * ratio,
+		restored:    true,
+	}
+	if r.restored {
+		clipped.upTime = time.Duration(float64(r.upTime) * ratio)
+		clipped.failureTime = time.Duration(float64(r.failureTime) * ratio)
+		clipped.excludedTime = time.Duration(float64(r.excludedTime) * ratio)
+		return clipped
+	}
+	// the minutes before startAt are counted neither as up nor as failure.
+	tmp := r.exclude(timeline{{startAt: r.TimeFrameStartAt(), endAt: startAt, value: 1.0}})
+	clipped.upTime = tmp.upTime
+	clipped.failureTime = tmp.failureTime
+	clipped.excludedTime = r.excluded.clip(startAt, r.cursorAt).duration()
+	return clipped
+}
+
 // Reliabilities is sortable
 type Reliabilities []*Reliability
 
@@ -181,6 +402,63 @@ func (c Reliabilities) CalcTime(cursor, n int) (upTime, failureTime, deltaFailur
 	return
 }
 
+// CalcExcludedTime sums up the excluded time of n tumbling windows from the cursor-th.
+func (c Reliabilities) CalcExcludedTime(cursor, n int) (excludedTime time.Duration) {
+	for i := cursor; i < cursor+n && i < c.Len(); i++ {
+		excludedTime += c[i].ExcludedTime()
+	}
+	return
+}
+
+// WithImpact returns Reliabilities whose failure rate of each minute is multiplied by impact.
+func (c Reliabilities) WithImpact(impact float64) Reliabilities {
+	if impact == 1.0 {
+		return c
+	}
+	ret := make(Reliabilities, 0, len(c))
+	for _, r := range c {
+		ret = append(ret, r.WithImpact(impact))
+	}
+	return ret
+}
+
+// Exclude returns Reliabilities that the minutes are excluded from.
+func (c Reliabilities) Exclude(excluded IsExcludedCollection) Reliabilities {
+	return c.exclude(excluded.timeline())
+}
+
+// exclude returns Reliabilities that the spans of the timeline are excluded from.
+func (c Reliabilities) exclude(excluded timeline) Reliabilities {
+	if len(excluded) == 0 {
+		return c
+	}
+	ret := make(Reliabilities, 0, len(c))
+	for _, r := range c {
+		ret = append(ret, r.exclude(excluded))
+	}
+	return ret
+}
+
+func (c Reliabilities) CalcEvents(cursor, n int) (goodEvents, totalEvents, deltaBadEvents float64) {
+	deltaBadEvents = c[cursor].TotalEvents() - c[cursor].GoodEvents()
+	for i := cursor; i < cursor+n && i < c.Len(); i++ {
+		goodEvents += c[i].GoodEvents()
+		totalEvents += c[i].TotalEvents()
+	}
+	return
+}
+
+// failureRates joins the failure rates of the tumbling windows into one timeline.
+func (c Reliabilities) failureRates() timeline {
+	var tl timeline
+	for i := c.Len() - 1; i >= 0; i-- {
+		for _, s := range c[i].failureRates {
+			tl = tl.append(s)
+		}
+	}
+	return tl
+}
+
 //TimeFrame is the size of the tumbling window
 func (c Reliabilities) TimeFrame() time.Duration {
 	if c.Len() == 0 {
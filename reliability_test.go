@@ -1,10 +1,13 @@
 package shimesaba_test
 
 import (
+	"io"
+	"os"
 	"testing"
 	"time"
 
 	"github.com/mashiike/shimesaba"
+	"github.com/mashiike/shimesaba/internal/logger"
 	"github.com/stretchr/testify/require"
 )
 
@@ -71,6 +74,32 @@ func TestReliabilityMerge(t *testing.T) {
 	require.True(t, actual.UpTime()+actual.FailureTime() == actual.TimeFrame(), "upTime + failureTime = timeFrame")
 }
 
+func TestReliabilityExclude(t *testing.T) {
+	r := shimesaba.NewReliability(
+		time.Date(2022, 1, 6, 9, 39, 0, 0, time.UTC),
+		time.Hour,
+		map[time.Time]bool{
+			time.Date(2022, 1, 6, 9, 39, 0, 0, time.UTC): false,
+			time.Date(2022, 1, 6, 9, 40, 0, 0, time.UTC): false,
+		},
+	)
+	excluded := make(shimesaba.IsExcludedCollection)
+	for i := 40; i < 50; i++ {
+		excluded[time.Date(2022, 1, 6, 9, i, 0, 0, time.UTC)] = true
+	}
+	excluded[time.Date(2022, 1, 6, 10, 0, 0, 0, time.UTC)] = true
+	actual := r.Exclude(excluded)
+	require.EqualValues(t, 49*time.Minute, actual.UpTime(), "upTime 49m")
+	require.EqualValues(t, 1*time.Minute, actual.FailureTime(), "failureTime 1m")
+	require.EqualValues(t, 10*time.Minute, actual.ExcludedTime(), "excludedTime 10m, out of the tumbling window is ignored")
+	require.True(t, actual.UpTime()+actual.FailureTime()+actual.ExcludedTime() == actual.TimeFrame(), "upTime + failureTime + excludedTime = timeFrame")
+
+	merged, err := r.Merge(actual)
+	require.NoError(t, err)
+	require.EqualValues(t, 1*time.Minute, merged.FailureTime(), "excluded minutes are kept after merge")
+	require.EqualValues(t, 10*time.Minute, merged.ExcludedTime())
+}
+
 func TestReliabilities(t *testing.T) {
 	allTimeIsNoViolation := map[time.Time]bool{
 
@@ -231,3 +260,130 @@ func TestReliabilitiesMerge(t *testing.T) {
 	require.EqualValues(t, 3*time.Minute, deltaFailureTime, "2nd deltaFailureTime")
 
 }
+
+func TestReliabilityWithEvents(t *testing.T) {
+	r := shimesaba.NewReliabilityWithEvents(
+		time.Date(2022, 1, 6, 9, 39, 0, 0, time.UTC),
+		time.Hour,
+		990,
+		1000,
+	)
+	require.EqualValues(t, time.Date(2022, 1, 6, 10, 0, 0, 0, time.UTC), r.CursorAt(), "cursorAt 2022-1-6 10:00")
+	require.EqualValues(t, 36*time.Second, r.FailureTime(), "failureTime 36s")
+	require.True(t, r.UpTime()+r.FailureTime() == r.TimeFrame(), "upTime + failureTime = timeFrame")
+
+	other := shimesaba.NewReliability(
+		time.Date(2022, 1, 6, 9, 39, 0, 0, time.UTC),
+		time.Hour,
+		map[time.Time]bool{
+			time.Date(2022, 1, 6, 9, 38, 0, 0, time.UTC): false,
+			time.Date(2022, 1, 6, 9, 39, 0, 0, time.UTC): false,
+		},
+	)
+	actual, err := r.Merge(other)
+	require.NoError(t, err)
+	require.EqualValues(t, 990.0, actual.GoodEvents(), "good events")
+	require.EqualValues(t, 1000.0, actual.TotalEvents(), "total events")
+	require.EqualValues(t, 2*time.Minute+58*600*time.Millisecond, actual.FailureTime(), "failureTime 2m + 58 * 0.6s")
+	require.True(t, actual.UpTime()+actual.FailureTime() == actual.TimeFrame(), "upTime + failureTime = timeFrame")
+}
+
+func TestReliabilityFailureRates(t *testing.T) {
+	r := shimesaba.NewReliabilityWithFailureRates(
+		time.Date(2022, 1, 6, 10, 0, 0, 0, time.UTC),
+		5*time.Minute,
+		shimesaba.FailureRateCollection{
+			time.Date(2022, 1, 6, 9, 59, 0, 0, time.UTC): 1.0,
+			time.Date(2022, 1, 6, 10, 1, 0, 0, time.UTC):  1.0,
+			time.Date(2022, 1, 6, 10, 2, 0, 0, time.UTC):  0.5,
+			time.Date(2022, 1, 6, 10, 5, 0, 0, time.UTC):  1.0,
+		},
+	).WithImpact(0.5).Exclude(shimesaba.IsExcludedCollection{
+		time.Date(2022, 1, 6, 10, 2, 0, 0, time.UTC): true,
+		time.Date(2022, 1, 6, 10, 3, 0, 0, time.UTC): true,
+	})
+	merged, err := r.Merge(shimesaba.NewReliabilityWithEvents(time.Date(2022, 1, 6, 10, 0, 0, 0, time.UTC), 5*time.Minute, 80, 100))
+	require.NoError(t, err)
+	require.EqualValues(t, shimesaba.FailureRateCollection{
+		time.Date(2022, 1, 6, 10, 0, 0, 0, time.UTC): 0.2,
+		time.Date(2022, 1, 6, 10, 1, 0, 0, time.UTC): 0.5,
+		time.Date(2022, 1, 6, 10, 4, 0, 0, time.UTC): 0.2,
+	}, merged.FailureRates(), "the minutes out of the window and the excluded minutes are dropped, the ratio of bad events is the lower bound")
+	require.EqualValues(t, 2*time.Minute, merged.ExcludedTime())
+	require.EqualValues(t, 54*time.Second, merged.FailureTime(), "12s + 30s + 12s")
+	require.EqualValues(t, 3*time.Minute-54*time.Second, merged.UpTime())
+}
+
+// benchmarkFailureRates returns the failure rates of days, with a failure of 30 minutes in every 12 hours.
+func benchmarkFailureRates(startAt time.Time, days int, offset time.Duration) shimesaba.FailureRateCollection {
+	rates := make(shimesaba.FailureRateCollection)
+	for t := startAt.Add(offset); t.Before(startAt.Add(time.Duration(days) * 24 * time.Hour)); t = t.Add(12 * time.Hour) {
+		for i := 0; i < 30; i++ {
+			rates[t.Add(time.Duration(i)*time.Minute)] = 0.5
+		}
+	}
+	return rates
+}
+
+func BenchmarkNewReliabilities(b *testing.B) {
+	startAt := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	endAt := startAt.Add(28 * 24 * time.Hour)
+	rates := benchmarkFailureRates(startAt, 28, 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := rates.NewReliabilities(10*time.Minute, startAt, endAt); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReliabilitiesMerge(b *testing.B) {
+	startAt := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	endAt := startAt.Add(28 * 24 * time.Hour)
+	c1, err := benchmarkFailureRates(startAt, 28, 0).NewReliabilities(10*time.Minute, startAt, endAt)
+	require.NoError(b, err)
+	c2, err := benchmarkFailureRates(startAt, 28, 6*time.Hour).NewReliabilities(10*time.Minute, startAt, endAt)
+	require.NoError(b, err)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c1.Merge(c2); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReliabilitiesExclude(b *testing.B) {
+	startAt := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	endAt := startAt.Add(28 * 24 * time.Hour)
+	c, err := benchmarkFailureRates(startAt, 28, 0).NewReliabilities(10*time.Minute, startAt, endAt)
+	require.NoError(b, err)
+	excluded := make(shimesaba.IsExcludedCollection)
+	for t := range benchmarkFailureRates(startAt, 28, 3*time.Hour) {
+		excluded[t] = true
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Exclude(excluded)
+	}
+}
+
+func BenchmarkAlertBasedSLIEvaluateReliabilities(b *testing.B) {
+	logger.Setup(io.Discard, "info")
+	defer logger.Setup(os.Stderr, "info")
+	startAt := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	endAt := startAt.Add(28 * 24 * time.Hour)
+	monitor := shimesaba.NewMonitor("1", "SLO api", "host")
+	o := shimesaba.NewAlertBasedSLI(&shimesaba.AlertBasedSLIConfig{MonitorNamePrefix: "SLO"})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		alerts := make(shimesaba.Alerts, 0, 56)
+		for t := startAt; t.Before(endAt); t = t.Add(12 * time.Hour) {
+			alerts = append(alerts, shimesaba.NewAlert(monitor, t, ptrTime(t.Add(30*time.Minute))))
+		}
+		b.StartTimer()
+		if _, err := o.EvaluateReliabilities(10*time.Minute, alerts, startAt, endAt); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
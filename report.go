@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"time"
+
+	"github.com/mashiike/shimesaba/internal/timeutils"
 )
 
 // Report has SLI/SLO/ErrorBudget numbers in one rolling window
@@ -18,9 +20,30 @@ type Report struct {
 	ErrorBudgetSize        time.Duration
 	ErrorBudget            time.Duration
 	ErrorBudgetConsumption time.Duration
+	// ExcludedTime is the time in the maintenance windows, which is excluded from the error budget size.
+	ExcludedTime time.Duration
+
+	// Unit is the unit of error budget, ErrorBudget* fields above are used for minutes and ErrorBudget*Events fields below are used for events.
+	Unit                         ErrorBudgetUnit
+	TotalEvents                  float64
+	BadEvents                    float64
+	ErrorBudgetSizeEvents        float64
+	ErrorBudgetEvents            float64
+	ErrorBudgetConsumptionEvents float64
+
+	// BurnRates is the ratio of the failure rate over each lookback to the error budget size.
+	BurnRates map[time.Duration]float64
+	// TimeUntilExhaustion is the forecast of the time until the error budget runs out at the current burn rate.
+	TimeUntilExhaustion time.Duration
+
+	// ErrorBudgetRecovery* are the error budget that comes back in the next 24h and 7d, as the failure rolls off the window.
+	ErrorBudgetRecovery24h       time.Duration
+	ErrorBudgetRecovery7d        time.Duration
+	ErrorBudgetRecovery24hEvents float64
+	ErrorBudgetRecovery7dEvents  float64
 }
 
-func NewReport(definitionID string, destination *Destination, cursorAt time.Time, timeFrame time.Duration, errorBudgetSize float64) *Report {
+func NewReport(definitionID string, destination *Destination, cursorAt time.Time, timeFrame time.Duration, errorBudgetSize float64, unit ErrorBudgetUnit) *Report {
 	report := &Report{
 		DefinitionID:     definitionID,
 		Destination:      destination,
@@ -28,11 +51,12 @@ func NewReport(definitionID string, destination *Destination, cursorAt time.Time
 		TimeFrameStartAt: cursorAt.Add(-timeFrame),
 		TimeFrameEndAt:   cursorAt.Add(-time.Nanosecond),
 		ErrorBudgetSize:  time.Duration(errorBudgetSize * float64(timeFrame)).Truncate(time.Minute),
+		Unit:             unit,
 	}
 	return report
 }
 
-func NewReports(definitionID string, destination *Destination, errorBudgetSize float64, timeFrame time.Duration, reliability Reliabilities) []*Report {
+func NewReports(definitionID string, destination *Destination, errorBudgetSize float64, timeFrame time.Duration, unit ErrorBudgetUnit, reliability Reliabilities) []*Report {
 	if reliability.Len() == 0 {
 		return make([]*Report, 0)
 	}
@@ -47,34 +71,233 @@ func NewReports(definitionID string, destination *Destination, errorBudgetSize f
 			reliability.CursorAt(i),
 			timeFrame,
 			errorBudgetSize,
+			unit,
 		)
+		report.SetExcludedTime(errorBudgetSize, timeFrame, reliability.CalcExcludedTime(i, n))
 		report.SetTime(reliability.CalcTime(i, n))
+		goodEvents, totalEvents, deltaBadEvents := reliability.CalcEvents(i, n)
+		report.SetEvents(errorBudgetSize, goodEvents, totalEvents, deltaBadEvents)
+		report.SetBurnRates(errorBudgetSize, reliability, i)
+		report.SetTimeUntilExhaustion(errorBudgetSize, reliability, i, timeFrame)
+		report.SetErrorBudgetRecovery(errorBudgetSize, reliability, i, n)
 		reports = append(reports, report)
 	}
 
 	return reports
 }
 
+// NewCalendarReports creates reports whose error budget is reset at the boundary of the calendar period.
+// The error budget size is based on the length of the whole calendar period,
+// and the remaining and consumption are calculated from the start of the calendar period to the data point.
+func NewCalendarReports(definitionID string, destination *Destination, errorBudgetSize float64, window WindowType, loc *time.Location, unit ErrorBudgetUnit, reliability Reliabilities) []*Report {
+	if reliability.Len() == 0 {
+		return make([]*Report, 0)
+	}
+	oldestStartAt := reliability[reliability.Len()-1].TimeFrameStartAt()
+	reports := make([]*Report, 0, reliability.Len())
+	for i := 0; i < reliability.Len(); i++ {
+		lastAt := reliability[i].TimeFrameEndAt()
+		periodStartAt := window.PeriodStartAt(lastAt, loc).UTC()
+		if periodStartAt.Before(oldestStartAt) {
+			break
+		}
+		periodEndAt := window.PeriodEndAt(lastAt, loc).UTC()
+		j := i
+		for j < reliability.Len() && !reliability[j].TimeFrameStartAt().Before(periodStartAt) {
+			j++
+		}
+		period := append(make(Reliabilities, 0, j-i+1), reliability[i:j]...)
+		if j < reliability.Len() && reliability[j].CursorAt().After(periodStartAt) {
+			// the tumbling window straddles the start of the calendar period, if it is not aligned with calculate_interval.
+			period = append(period, reliability[j].clipFrom(periodStartAt))
+		}
+		n := period.Len()
+		if n == 0 {
+			continue
+		}
+		report := NewReport(
+			definitionID,
+			destination,
+			reliability.CursorAt(i),
+			periodEndAt.Sub(periodStartAt),
+			errorBudgetSize,
+			unit,
+		)
+		report.TimeFrameStartAt = periodStartAt
+		report.SetExcludedTime(errorBudgetSize, periodEndAt.Sub(periodStartAt), period.CalcExcludedTime(0, n))
+		report.SetTime(period.CalcTime(0, n))
+		goodEvents, totalEvents, deltaBadEvents := period.CalcEvents(0, n)
+		report.SetEvents(errorBudgetSize, goodEvents, totalEvents, deltaBadEvents)
+		report.SetBurnRates(errorBudgetSize, reliability, i)
+		report.SetTimeUntilExhaustion(errorBudgetSize, reliability, i, periodEndAt.Sub(report.DataPoint))
+		report.SetCalendarErrorBudgetRecovery(periodEndAt)
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// SetExcludedTime scales the error budget size to the time frame without the excluded time. It must be called before SetTime.
+func (r *Report) SetExcludedTime(errorBudgetSize float64, timeFrame time.Duration, excludedTime time.Duration) {
+	r.ExcludedTime = excludedTime
+	if excludedTime > 0 {
+		r.ErrorBudgetSize = time.Duration(errorBudgetSize * float64(timeFrame-excludedTime)).Truncate(time.Minute)
+	}
+}
+
 func (r *Report) SetTime(upTime time.Duration, failureTime time.Duration, deltaFailureTime time.Duration) {
 	r.UpTime = upTime
 	r.FailureTime = failureTime
-	r.ErrorBudget = (r.ErrorBudgetSize - failureTime).Truncate(time.Minute)
-	r.ErrorBudgetConsumption = deltaFailureTime.Truncate(time.Minute)
+	r.ErrorBudget = r.ErrorBudgetSize - failureTime
+	r.ErrorBudgetConsumption = deltaFailureTime
+}
+
+// SetEvents sets the number of events in the rolling window. errorBudgetSize is the ratio of allowed bad events.
+func (r *Report) SetEvents(errorBudgetSize float64, goodEvents float64, totalEvents float64, deltaBadEvents float64) {
+	r.TotalEvents = totalEvents
+	r.BadEvents = totalEvents - goodEvents
+	r.ErrorBudgetSizeEvents = errorBudgetSize * totalEvents
+	r.ErrorBudgetEvents = r.ErrorBudgetSizeEvents - r.BadEvents
+	r.ErrorBudgetConsumptionEvents = deltaBadEvents
+}
+
+// SetBurnRates sets the burn rate for each lookback of the destination, from the cursor-th reliability to the past.
+// If the reliabilities do not cover the whole lookback, the burn rate is calculated with the covered part.
+func (r *Report) SetBurnRates(errorBudgetSize float64, reliability Reliabilities, cursor int) {
+	if r.Destination == nil || len(r.Destination.BurnRateLookbacks) == 0 || reliability.Len() == 0 {
+		return
+	}
+	r.BurnRates = make(map[time.Duration]float64, len(r.Destination.BurnRateLookbacks))
+	for _, lookback := range r.Destination.BurnRateLookbacks {
+		n := int(lookback / reliability.TimeFrame())
+		var failureRate float64
+		if r.Unit == ErrorBudgetUnitEvents {
+			goodEvents, totalEvents, _ := reliability.CalcEvents(cursor, n)
+			if totalEvents > 0 {
+				failureRate = (totalEvents - goodEvents) / totalEvents
+			}
+		} else {
+			upTime, failureTime, _ := reliability.CalcTime(cursor, n)
+			if upTime+failureTime > 0 {
+				failureRate = float64(failureTime) / float64(upTime+failureTime)
+			}
+		}
+		r.BurnRates[lookback] = failureRate / errorBudgetSize
+	}
+}
+
+const defaultExhaustionLookback = time.Hour
+
+// SetTimeUntilExhaustion sets the forecast of the time until the error budget runs out,
+// assuming that the failure rate over the lookback of the destination continues. The forecast is capped at limit.
+// SetTime and SetEvents must be called before.
+func (r *Report) SetTimeUntilExhaustion(errorBudgetSize float64, reliability Reliabilities, cursor int, limit time.Duration) {
+	if reliability.Len() == 0 {
+		return
+	}
+	lookback := defaultExhaustionLookback
+	if r.Destination != nil && r.Destination.ExhaustionLookback > 0 {
+		lookback = r.Destination.ExhaustionLookback
+	}
+	n := int(lookback / reliability.TimeFrame())
+	if n < 1 {
+		n = 1
+	}
+	if rest := reliability.Len() - cursor; n > rest {
+		n = rest
+	}
+	elapsed := time.Duration(n) * reliability.TimeFrame()
+
+	var remaining, consumptionPerMinute float64
+	if r.Unit == ErrorBudgetUnitEvents {
+		goodEvents, totalEvents, _ := reliability.CalcEvents(cursor, n)
+		remaining = r.ErrorBudgetEvents
+		// the error budget in events also increases with the total events.
+		consumptionPerMinute = ((totalEvents - goodEvents) - errorBudgetSize*totalEvents) / elapsed.Minutes()
+	} else {
+		_, failureTime, _ := reliability.CalcTime(cursor, n)
+		remaining = r.ErrorBudget.Minutes()
+		consumptionPerMinute = failureTime.Minutes() / elapsed.Minutes()
+	}
+	switch {
+	case remaining <= 0.0:
+		r.TimeUntilExhaustion = 0
+	case consumptionPerMinute <= 0.0:
+		r.TimeUntilExhaustion = limit
+	default:
+		forecast := time.Duration(remaining / consumptionPerMinute * float64(time.Minute))
+		if forecast > limit {
+			forecast = limit
+		}
+		r.TimeUntilExhaustion = forecast.Truncate(time.Minute)
+	}
+}
+
+// SetErrorBudgetRecovery sets the error budget that comes back in the next 24h and 7d,
+// as the oldest tumbling windows of the rolling window (reliability[cursor:cursor+n]) roll off, assuming no new failure.
+func (r *Report) SetErrorBudgetRecovery(errorBudgetSize float64, reliability Reliabilities, cursor int, n int) {
+	if reliability.Len() == 0 {
+		return
+	}
+	if rest := reliability.Len() - cursor; n > rest {
+		n = rest
+	}
+	recovery := func(d time.Duration) (time.Duration, float64) {
+		k := int(d / reliability.TimeFrame())
+		if k > n {
+			k = n
+		}
+		if k <= 0 {
+			return 0, 0.0
+		}
+		_, failureTime, _ := reliability.CalcTime(cursor+n-k, k)
+		goodEvents, totalEvents, _ := reliability.CalcEvents(cursor+n-k, k)
+		// the error budget in events also decreases with the total events rolling off.
+		return failureTime, (totalEvents - goodEvents) - errorBudgetSize*totalEvents
+	}
+	r.ErrorBudgetRecovery24h, r.ErrorBudgetRecovery24hEvents = recovery(24 * time.Hour)
+	r.ErrorBudgetRecovery7d, r.ErrorBudgetRecovery7dEvents = recovery(7 * 24 * time.Hour)
+}
+
+// SetCalendarErrorBudgetRecovery sets the error budget that comes back in the next 24h and 7d,
+// the whole consumed error budget comes back if the calendar period ends. SetTime and SetEvents must be called before.
+func (r *Report) SetCalendarErrorBudgetRecovery(periodEndAt time.Time) {
+	untilReset := periodEndAt.Sub(r.DataPoint)
+	if untilReset <= 24*time.Hour {
+		r.ErrorBudgetRecovery24h, r.ErrorBudgetRecovery24hEvents = r.FailureTime, -r.ErrorBudgetEvents
+	}
+	if untilReset <= 7*24*time.Hour {
+		r.ErrorBudgetRecovery7d, r.ErrorBudgetRecovery7dEvents = r.FailureTime, -r.ErrorBudgetEvents
+	}
 }
 
 // String implements fmt.Stringer
 func (r *Report) String() string {
+	unit := "min"
+	size, remaining, consumption := r.ErrorBudgetSize.Minutes(), r.ErrorBudget.Minutes(), r.ErrorBudgetConsumption.Minutes()
+	if r.Unit == ErrorBudgetUnitEvents {
+		unit = "events"
+		size, remaining, consumption = r.ErrorBudgetSizeEvents, r.ErrorBudgetEvents, r.ErrorBudgetConsumptionEvents
+	}
 	return fmt.Sprintf(
-		"error budget report[id=`%s`,data_point=`%s`]: size=%0.4f[min], remaining=%0.4f[min](%0.1f%%), consumption=%0.4f[min](%0.1f%%)",
+		"error budget report[id=`%s`,data_point=`%s`]: size=%0.4f[%s], remaining=%0.4f[%s](%0.1f%%), consumption=%0.4f[%s](%0.1f%%)",
 		r.DefinitionID, r.DataPoint.Format(time.RFC3339),
-		r.ErrorBudgetSize.Minutes(),
-		r.ErrorBudget.Minutes(), r.ErrorBudgetUsageRate()*100.0,
-		r.ErrorBudgetConsumption.Minutes(), r.ErrorBudgetConsumptionRate()*100.0,
+		size, unit,
+		remaining, unit, r.ErrorBudgetUsageRate()*100.0,
+		consumption, unit, r.ErrorBudgetConsumptionRate()*100.0,
 	)
 }
 
 // ErrorBudgetUsageRate returns (1.0 - ErrorBudget/ErrorBudgetSize)
 func (r *Report) ErrorBudgetUsageRate() float64 {
+	if r.Unit == ErrorBudgetUnitEvents {
+		if r.ErrorBudgetSizeEvents == 0 {
+			return 0.0
+		}
+		return 1.0 - r.ErrorBudgetEvents/r.ErrorBudgetSizeEvents
+	}
+	if r.ErrorBudgetSize == 0 {
+		return 0.0
+	}
 	if r.ErrorBudget >= 0 {
 		return 1.0 - float64(r.ErrorBudget)/float64(r.ErrorBudgetSize)
 	}
@@ -83,28 +306,46 @@ func (r *Report) ErrorBudgetUsageRate() float64 {
 
 // ErrorBudgetConsumptionRate returns ErrorBudgetConsumption/ErrorBudgetSize
 func (r *Report) ErrorBudgetConsumptionRate() float64 {
+	if r.Unit == ErrorBudgetUnitEvents {
+		if r.ErrorBudgetSizeEvents == 0 {
+			return 0.0
+		}
+		return r.ErrorBudgetConsumptionEvents / r.ErrorBudgetSizeEvents
+	}
+	if r.ErrorBudgetSize == 0 {
+		return 0.0
+	}
 	return float64(r.ErrorBudgetConsumption) / float64(r.ErrorBudgetSize)
 }
 
 // MarshalJSON implements json.Marshaler
 func (r *Report) MarshalJSON() ([]byte, error) {
 	d := struct {
-		DefinitionID               string    `json:"definition_id" yaml:"definition_id"`
-		DataPoint                  time.Time `json:"data_point" yaml:"data_point"`
-		TimeFrameStartAt           time.Time `json:"time_frame_start_at" yaml:"time_frame_start_at"`
-		TimeFrameEndAt             time.Time `json:"time_frame_end_at" yaml:"time_frame_end_at"`
-		UpTime                     float64   `json:"up_time" yaml:"up_time"`
-		FailureTime                float64   `json:"failure_time" yaml:"failure_time"`
-		ErrorBudgetSize            float64   `json:"error_budget_size" yaml:"error_budget_size"`
-		ErrorBudget                float64   `json:"error_budget" yaml:"error_budget"`
-		ErrorBudgetUsageRate       float64   `json:"error_budget_usage_rate" yaml:"error_budget_usage_rate"`
-		ErrorBudgetConsumption     float64   `json:"error_budget_consumption" yaml:"error_budget_consumption"`
-		ErrorBudgetConsumptionRate float64   `json:"error_budget_consumption_rate" yaml:"error_budget_consumption_rate"`
+		DefinitionID               string             `json:"definition_id" yaml:"definition_id"`
+		DataPoint                  time.Time          `json:"data_point" yaml:"data_point"`
+		TimeFrameStartAt           time.Time          `json:"time_frame_start_at" yaml:"time_frame_start_at"`
+		TimeFrameEndAt             time.Time          `json:"time_frame_end_at" yaml:"time_frame_end_at"`
+		Unit                       string             `json:"unit" yaml:"unit"`
+		UpTime                     float64            `json:"up_time" yaml:"up_time"`
+		FailureTime                float64            `json:"failure_time" yaml:"failure_time"`
+		ErrorBudgetSize            float64            `json:"error_budget_size" yaml:"error_budget_size"`
+		ErrorBudget                float64            `json:"error_budget" yaml:"error_budget"`
+		ErrorBudgetUsageRate       float64            `json:"error_budget_usage_rate" yaml:"error_budget_usage_rate"`
+		ErrorBudgetConsumption     float64            `json:"error_budget_consumption" yaml:"error_budget_consumption"`
+		ErrorBudgetConsumptionRate float64            `json:"error_budget_consumption_rate" yaml:"error_budget_consumption_rate"`
+		ExcludedTime               float64            `json:"excluded_time,omitempty" yaml:"excluded_time,omitempty"`
+		TotalEvents                *float64           `json:"total_events,omitempty" yaml:"total_events,omitempty"`
+		BadEvents                  *float64           `json:"bad_events,omitempty" yaml:"bad_events,omitempty"`
+		BurnRates                  map[string]float64 `json:"burn_rates,omitempty" yaml:"burn_rates,omitempty"`
+		TimeUntilExhaustion        float64            `json:"time_until_exhaustion" yaml:"time_until_exhaustion"`
+		ErrorBudgetRecovery24h     float64            `json:"error_budget_recovery_24h" yaml:"error_budget_recovery_24h"`
+		ErrorBudgetRecovery7d      float64            `json:"error_budget_recovery_7d" yaml:"error_budget_recovery_7d"`
 	}{
 		DefinitionID:               r.DefinitionID,
 		DataPoint:                  r.DataPoint,
 		TimeFrameStartAt:           r.TimeFrameStartAt,
 		TimeFrameEndAt:             r.TimeFrameEndAt,
+		Unit:                       r.Unit.String(),
 		UpTime:                     r.UpTime.Minutes(),
 		FailureTime:                r.FailureTime.Minutes(),
 		ErrorBudgetSize:            r.ErrorBudgetSize.Minutes(),
@@ -112,6 +353,25 @@ func (r *Report) MarshalJSON() ([]byte, error) {
 		ErrorBudgetUsageRate:       r.ErrorBudgetUsageRate(),
 		ErrorBudgetConsumption:     r.ErrorBudgetConsumption.Minutes(),
 		ErrorBudgetConsumptionRate: r.ErrorBudgetConsumptionRate(),
+		ExcludedTime:               r.ExcludedTime.Minutes(),
+		TimeUntilExhaustion:        r.TimeUntilExhaustion.Minutes(),
+		ErrorBudgetRecovery24h:     r.ErrorBudgetRecovery24h.Minutes(),
+		ErrorBudgetRecovery7d:      r.ErrorBudgetRecovery7d.Minutes(),
+	}
+	if r.Unit == ErrorBudgetUnitEvents {
+		d.TotalEvents = &r.TotalEvents
+		d.BadEvents = &r.BadEvents
+		d.ErrorBudgetSize = r.ErrorBudgetSizeEvents
+		d.ErrorBudget = r.ErrorBudgetEvents
+		d.ErrorBudgetConsumption = r.ErrorBudgetConsumptionEvents
+		d.ErrorBudgetRecovery24h = r.ErrorBudgetRecovery24hEvents
+		d.ErrorBudgetRecovery7d = r.ErrorBudgetRecovery7dEvents
+	}
+	if len(r.BurnRates) != 0 {
+		d.BurnRates = make(map[string]float64, len(r.BurnRates))
+		for lookback, burnRate := range r.BurnRates {
+			d.BurnRates[timeutils.DurationString(lookback)] = burnRate
+		}
 	}
 	return json.Marshal(d)
 }
@@ -119,12 +379,18 @@ func (r *Report) MarshalJSON() ([]byte, error) {
 func (r *Report) GetDestinationMetricValue(metricType DestinationMetricType) float64 {
 	switch metricType {
 	case ErrorBudget:
+		if r.Unit == ErrorBudgetUnitEvents {
+			return r.ErrorBudgetEvents
+		}
 		return r.ErrorBudget.Minutes()
 	case ErrorBudgetRemainingPercentage:
 		return (1.0 - r.ErrorBudgetUsageRate()) * 100.0
 	case ErrorBudgetPercentage:
 		return r.ErrorBudgetUsageRate() * 100.0
 	case ErrorBudgetConsumption:
+		if r.Unit == ErrorBudgetUnitEvents {
+			return r.ErrorBudgetConsumptionEvents
+		}
 		return r.ErrorBudgetConsumption.Minutes()
 	case ErrorBudgetConsumptionPercentage:
 		return r.ErrorBudgetConsumptionRate() * 100.0
@@ -132,6 +398,24 @@ func (r *Report) GetDestinationMetricValue(metricType DestinationMetricType) flo
 		return r.UpTime.Minutes()
 	case FailureTime:
 		return r.FailureTime.Minutes()
+	case TimeUntilExhaustion:
+		return r.TimeUntilExhaustion.Minutes()
+	case ErrorBudgetRecovery24h:
+		if r.Unit == ErrorBudgetUnitEvents {
+			return r.ErrorBudgetRecovery24hEvents
+		}
+		return r.ErrorBudgetRecovery24h.Minutes()
+	case ErrorBudgetRecovery7d:
+		if r.Unit == ErrorBudgetUnitEvents {
+			return r.ErrorBudgetRecovery7dEvents
+		}
+		return r.ErrorBudgetRecovery7d.Minutes()
+	case BurnRate:
+		// burn rate has a value for each lookback, the first one is returned as the representative value.
+		if r.Destination == nil || len(r.Destination.BurnRateLookbacks) == 0 {
+			return 0.0
+		}
+		return r.BurnRates[r.Destination.BurnRateLookbacks[0]]
 	}
 	panic(fmt.Sprintf("unknown metric type %v", metricType))
 }
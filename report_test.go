@@ -105,7 +105,7 @@ func TestNewReports(t *testing.T) {
 			),
 		},
 	)
-	actual := shimesaba.NewReports("test", dest, 0.05, 2*time.Hour, c)
+	actual := shimesaba.NewReports("test", dest, 0.05, 2*time.Hour, shimesaba.ErrorBudgetUnitMinutes, c)
 	expected := []*shimesaba.Report{
 		{
 			DefinitionID:           "test",
@@ -118,6 +118,9 @@ func TestNewReports(t *testing.T) {
 			FailureTime:            (3 + 2) * time.Minute,
 			ErrorBudget:            1 * time.Minute,
 			ErrorBudgetConsumption: 3 * time.Minute,
+			ErrorBudgetRecovery24h: (3 + 2) * time.Minute,
+			ErrorBudgetRecovery7d:  (3 + 2) * time.Minute,
+			TimeUntilExhaustion:    20 * time.Minute,
 		},
 		{
 			DefinitionID:           "test",
@@ -130,6 +133,9 @@ func TestNewReports(t *testing.T) {
 			FailureTime:            (2 + 1) * time.Minute,
 			ErrorBudget:            3 * time.Minute,
 			ErrorBudgetConsumption: 2 * time.Minute,
+			ErrorBudgetRecovery24h: (2 + 1) * time.Minute,
+			ErrorBudgetRecovery7d:  (2 + 1) * time.Minute,
+			TimeUntilExhaustion:    90 * time.Minute,
 		},
 	}
 	for i, a := range actual {
@@ -142,3 +148,215 @@ func TestNewReports(t *testing.T) {
 	}
 	require.EqualValues(t, expected, actual)
 }
+
+func TestNewReportsWithEvents(t *testing.T) {
+	dest := &shimesaba.Destination{
+		ServiceName:  "test",
+		MetricPrefix: "test",
+	}
+	c, _ := shimesaba.NewReliabilities(
+		[]*shimesaba.Reliability{
+			shimesaba.NewReliabilityWithEvents(time.Date(2022, 1, 6, 8, 0, 0, 0, time.UTC), time.Hour, 9990, 10000),
+			shimesaba.NewReliabilityWithEvents(time.Date(2022, 1, 6, 9, 0, 0, 0, time.UTC), time.Hour, 9950, 10000),
+			shimesaba.NewReliabilityWithEvents(time.Date(2022, 1, 6, 10, 0, 0, 0, time.UTC), time.Hour, 20000, 20000),
+		},
+	)
+	actual := shimesaba.NewReports("test", dest, 0.005, 2*time.Hour, shimesaba.ErrorBudgetUnitEvents, c)
+	require.Len(t, actual, 2)
+
+	epsilon := 0.00001
+	require.EqualValues(t, time.Date(2022, 1, 6, 11, 0, 0, 0, time.UTC), actual[0].DataPoint)
+	require.InEpsilon(t, 30000.0, actual[0].TotalEvents, epsilon, "total events")
+	require.InEpsilon(t, 50.0, actual[0].BadEvents, epsilon, "bad events")
+	require.InEpsilon(t, 150.0, actual[0].ErrorBudgetSizeEvents, epsilon, "error budget size")
+	require.InEpsilon(t, 100.0, actual[0].ErrorBudgetEvents, epsilon, "error budget")
+	require.EqualValues(t, 0.0, actual[0].ErrorBudgetConsumptionEvents, "error budget consumption")
+	require.InEpsilon(t, 1.0/3.0, actual[0].ErrorBudgetUsageRate(), epsilon, "usage rate")
+	require.InEpsilon(t, 100.0, actual[0].GetDestinationMetricValue(shimesaba.ErrorBudget), epsilon, "error_budget metric")
+	require.EqualValues(t, 2*time.Hour, actual[0].TimeUntilExhaustion, "no bad events, capped at the window size")
+
+	require.EqualValues(t, time.Date(2022, 1, 6, 10, 0, 0, 0, time.UTC), actual[1].DataPoint)
+	require.InEpsilon(t, 60.0, actual[1].BadEvents, epsilon, "bad events")
+	require.InEpsilon(t, 100.0, actual[1].ErrorBudgetSizeEvents, epsilon, "error budget size")
+	require.InEpsilon(t, 40.0, actual[1].ErrorBudgetEvents, epsilon, "error budget")
+	require.InEpsilon(t, 50.0, actual[1].ErrorBudgetConsumptionEvents, epsilon, "error budget consumption")
+	require.InEpsilon(t, 0.5, actual[1].ErrorBudgetConsumptionRate(), epsilon, "consumption rate")
+	require.InEpsilon(t, 50.0, actual[1].GetDestinationMetricValue(shimesaba.ErrorBudgetConsumption), epsilon, "error_budget_consumption metric")
+	require.EqualValues(t, 2*time.Hour, actual[1].TimeUntilExhaustion, "bad events do not exceed the increase of the error budget")
+}
+
+func TestNewReportsWithExcludedTime(t *testing.T) {
+	dest := &shimesaba.Destination{
+		ServiceName:  "test",
+		MetricPrefix: "test",
+	}
+	excluded := make(shimesaba.IsExcludedCollection)
+	for i := 0; i < 30; i++ {
+		excluded[time.Date(2022, 1, 6, 9, i, 0, 0, time.UTC)] = true
+	}
+	c, _ := shimesaba.NewReliabilities(
+		[]*shimesaba.Reliability{
+			shimesaba.NewReliability(time.Date(2022, 1, 6, 8, 0, 0, 0, time.UTC), time.Hour, map[time.Time]bool{
+				time.Date(2022, 1, 6, 8, 10, 0, 0, time.UTC): false,
+			}),
+			shimesaba.NewReliability(time.Date(2022, 1, 6, 9, 0, 0, 0, time.UTC), time.Hour, map[time.Time]bool{
+				time.Date(2022, 1, 6, 9, 10, 0, 0, time.UTC): false,
+				time.Date(2022, 1, 6, 9, 40, 0, 0, time.UTC): false,
+			}),
+		},
+	)
+	c = c.Exclude(excluded)
+	actual := shimesaba.NewReports("test", dest, 0.1, 2*time.Hour, shimesaba.ErrorBudgetUnitMinutes, c)
+	require.Len(t, actual, 1)
+	require.EqualValues(t, 30*time.Minute, actual[0].ExcludedTime, "excluded time")
+	require.EqualValues(t, 88*time.Minute, actual[0].UpTime, "up time")
+	require.EqualValues(t, 2*time.Minute, actual[0].FailureTime, "failure in the maintenance window is not counted")
+	require.EqualValues(t, 9*time.Minute, actual[0].ErrorBudgetSize, "error budget size is scaled to 90m")
+	require.EqualValues(t, 7*time.Minute, actual[0].ErrorBudget, "error budget")
+}
+
+func TestNewCalendarReports(t *testing.T) {
+	dest := &shimesaba.Destination{
+		ServiceName:  "test",
+		MetricPrefix: "test",
+	}
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	isNoViolation := make(shimesaba.IsNoViolationCollection)
+	for _, r := range []struct {
+		startAt time.Time
+		n       int
+	}{
+		{startAt: time.Date(2022, 1, 31, 14, 0, 0, 0, time.UTC), n: 10},
+		{startAt: time.Date(2022, 1, 31, 15, 0, 0, 0, time.UTC), n: 5},
+		{startAt: time.Date(2022, 1, 31, 16, 10, 0, 0, time.UTC), n: 3},
+	} {
+		for i := 0; i < r.n; i++ {
+			isNoViolation[r.startAt.Add(time.Duration(i)*time.Minute)] = false
+		}
+	}
+	c, err := isNoViolation.NewReliabilities(
+		time.Hour,
+		time.Date(2022, 1, 31, 13, 0, 0, 0, time.UTC),
+		time.Date(2022, 1, 31, 16, 59, 59, 0, time.UTC),
+	)
+	require.NoError(t, err)
+	actual := shimesaba.NewCalendarReports("test", dest, 0.001, shimesaba.WindowTypeCalendarMonth, loc, shimesaba.ErrorBudgetUnitMinutes, c)
+	require.Len(t, actual, 2, "reports of January in JST are not created, because the reliabilities do not cover the whole period")
+
+	require.EqualValues(t, time.Date(2022, 1, 31, 17, 0, 0, 0, time.UTC), actual[0].DataPoint)
+	require.EqualValues(t, time.Date(2022, 1, 31, 15, 0, 0, 0, time.UTC), actual[0].TimeFrameStartAt)
+	require.EqualValues(t, 40*time.Minute, actual[0].ErrorBudgetSize, "28d * 0.1%")
+	require.EqualValues(t, 8*time.Minute, actual[0].FailureTime)
+	require.EqualValues(t, 112*time.Minute, actual[0].UpTime)
+	require.EqualValues(t, 32*time.Minute, actual[0].ErrorBudget)
+	require.EqualValues(t, 3*time.Minute, actual[0].ErrorBudgetConsumption)
+	require.EqualValues(t, 640*time.Minute, actual[0].TimeUntilExhaustion, "32m / (3m / 60m)")
+
+	require.EqualValues(t, time.Date(2022, 1, 31, 16, 0, 0, 0, time.UTC), actual[1].DataPoint)
+	require.EqualValues(t, time.Date(2022, 1, 31, 15, 0, 0, 0, time.UTC), actual[1].TimeFrameStartAt)
+	require.EqualValues(t, 5*time.Minute, actual[1].FailureTime)
+	require.EqualValues(t, 35*time.Minute, actual[1].ErrorBudget)
+	require.EqualValues(t, 5*time.Minute, actual[1].ErrorBudgetConsumption)
+	require.EqualValues(t, 420*time.Minute, actual[1].TimeUntilExhaustion, "35m / (5m / 60m)")
+}
+
+func TestNewCalendarReportsNotAligned(t *testing.T) {
+	dest := &shimesaba.Destination{
+		ServiceName:  "test",
+		MetricPrefix: "test",
+	}
+	// February in Asia/Kolkata starts at 2022-01-31T18:30:00Z, in the middle of the calculate interval of 1h.
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	require.NoError(t, err)
+	isNoViolation := make(shimesaba.IsNoViolationCollection)
+	for _, r := range []struct {
+		startAt time.Time
+		n       int
+	}{
+		{startAt: time.Date(2022, 1, 31, 18, 0, 0, 0, time.UTC), n: 10},
+		{startAt: time.Date(2022, 1, 31, 18, 40, 0, 0, time.UTC), n: 5},
+		{startAt: time.Date(2022, 1, 31, 19, 10, 0, 0, time.UTC), n: 3},
+	} {
+		for i := 0; i < r.n; i++ {
+			isNoViolation[r.startAt.Add(time.Duration(i)*time.Minute)] = false
+		}
+	}
+	c, err := isNoViolation.NewReliabilities(
+		time.Hour,
+		time.Date(2022, 1, 31, 17, 0, 0, 0, time.UTC),
+		time.Date(2022, 1, 31, 20, 59, 59, 0, time.UTC),
+	)
+	require.NoError(t, err)
+	actual := shimesaba.NewCalendarReports("test", dest, 0.001, shimesaba.WindowTypeCalendarMonth, loc, shimesaba.ErrorBudgetUnitMinutes, c)
+	require.Len(t, actual, 3, "the report of the interval straddling the start of February is created")
+
+	require.EqualValues(t, time.Date(2022, 1, 31, 21, 0, 0, 0, time.UTC), actual[0].DataPoint)
+	require.EqualValues(t, time.Date(2022, 1, 31, 18, 30, 0, 0, time.UTC), actual[0].TimeFrameStartAt)
+	require.EqualValues(t, 8*time.Minute, actual[0].FailureTime, "the failure before the start of February is not counted")
+	require.EqualValues(t, 142*time.Minute, actual[0].UpTime)
+
+	require.EqualValues(t, time.Date(2022, 1, 31, 19, 0, 0, 0, time.UTC), actual[2].DataPoint)
+	require.EqualValues(t, time.Date(2022, 1, 31, 18, 30, 0, 0, time.UTC), actual[2].TimeFrameStartAt)
+	require.EqualValues(t, 5*time.Minute, actual[2].FailureTime)
+	require.EqualValues(t, 25*time.Minute, actual[2].UpTime)
+	require.EqualValues(t, 5*time.Minute, actual[2].ErrorBudgetConsumption)
+}
+
+func TestReportBurnRates(t *testing.T) {
+	dest := &shimesaba.Destination{
+		ServiceName:       "test",
+		MetricPrefix:      "test",
+		MetricSuffix:      "test",
+		BurnRateLookbacks: []time.Duration{time.Hour, 3 * time.Hour},
+	}
+	isNoViolation := make(shimesaba.IsNoViolationCollection)
+	for i := 0; i < 6; i++ {
+		isNoViolation[time.Date(2022, 1, 6, 10, i, 0, 0, time.UTC)] = false
+	}
+	c, err := isNoViolation.NewReliabilities(
+		time.Hour,
+		time.Date(2022, 1, 6, 8, 0, 0, 0, time.UTC),
+		time.Date(2022, 1, 6, 10, 59, 59, 0, time.UTC),
+	)
+	require.NoError(t, err)
+	actual := shimesaba.NewReports("test", dest, 0.01, 3*time.Hour, shimesaba.ErrorBudgetUnitMinutes, c)
+	require.Len(t, actual, 1)
+	epsilon := 0.00001
+	require.InEpsilon(t, 10.0, actual[0].BurnRates[time.Hour], epsilon, "6m / 60m / 1%")
+	require.InEpsilon(t, 10.0/3.0, actual[0].BurnRates[3*time.Hour], epsilon, "6m / 180m / 1%")
+	require.InEpsilon(t, 10.0, actual[0].GetDestinationMetricValue(shimesaba.BurnRate), epsilon, "representative value")
+	require.EqualValues(t, "test.burn_rate_1h.test", dest.BurnRateMetricName(time.Hour))
+	require.EqualValues(t, "test.burn_rate_3h.test", dest.BurnRateMetricName(3*time.Hour))
+}
+
+func TestReportErrorBudgetRecovery(t *testing.T) {
+	dest := &shimesaba.Destination{
+		ServiceName:  "test",
+		MetricPrefix: "test",
+	}
+	isNoViolation := make(shimesaba.IsNoViolationCollection)
+	for day, n := range []int{10, 20, 30, 40} {
+		startAt := time.Date(2022, 1, 1+day, 0, 0, 0, 0, time.UTC)
+		for i := 0; i < n; i++ {
+			isNoViolation[startAt.Add(time.Duration(i)*time.Minute)] = false
+		}
+	}
+	c, err := isNoViolation.NewReliabilities(
+		24*time.Hour,
+		time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2022, 1, 4, 23, 59, 59, 0, time.UTC),
+	)
+	require.NoError(t, err)
+	actual := shimesaba.NewReports("test", dest, 0.05, 3*24*time.Hour, shimesaba.ErrorBudgetUnitMinutes, c)
+	require.Len(t, actual, 2)
+
+	require.EqualValues(t, time.Date(2022, 1, 5, 0, 0, 0, 0, time.UTC), actual[0].DataPoint)
+	require.EqualValues(t, 20*time.Minute, actual[0].ErrorBudgetRecovery24h, "2022-01-02 rolls off in the next 24h")
+	require.EqualValues(t, 90*time.Minute, actual[0].ErrorBudgetRecovery7d, "all failure rolls off in the next 7d")
+	require.EqualValues(t, 20.0, actual[0].GetDestinationMetricValue(shimesaba.ErrorBudgetRecovery24h))
+
+	require.EqualValues(t, time.Date(2022, 1, 4, 0, 0, 0, 0, time.UTC), actual[1].DataPoint)
+	require.EqualValues(t, 10*time.Minute, actual[1].ErrorBudgetRecovery24h)
+	require.EqualValues(t, 60*time.Minute, actual[1].ErrorBudgetRecovery7d)
+}
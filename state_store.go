@@ -0,0 +1,237 @@
+package shimesaba
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// defaultStateGracePeriod is the default period in which the metric based and threshold based SLIs are always evaluated again.
+const defaultStateGracePeriod = time.Hour
+
+// StateStore persists the reliabilities evaluated in a run, so the next run evaluates only the new intervals.
+type StateStore interface {
+	// LoadReliabilityState returns the state of the SLO definition saved with configHash. it returns nil if no state is saved.
+	LoadReliabilityState(ctx context.Context, sloID string, configHash string) (*ReliabilityState, error)
+	SaveReliabilityState(ctx context.Context, state *ReliabilityState) error
+}
+
+// NewStateStore creates StateStore from the configuration.
+func NewStateStore(cfg *StateStoreConfig) (StateStore, error) {
+	switch cfg.Type {
+	case "", "file":
+		return NewFileStateStore(cfg.Path), nil
+	default:
+		return nil, fmt.Errorf("state store type `%s` is not supported", cfg.Type)
+	}
+}
+
+// ReliabilityState is the reliabilities of the SLO definition evaluated in a run.
+type ReliabilityState struct {
+	SLOID       string                    `json:"slo_id"`
+	ConfigHash  string                    `json:"config_hash"`
+	EvaluatedAt time.Time                 `json:"evaluated_at"`
+	Windows     []*ReliabilityWindowState `json:"windows"`
+	// Sources are the fingerprints of the alerts and the excluded periods used in the evaluation,
+	// with the start of the period affected by each of them.
+	Sources map[string]time.Time `json:"sources"`
+}
+
+// ReliabilityWindowState is the totals of a tumbling window.
+type ReliabilityWindowState struct {
+	CursorAt     time.Time     `json:"cursor_at"`
+	TimeFrame    time.Duration `json:"time_frame"`
+	UpTime       time.Duration `json:"up_time"`
+	FailureTime  time.Duration `json:"failure_time"`
+	ExcludedTime time.Duration `json:"excluded_time,omitempty"`
+	GoodEvents   float64       `json:"good_events,omitempty"`
+	TotalEvents  float64       `json:"total_events,omitempty"`
+}
+
+// NewReliabilityState creates ReliabilityState from the evaluated reliabilities.
+func NewReliabilityState(sloID string, configHash string, evaluatedAt time.Time, reliabilities Reliabilities, sources map[string]time.Time) *ReliabilityState {
+	windows := make([]*ReliabilityWindowState, 0, len(reliabilities))
+	for _, r := range reliabilities {
+		windows = append(windows, &ReliabilityWindowState{
+			CursorAt:     r.CursorAt(),
+			TimeFrame:    r.TimeFrame(),
+			UpTime:       r.UpTime(),
+			FailureTime:  r.FailureTime(),
+			ExcludedTime: r.ExcludedTime(),
+			GoodEvents:   r.GoodEvents(),
+			TotalEvents:  r.TotalEvents(),
+		})
+	}
+	return &ReliabilityState{
+		SLOID:       sloID,
+		ConfigHash:  configHash,
+		EvaluatedAt: evaluatedAt,
+		Windows:     windows,
+		Sources:     sources,
+	}
+}
+
+// Reliabilities restores the reliabilities from the totals of the windows.
+func (state *ReliabilityState) Reliabilities() (Reliabilities, error) {
+	rc := make([]*Reliability, 0, len(state.Windows))
+	for _, w := range state.Windows {
+		rc = append(rc, NewReliabilityWithTotals(w.CursorAt.Add(-w.TimeFrame), w.TimeFrame, w.UpTime, w.FailureTime, w.ExcludedTime, w.GoodEvents, w.TotalEvents))
+	}
+	return NewReliabilities(rc)
+}
+
+// resume returns the time to evaluate from, and the saved reliabilities of the intervals between startAt and it.
+// The intervals after the newest saved one, the intervals affected by the sources changed since the state was saved,
+// and the intervals after graceFrom are evaluated again.
+func (state *ReliabilityState) resume(startAt time.Time, timeFrame time.Duration, sources map[string]time.Time, graceFrom time.Time) (time.Time, Reliabilities) {
+	reliabilities, err := state.Reliabilities()
+	if err != nil {
+		log.Printf("[warn] reliability state of slo[%s] is broken, evaluate all intervals: %s", state.SLOID, err)
+		return startAt, nil
+	}
+	if reliabilities.Len() == 0 || reliabilities.TimeFrame() != timeFrame {
+		return startAt, nil
+	}
+	evaluateFrom := reliabilities.CursorAt(0)
+	for key, at := range sources {
+		if _, ok := state.Sources[key]; !ok && at.Before(evaluateFrom) {
+			log.Printf("[debug] slo[%s]: source %s affecting from %s is added since %s", state.SLOID, key, at, state.EvaluatedAt)
+			evaluateFrom = at
+		}
+	}
+	for key, at := range state.Sources {
+		// the sources before startAt are out of the fetched period, not removed.
+		if _, ok := sources[key]; ok || at.Before(startAt) {
+			continue
+		}
+		if at.Before(evaluateFrom) {
+			log.Printf("[debug] slo[%s]: source %s affecting from %s is changed since %s", state.SLOID, key, at, state.EvaluatedAt)
+			evaluateFrom = at
+		}
+	}
+	if graceFrom.Before(evaluateFrom) {
+		log.Printf("[debug] slo[%s]: metric values after %s may be posted late", state.SLOID, graceFrom)
+		evaluateFrom = graceFrom
+	}
+	evaluateFrom = evaluateFrom.Truncate(timeFrame)
+	if !evaluateFrom.After(startAt) {
+		return startAt, nil
+	}
+	stored := make(Reliabilities, 0, reliabilities.Len())
+	for _, r := range reliabilities {
+		if r.TimeFrameStartAt().Before(startAt) || r.CursorAt().After(evaluateFrom) {
+			continue
+		}
+		stored = append(stored, r)
+	}
+	if stored.Len() != int(evaluateFrom.Sub(startAt)/timeFrame) {
+		log.Printf("[debug] slo[%s]: reliability state does not cover %s ~ %s, evaluate all intervals", state.SLOID, startAt, evaluateFrom)
+		return startAt, nil
+	}
+	return evaluateFrom, stored
+}
+
+// newStateSources returns the fingerprints of the alerts and the maintenance windows, with the start of the period affected by each of them.
+func newStateSources(alerts Alerts, maintenanceWindows MaintenanceWindows) map[string]time.Time {
+	sources := make(map[string]time.Time, len(alerts)+len(maintenanceWindows))
+	for _, alert := range alerts {
+		monitorID := ""
+		if alert.Monitor != nil {
+			monitorID = alert.Monitor.ID()
+		}
+		closedAt := ""
+		if alert.ClosedAt != nil {
+			closedAt = alert.ClosedAt.Format(time.RFC3339)
+		}
+		impact := ""
+		if alert.impact != nil {
+			impact = fmt.Sprint(*alert.impact)
+		}
+		key := fmt.Sprintf("alert\x00%s\x00%s\x00%s\x00%s\x00%s\x00%v\x00%s\x00%s", monitorID, alert.HostID, alert.OpenedAt.Format(time.RFC3339), closedAt, alert.Reason, alert.Severity, alert.Description(), impact)
+		sources[fingerprint(key)] = alert.OpenedAt
+	}
+	for _, w := range maintenanceWindows {
+		if w.schedule != nil {
+			// the recurring maintenance windows are in the configuration, which is covered by the config hash.
+			continue
+		}
+		key := fmt.Sprintf("window\x00%s\x00%s", w.startAt.Format(time.RFC3339), w.endAt.Format(time.RFC3339))
+		sources[fingerprint(key)] = w.startAt
+	}
+	return sources
+}
+
+func fingerprint(str string) string {
+	sum := sha256.Sum256([]byte(str))
+	return hex.EncodeToString(sum[:8])
+}
+
+// newConfigHash returns the hash of the SLO configuration. The saved state is used only with the same hash.
+func newConfigHash(cfg *SLOConfig) (string, error) {
+	bs, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(bs)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// FileStateStore is StateStore of local JSON files, one file for each SLO definition.
+type FileStateStore struct {
+	dir string
+}
+
+// NewFileStateStore creates FileStateStore that saves the files in dir.
+func NewFileStateStore(dir string) *FileStateStore {
+	return &FileStateStore{dir: dir}
+}
+
+func (s *FileStateStore) path(sloID string) string {
+	return filepath.Join(s.dir, url.PathEscape(sloID)+".json")
+}
+
+// LoadReliabilityState implements StateStore.
+func (s *FileStateStore) LoadReliabilityState(_ context.Context, sloID string, configHash string) (*ReliabilityState, error) {
+	path := s.path(sloID)
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			log.Printf("[debug] reliability state `%s` does not exist", path)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read reliability state: %w", err)
+	}
+	var state ReliabilityState
+	if err := json.Unmarshal(bs, &state); err != nil {
+		log.Printf("[warn] reliability state `%s` is broken, ignored: %s", path, err)
+		return nil, nil
+	}
+	if state.ConfigHash != configHash {
+		log.Printf("[info] slo[%s]: configuration is changed since the reliability state was saved, evaluate all intervals", sloID)
+		return nil, nil
+	}
+	log.Printf("[debug] load reliability state `%s`: %d windows, evaluated at %s", path, len(state.Windows), state.EvaluatedAt)
+	return &state, nil
+}
+
+// SaveReliabilityState implements StateStore.
+func (s *FileStateStore) SaveReliabilityState(_ context.Context, state *ReliabilityState) error {
+	bs, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	path := s.path(state.SLOID)
+	if err := writeFileAtomic(path, bs); err != nil {
+		return fmt.Errorf("failed to write reliability state: %w", err)
+	}
+	log.Printf("[debug] save reliability state `%s`: %d windows", path, len(state.Windows))
+	return nil
+}
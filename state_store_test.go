@@ -0,0 +1,158 @@
+package shimesaba_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mashiike/shimesaba"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStateStore(t *testing.T) {
+	store := shimesaba.NewFileStateStore(t.TempDir())
+	ctx := context.Background()
+	state, err := store.LoadReliabilityState(ctx, "test", "hash")
+	require.NoError(t, err)
+	require.Nil(t, state)
+
+	reliabilities, err := shimesaba.NewReliabilities([]*shimesaba.Reliability{
+		shimesaba.NewReliabilityWithEvents(time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC), 10*time.Minute, 90, 100),
+		shimesaba.NewReliabilityWithTotals(time.Date(2021, 10, 1, 0, 10, 0, 0, time.UTC), 10*time.Minute, 7*time.Minute, 2*time.Minute, time.Minute, 0, 0),
+	})
+	require.NoError(t, err)
+	evaluatedAt := time.Date(2021, 10, 1, 0, 20, 0, 0, time.UTC)
+	err = store.SaveReliabilityState(ctx, shimesaba.NewReliabilityState("test", "hash", evaluatedAt, reliabilities, nil))
+	require.NoError(t, err)
+
+	state, err = store.LoadReliabilityState(ctx, "test", "hash")
+	require.NoError(t, err)
+	require.NotNil(t, state)
+	require.True(t, evaluatedAt.Equal(state.EvaluatedAt))
+	restored, err := state.Reliabilities()
+	require.NoError(t, err)
+	require.Equal(t, 2, restored.Len())
+	for i, r := range reliabilities {
+		require.EqualValues(t, r.CursorAt(), restored[i].CursorAt())
+		require.EqualValues(t, r.UpTime(), restored[i].UpTime())
+		require.EqualValues(t, r.FailureTime(), restored[i].FailureTime())
+		require.EqualValues(t, r.ExcludedTime(), restored[i].ExcludedTime())
+		require.EqualValues(t, r.GoodEvents(), restored[i].GoodEvents())
+		require.EqualValues(t, r.TotalEvents(), restored[i].TotalEvents())
+	}
+
+	state, err = store.LoadReliabilityState(ctx, "test", "changed")
+	require.NoError(t, err)
+	require.Nil(t, state, "the state saved with another config hash is not used")
+}
+
+func TestDefinitionWithStateStore(t *testing.T) {
+	ctx := context.Background()
+	monitor := shimesaba.NewMonitor("1", "SLO api", "host")
+	changed := shimesaba.NewAlert(monitor, time.Date(2021, 10, 1, 0, 32, 0, 0, time.UTC), ptrTime(time.Date(2021, 10, 1, 0, 35, 0, 0, time.UTC)))
+	provider := &stubAlertDataProvider{
+		alerts: shimesaba.Alerts{
+			shimesaba.NewAlert(monitor, time.Date(2021, 10, 1, 0, 15, 0, 0, time.UTC), ptrTime(time.Date(2021, 10, 1, 0, 20, 0, 0, time.UTC))),
+			changed,
+		},
+	}
+	cfg := &shimesaba.SLOConfig{
+		ID: "test",
+		Destination: &shimesaba.DestinationConfig{
+			ServiceName: "test",
+		},
+		RollingPeriod:     "1h",
+		CalculateInterval: "10m",
+		ErrorBudgetSize:   "1%",
+		AlertBasedSLI: []*shimesaba.AlertBasedSLIConfig{
+			{MonitorNamePrefix: "SLO"},
+		},
+	}
+	require.NoError(t, cfg.Restrict())
+	d, err := shimesaba.NewDefinition(cfg)
+	require.NoError(t, err)
+	store := shimesaba.NewFileStateStore(t.TempDir())
+	incremental := d.WithStateStore(store)
+
+	now := time.Date(2021, 10, 1, 1, 0, 0, 0, time.UTC)
+	expected, err := d.CreateReports(ctx, provider, now, 1)
+	require.NoError(t, err)
+	actual, err := incremental.CreateReports(ctx, provider, now, 1)
+	require.NoError(t, err)
+	require.EqualValues(t, expected, actual, "the first run evaluates all intervals")
+
+	// Overwrite the saved window of 00:10~00:20, to see that the next run reuses it.
+	state, err := store.LoadReliabilityState(ctx, "test", d.ConfigHash())
+	require.NoError(t, err)
+	require.NotNil(t, state)
+	for _, w := range state.Windows {
+		if w.CursorAt.Equal(time.Date(2021, 10, 1, 0, 20, 0, 0, time.UTC)) {
+			require.EqualValues(t, 5*time.Minute, w.FailureTime)
+			w.UpTime, w.FailureTime = 4*time.Minute, 6*time.Minute
+		}
+	}
+	require.NoError(t, store.SaveReliabilityState(ctx, state))
+
+	// The alert of 00:32 is closed as a false positive, and a new alert is opened at 01:05.
+	provider.alerts = shimesaba.Alerts{
+		provider.alerts[0],
+		changed.WithReason("nodowntime"),
+		shimesaba.NewAlert(monitor, time.Date(2021, 10, 1, 1, 5, 0, 0, time.UTC), ptrTime(time.Date(2021, 10, 1, 1, 7, 0, 0, time.UTC))),
+	}
+	now = time.Date(2021, 10, 1, 1, 20, 0, 0, time.UTC)
+	reports, err := incremental.CreateReports(ctx, provider, now, 1)
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+	require.EqualValues(t, time.Date(2021, 10, 1, 1, 10, 0, 0, time.UTC), reports[0].DataPoint)
+	require.EqualValues(t, 8*time.Minute, reports[0].FailureTime, "reused 6m at 00:10~00:20, re-evaluated 0m at 00:30~00:40 and 2m at 01:00~01:10")
+	require.EqualValues(t, time.Date(2021, 10, 1, 1, 20, 0, 0, time.UTC), reports[1].DataPoint)
+	require.EqualValues(t, 2*time.Minute, reports[1].FailureTime)
+}
+
+func TestDefinitionWithStateStoreLateMetrics(t *testing.T) {
+	ctx := context.Background()
+	provider := &stubDataProvider{
+		metrics: map[string]shimesaba.MetricValues{
+			"test/api.latency": {
+				time.Date(2021, 10, 1, 0, 15, 0, 0, time.UTC): 1.0,
+			},
+		},
+	}
+	threshold := 0.5
+	cfg := &shimesaba.SLOConfig{
+		ID: "test",
+		Destination: &shimesaba.DestinationConfig{
+			ServiceName: "test",
+		},
+		RollingPeriod:     "1h",
+		CalculateInterval: "10m",
+		ErrorBudgetSize:   "1%",
+		ThresholdBasedSLI: []*shimesaba.ThresholdBasedSLIConfig{
+			{MetricName: "api.latency", Operator: ">", Threshold: &threshold},
+		},
+	}
+	require.NoError(t, cfg.Restrict())
+	d, err := shimesaba.NewDefinition(cfg)
+	require.NoError(t, err)
+	store := shimesaba.NewFileStateStore(t.TempDir())
+	incremental := d.WithStateStore(store).WithStateGracePeriod(30 * time.Minute)
+
+	now := time.Date(2021, 10, 1, 1, 0, 0, 0, time.UTC)
+	reports, err := incremental.CreateReports(ctx, provider, now, 1)
+	require.NoError(t, err)
+	require.EqualValues(t, now, reports[len(reports)-1].DataPoint)
+	require.EqualValues(t, time.Minute, reports[len(reports)-1].FailureTime)
+
+	// The metric values of 00:15 and 00:45 are posted after the windows are saved.
+	provider.metrics["test/api.latency"][time.Date(2021, 10, 1, 0, 16, 0, 0, time.UTC)] = 1.0
+	provider.metrics["test/api.latency"][time.Date(2021, 10, 1, 0, 45, 0, 0, time.UTC)] = 1.0
+	now = time.Date(2021, 10, 1, 1, 10, 0, 0, time.UTC)
+	reports, err = incremental.CreateReports(ctx, provider, now, 1)
+	require.NoError(t, err)
+	require.EqualValues(t, now, reports[len(reports)-1].DataPoint)
+	require.EqualValues(t, 2*time.Minute, reports[len(reports)-1].FailureTime, "reused 1m at 00:10~00:20 out of the grace period, re-evaluated 1m at 00:40~00:50 in the grace period")
+
+	expected, err := d.CreateReports(ctx, provider, now, 1)
+	require.NoError(t, err)
+	require.EqualValues(t, 3*time.Minute, expected[len(expected)-1].FailureTime, "without the state store, all late metric values are counted")
+}
@@ -0,0 +1,80 @@
+package shimesaba
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mashiike/shimesaba/internal/timeutils"
+)
+
+// ThresholdBasedSLI is an SLI that compares host or service metrics with a threshold directly.
+type ThresholdBasedSLI struct {
+	cfg *ThresholdBasedSLIConfig
+}
+
+func NewThresholdBasedSLI(cfg *ThresholdBasedSLIConfig) *ThresholdBasedSLI {
+	return &ThresholdBasedSLI{cfg: cfg}
+}
+
+func (o ThresholdBasedSLI) String() string {
+	target := "service=" + o.cfg.ServiceName
+	if o.cfg.HostID != "" {
+		target = "host_id=" + o.cfg.HostID
+	}
+	return fmt.Sprintf("threshold_based_sli[%s, metric=%s %s %f]", target, o.cfg.MetricName, o.cfg.Operator, *o.cfg.Threshold)
+}
+
+// EvaluateReliabilities treats each minute in which the metric value violates the threshold as an SLO violation.
+// Minutes without metric values are treated as no violation.
+func (o ThresholdBasedSLI) EvaluateReliabilities(ctx context.Context, provider DataProvider, timeFrame time.Duration, startAt, endAt time.Time) (Reliabilities, error) {
+	var values MetricValues
+	var err error
+	if o.cfg.HostID != "" {
+		values, err = provider.FetchHostMetricValues(ctx, o.cfg.HostID, o.cfg.MetricName, startAt, endAt)
+	} else {
+		values, err = provider.FetchServiceMetricValues(ctx, o.cfg.ServiceName, o.cfg.MetricName, startAt, endAt)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch metric `%s`: %w", o.cfg.MetricName, err)
+	}
+	log.Printf("[debug] %s get %d values", o, len(values))
+	isNoViolation := make(IsNoViolationCollection, len(values))
+	for t, value := range values {
+		violated, err := isThresholdViolated(o.cfg.Operator, value, *o.cfg.Threshold)
+		if err != nil {
+			return nil, err
+		}
+		if violated {
+			log.Printf("[debug] %s, SLO Violation, time=`%s`, value=%f", o, t, value)
+			isNoViolation[t.Truncate(time.Minute).UTC()] = false
+		}
+	}
+
+	failureRates := newTimeline(isNoViolation.FailureRates())
+	iter := timeutils.NewIterator(startAt, endAt, timeFrame)
+	iter.SetEnableOverWindow(true)
+	rc := make([]*Reliability, 0)
+	for iter.HasNext() {
+		cursorAt, _ := iter.Next()
+		rc = append(rc, newReliabilityWithTimeline(cursorAt, timeFrame, failureRates))
+	}
+	return NewReliabilities(rc)
+}
+
+// isThresholdViolated reports whether value violates threshold under operator.
+func isThresholdViolated(operator string, value, threshold float64) (bool, error) {
+	switch operator {
+	case ">":
+		return value > threshold, nil
+	case ">=":
+		return value >= threshold, nil
+	case "<":
+		return value < threshold, nil
+	case "<=":
+		return value <= threshold, nil
+	default:
+		return false, fmt.Errorf("unknown operator `%s`", operator)
+	}
+}
@@ -0,0 +1,76 @@
+package shimesaba_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mashiike/shimesaba"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThresholdBasedSLI(t *testing.T) {
+	provider := &stubDataProvider{
+		metrics: map[string]shimesaba.MetricValues{
+			"shimesaba/api.latency": {
+				time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC): 0.1,
+				time.Date(2021, time.October, 1, 0, 1, 0, 0, time.UTC): 0.6,
+				time.Date(2021, time.October, 1, 0, 2, 0, 0, time.UTC): 0.5,
+				time.Date(2021, time.October, 1, 0, 3, 0, 0, time.UTC): 0.2,
+			},
+			"host:4XYZabc/loadavg5": {
+				time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC): 1,
+				time.Date(2021, time.October, 1, 0, 1, 0, 0, time.UTC): 1,
+				time.Date(2021, time.October, 1, 0, 2, 0, 0, time.UTC): 12,
+				time.Date(2021, time.October, 1, 0, 3, 0, 0, time.UTC): 10,
+			},
+		},
+	}
+	threshold := func(v float64) *float64 {
+		return &v
+	}
+	cases := []struct {
+		name            string
+		cfg             *shimesaba.ThresholdBasedSLIConfig
+		expectedFailure []time.Duration
+	}{
+		{
+			name: "service_metric",
+			cfg: &shimesaba.ThresholdBasedSLIConfig{
+				ServiceName: "shimesaba",
+				MetricName:  "api.latency",
+				Operator:    ">",
+				Threshold:   threshold(0.5),
+			},
+			expectedFailure: []time.Duration{time.Minute, 0},
+		},
+		{
+			name: "host_metric",
+			cfg: &shimesaba.ThresholdBasedSLIConfig{
+				HostID:     "4XYZabc",
+				MetricName: "loadavg5",
+				Operator:   ">=",
+				Threshold:  threshold(10),
+			},
+			expectedFailure: []time.Duration{0, 2 * time.Minute},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			obj := shimesaba.NewThresholdBasedSLI(c.cfg)
+			actual, err := obj.EvaluateReliabilities(
+				context.Background(),
+				provider,
+				2*time.Minute,
+				time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2021, time.October, 1, 0, 3, 59, 0, time.UTC),
+			)
+			require.NoError(t, err)
+			require.Equal(t, 2, actual.Len())
+			require.EqualValues(t, time.Date(2021, time.October, 1, 0, 4, 0, 0, time.UTC), actual[0].CursorAt())
+			require.EqualValues(t, c.expectedFailure[1], actual[0].FailureTime())
+			require.EqualValues(t, time.Date(2021, time.October, 1, 0, 2, 0, 0, time.UTC), actual[1].CursorAt())
+			require.EqualValues(t, c.expectedFailure[0], actual[1].FailureTime())
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package shimesaba
+
+import (
+	"sort"
+	"time"
+)
+
+// span is the period [startAt, endAt) that has the same value, such as a failure rate.
+type span struct {
+	startAt time.Time
+	endAt   time.Time
+	value   float64
+}
+
+// minutes returns the number of minutes in the span.
+func (s span) minutes() int64 {
+	return int64(s.endAt.Sub(s.startAt) / time.Minute)
+}
+
+// timeline is the sorted and non-overlapping spans. The value is 0.0 out of the spans, and an empty timeline is nil.
+// The boundaries of the spans are aligned to minutes, because reliabilities are evaluated for each minute.
+// A timeline is never modified after it is created, so it is shared between reliabilities without copying.
+type timeline []span
+
+// newTimeline creates timeline from the value of each minute. The times not aligned to minutes are ignored.
+func newTimeline(values map[time.Time]float64) timeline {
+	minutes := make([]time.Time, 0, len(values))
+	for t, v := range values {
+		if v == 0.0 || !t.Truncate(time.Minute).Equal(t) {
+			continue
+		}
+		minutes = append(minutes, t)
+	}
+	sort.Slice(minutes, func(i, j int) bool {
+		return minutes[i].Before(minutes[j])
+	})
+	var tl timeline
+	for _, t := range minutes {
+		if n := len(tl); n > 0 && t.Before(tl[n-1].endAt) {
+			// the same minute in another location
+			continue
+		}
+		tl = tl.append(span{startAt: t.UTC(), endAt: t.Add(time.Minute).UTC(), value: values[t]})
+	}
+	return tl
+}
+
+// newSpanTimeline creates timeline that has value in [startAt, endAt). endAt is rounded up to the minute.
+func newSpanTimeline(startAt, endAt time.Time, value float64) timeline {
+	startAt = startAt.Truncate(time.Minute).UTC()
+	if rounded := endAt.Truncate(time.Minute); rounded.Before(endAt) {
+		endAt = rounded.Add(time.Minute)
+	}
+	return timeline(nil).append(span{startAt: startAt, endAt: endAt.UTC(), value: value})
+}
+
+// append appends the span after the last span, and joins them if they are adjacent and have the same value.
+// The span of the value 0.0 is dropped.
+func (tl timeline) append(s span) timeline {
+	if s.value == 0.0 || !s.startAt.Before(s.endAt) {
+		return tl
+	}
+	if n := len(tl); n > 0 && tl[n-1].endAt.Equal(s.startAt) && tl[n-1].value == s.value {
+		tl[n-1].endAt = s.endAt
+		return tl
+	}
+	return append(tl, s)
+}
+
+// valueAt returns the value at t.
+func (tl timeline) valueAt(t time.Time) float64 {
+	i := sort.Search(len(tl), func(i int) bool {
+		return tl[i].endAt.After(t)
+	})
+	if i < len(tl) && !tl[i].startAt.After(t) {
+		return tl[i].value
+	}
+	return 0.0
+}
+
+// clip returns the part of the timeline in [startAt, endAt).
+func (tl timeline) clip(startAt, endAt time.Time) timeline {
+	i := sort.Search(len(tl), func(i int) bool {
+		return tl[i].endAt.After(startAt)
+	})
+	j := sort.Search(len(tl), func(j int) bool {
+		return !tl[j].startAt.Before(endAt)
+	})
+	if i >= j {
+		return nil
+	}
+	clipped := make(timeline, j-i)
+	copy(clipped, tl[i:j])
+	if clipped[0].startAt.Before(startAt) {
+		clipped[0].startAt = startAt
+	}
+	if clipped[len(clipped)-1].endAt.After(endAt) {
+		clipped[len(clipped)-1].endAt = endAt
+	}
+	return clipped
+}
+
+// duration returns the total length of the spans.
+func (tl timeline) duration() time.Duration {
+	var d time.Duration
+	for _, s := range tl {
+		d += s.endAt.Sub(s.startAt)
+	}
+	return d
+}
+
+// mapValues returns timeline whose values are converted by fn.
+func (tl timeline) mapValues(fn func(value float64) float64) timeline {
+	var mapped timeline
+	for _, s := range tl {
+		mapped = mapped.append(span{startAt: s.startAt, endAt: s.endAt, value: fn(s.value)})
+	}
+	return mapped
+}
+
+// minuteValues returns the value of each minute in the timeline.
+func (tl timeline) minuteValues() map[time.Time]float64 {
+	values := make(map[time.Time]float64)
+	for _, s := range tl {
+		for t := s.startAt; t.Before(s.endAt); t = t.Add(time.Minute) {
+			values[t] = s.value
+		}
+	}
+	return values
+}
+
+// combineTimelines combines the values of the timelines at each time in [startAt, endAt) with fn.
+// values passed to fn are in the same order as the timelines, and 0.0 out of the spans.
+func combineTimelines(startAt, endAt time.Time, fn func(values []float64) float64, timelines ...timeline) timeline {
+	bounds := []time.Time{startAt, endAt}
+	for _, tl := range timelines {
+		for _, s := range tl.clip(startAt, endAt) {
+			bounds = append(bounds, s.startAt, s.endAt)
+		}
+	}
+	sort.Slice(bounds, func(i, j int) bool {
+		return bounds[i].Before(bounds[j])
+	})
+	cursors := make([]int, len(timelines))
+	values := make([]float64, len(timelines))
+	var combined timeline
+	for i := 0; i+1 < len(bounds); i++ {
+		at := bounds[i]
+		if !at.Before(bounds[i+1]) {
+			continue
+		}
+		for j, tl := range timelines {
+			for cursors[j] < len(tl) && !tl[cursors[j]].endAt.After(at) {
+				cursors[j]++
+			}
+			values[j] = 0.0
+			if cursors[j] < len(tl) && !tl[cursors[j]].startAt.After(at) {
+				values[j] = tl[cursors[j]].value
+			}
+		}
+		combined = combined.append(span{startAt: at, endAt: bounds[i+1], value: fn(values)})
+	}
+	return combined
+}
+
+// maxValue is the combining function that takes the largest value.
+func maxValue(values []float64) float64 {
+	var max float64
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+	return max
+}
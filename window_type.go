@@ -0,0 +1,46 @@
+package shimesaba
+
+import "time"
+
+// WindowType is the type of the time window in which the error budget is calculated
+type WindowType int
+
+//go:generate enumer -type=WindowType -yaml -linecomment -output window_type_enumer.go
+
+const (
+	WindowTypeRolling         WindowType = iota //rolling
+	WindowTypeCalendarMonth                     //calendar_month
+	WindowTypeCalendarQuarter                   //calendar_quarter
+)
+
+// IsCalendar reports whether the error budget is reset at the boundary of the calendar period.
+func (t WindowType) IsCalendar() bool {
+	return t == WindowTypeCalendarMonth || t == WindowTypeCalendarQuarter
+}
+
+// PeriodStartAt returns the start time of the calendar period that contains the specified time.
+func (t WindowType) PeriodStartAt(at time.Time, loc *time.Location) time.Time {
+	at = at.In(loc)
+	switch t {
+	case WindowTypeCalendarMonth:
+		return time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, loc)
+	case WindowTypeCalendarQuarter:
+		month := (at.Month()-1)/3*3 + 1
+		return time.Date(at.Year(), month, 1, 0, 0, 0, 0, loc)
+	default:
+		return at
+	}
+}
+
+// PeriodEndAt returns the start time of the next calendar period of the period that contains the specified time.
+func (t WindowType) PeriodEndAt(at time.Time, loc *time.Location) time.Time {
+	startAt := t.PeriodStartAt(at, loc)
+	switch t {
+	case WindowTypeCalendarMonth:
+		return startAt.AddDate(0, 1, 0)
+	case WindowTypeCalendarQuarter:
+		return startAt.AddDate(0, 3, 0)
+	default:
+		return startAt
+	}
+}
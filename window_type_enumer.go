@@ -0,0 +1,99 @@
+// Code generated by "enumer -type=WindowType -yaml -linecomment -output window_type_enumer.go"; DO NOT EDIT.
+
+package shimesaba
+
+import (
+	"fmt"
+	"strings"
+)
+
+const _WindowTypeName = "rollingcalendar_monthcalendar_quarter"
+
+var _WindowTypeIndex = [...]uint8{0, 7, 21, 37}
+
+const _WindowTypeLowerName = "rollingcalendar_monthcalendar_quarter"
+
+func (i WindowType) String() string {
+	if i < 0 || i >= WindowType(len(_WindowTypeIndex)-1) {
+		return fmt.Sprintf("WindowType(%d)", i)
+	}
+	return _WindowTypeName[_WindowTypeIndex[i]:_WindowTypeIndex[i+1]]
+}
+
+// An "invalid array index" compiler error signifies that the constant values have changed.
+// Re-run the stringer command to generate them again.
+func _WindowTypeNoOp() {
+	var x [1]struct{}
+	_ = x[WindowTypeRolling-(0)]
+	_ = x[WindowTypeCalendarMonth-(1)]
+	_ = x[WindowTypeCalendarQuarter-(2)]
+}
+
+var _WindowTypeValues = []WindowType{WindowTypeRolling, WindowTypeCalendarMonth, WindowTypeCalendarQuarter}
+
+var _WindowTypeNameToValueMap = map[string]WindowType{
+	_WindowTypeName[0:7]:        WindowTypeRolling,
+	_WindowTypeLowerName[0:7]:   WindowTypeRolling,
+	_WindowTypeName[7:21]:       WindowTypeCalendarMonth,
+	_WindowTypeLowerName[7:21]:  WindowTypeCalendarMonth,
+	_WindowTypeName[21:37]:      WindowTypeCalendarQuarter,
+	_WindowTypeLowerName[21:37]: WindowTypeCalendarQuarter,
+}
+
+var _WindowTypeNames = []string{
+	_WindowTypeName[0:7],
+	_WindowTypeName[7:21],
+	_WindowTypeName[21:37],
+}
+
+// WindowTypeString retrieves an enum value from the enum constants string name.
+// Throws an error if the param is not part of the enum.
+func WindowTypeString(s string) (WindowType, error) {
+	if val, ok := _WindowTypeNameToValueMap[s]; ok {
+		return val, nil
+	}
+
+	if val, ok := _WindowTypeNameToValueMap[strings.ToLower(s)]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("%s does not belong to WindowType values", s)
+}
+
+// WindowTypeValues returns all values of the enum
+func WindowTypeValues() []WindowType {
+	return _WindowTypeValues
+}
+
+// WindowTypeStrings returns a slice of all String values of the enum
+func WindowTypeStrings() []string {
+	strs := make([]string, len(_WindowTypeNames))
+	copy(strs, _WindowTypeNames)
+	return strs
+}
+
+// IsAWindowType returns "true" if the value is listed in the enum definition. "false" otherwise
+func (i WindowType) IsAWindowType() bool {
+	for _, v := range _WindowTypeValues {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalYAML implements a YAML Marshaler for WindowType
+func (i WindowType) MarshalYAML() (interface{}, error) {
+	return i.String(), nil
+}
+
+// UnmarshalYAML implements a YAML Unmarshaler for WindowType
+func (i *WindowType) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+
+	var err error
+	*i, err = WindowTypeString(s)
+	return err
+}